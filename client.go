@@ -1,12 +1,14 @@
 package fireactions
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -137,7 +139,8 @@ func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
 	case
 		http.StatusOK,
 		http.StatusNoContent,
-		http.StatusCreated:
+		http.StatusCreated,
+		http.StatusAccepted:
 
 		if v != nil {
 			if w, ok := v.(io.Writer); ok {
@@ -255,6 +258,104 @@ func (c *Client) GetPool(ctx context.Context, id string) (*Pool, *Response, erro
 	return root.Pool, rsp, nil
 }
 
+// GetCapacity returns the capacity of the host and the capacity allocated to
+// each pool.
+func (c *Client) GetCapacity(ctx context.Context) (*Capacity, *Response, error) {
+	req, err := c.newRequestWithContext(ctx, "GET", "/api/v1/capacity", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type Root struct {
+		Capacity *Capacity `json:"capacity"`
+	}
+
+	var root Root
+	rsp, err := c.do(req, &root)
+	if err != nil {
+		return nil, rsp, err
+	}
+
+	return root.Capacity, rsp, nil
+}
+
+// EventsOptions specifies the optional parameters of StreamEvents.
+type EventsOptions struct {
+	// Pool only streams the events of the given pool.
+	Pool string
+
+	// Follow keeps streaming new events after the recorded ones.
+	Follow bool
+}
+
+// StreamEvents streams the events recorded by the server, followed by the new
+// ones if opts.Follow is set, calling fn for each of them until the stream
+// ends, ctx is done or fn returns an error.
+func (c *Client) StreamEvents(ctx context.Context, opts *EventsOptions, fn func(*Event) error) (*Response, error) {
+	req, err := c.newRequestWithContext(ctx, "GET", "/api/v1/events", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &EventsOptions{}
+	}
+
+	q := req.URL.Query()
+	q.Set("follow", fmt.Sprintf("%t", opts.Follow))
+	if opts.Pool != "" {
+		q.Set("pool", opts.Pool)
+	}
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream is not bound by the timeout of the client.
+	client := *c.client
+	client.Timeout = 0
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	response := &Response{Response: rsp}
+	if rsp.StatusCode != http.StatusOK {
+		var apiErr Error
+		if err := json.NewDecoder(rsp.Body).Decode(&apiErr); err != nil {
+			return response, fmt.Errorf("%v %v: %d", req.Method, req.URL, rsp.StatusCode)
+		}
+
+		return response, &apiErr
+	}
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(rsp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" && data.Len() > 0:
+			var event Event
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return response, fmt.Errorf("decoding event: %w", err)
+			}
+
+			data.Reset()
+			if err := fn(&event); err != nil {
+				return response, err
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
 // PausePool pauses a pool by ID.
 func (c *Client) PausePool(ctx context.Context, id string) (*Response, error) {
 	req, err := c.newRequestWithContext(ctx, "POST", fmt.Sprintf("/api/v1/pools/%s/pause", id), nil)
@@ -275,22 +376,123 @@ func (c *Client) ResumePool(ctx context.Context, id string) (*Response, error) {
 	return c.do(req, nil)
 }
 
-// ScalePool scales a pool by ID.
-func (c *Client) ScalePool(ctx context.Context, id string) (*Response, error) {
-	req, err := c.newRequestWithContext(ctx, "POST", fmt.Sprintf("/api/v1/pools/%s/scale", id), nil)
+// DrainPool drains a pool by ID. The pool stops scaling and its runners are
+// removed once idle, or forcefully once the timeout expires. A zero timeout
+// uses the server default.
+func (c *Client) DrainPool(ctx context.Context, id string, timeout time.Duration) (*Response, error) {
+	req, err := c.newRequestWithContext(ctx, "POST", fmt.Sprintf("/api/v1/pools/%s/drain", id), nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if timeout > 0 {
+		q := req.URL.Query()
+		q.Set("timeout", timeout.String())
+		req.URL.RawQuery = q.Encode()
+	}
+
 	return c.do(req, nil)
 }
 
-// Reload reloads the Fireactions server.
-func (c *Client) Reload(ctx context.Context) (*Response, error) {
-	req, err := c.newRequestWithContext(ctx, "POST", "/api/v1/reload", nil)
+// ScalePool scales a pool by ID, either to the desired number of replicas or by
+// a delta. See ScalePoolRequest.
+func (c *Client) ScalePool(ctx context.Context, id string, request *ScalePoolRequest) (*ScalePoolResult, *Response, error) {
+	req, err := c.newRequestWithContext(ctx, "POST", fmt.Sprintf("/api/v1/pools/%s/scale", id), request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type Root struct {
+		Result *ScalePoolResult `json:"result"`
+	}
+
+	var root Root
+	rsp, err := c.do(req, &root)
+	if err != nil {
+		return nil, rsp, err
+	}
+
+	return root.Result, rsp, nil
+}
+
+// ListRunners returns a list of runners of a pool by ID.
+func (c *Client) ListRunners(ctx context.Context, pool string, opts *ListOptions) (Runners, *Response, error) {
+	req, err := c.newRequestWithContext(ctx, "GET", fmt.Sprintf("/api/v1/pools/%s/runners", pool), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts != nil {
+		opts.Apply(req)
+	}
+
+	type Root struct {
+		Runners Runners `json:"runners"`
+	}
+
+	var root Root
+	rsp, err := c.do(req, &root)
+	if err != nil {
+		return nil, rsp, err
+	}
+
+	return root.Runners, rsp, nil
+}
+
+// GetRunner returns a runner by name.
+func (c *Client) GetRunner(ctx context.Context, name string) (*Runner, *Response, error) {
+	req, err := c.newRequestWithContext(ctx, "GET", fmt.Sprintf("/api/v1/runners/%s", name), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type Root struct {
+		Runner *Runner `json:"runner"`
+	}
+
+	var root Root
+	rsp, err := c.do(req, &root)
+	if err != nil {
+		return nil, rsp, err
+	}
+
+	return root.Runner, rsp, nil
+}
+
+// DeleteRunner deletes a runner by name. If force is true, the runner virtual
+// machine is killed immediately instead of being shut down gracefully.
+func (c *Client) DeleteRunner(ctx context.Context, name string, force bool) (*Response, error) {
+	req, err := c.newRequestWithContext(ctx, "DELETE", fmt.Sprintf("/api/v1/runners/%s", name), nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if force {
+		q := req.URL.Query()
+		q.Set("force", "true")
+		req.URL.RawQuery = q.Encode()
+	}
+
 	return c.do(req, nil)
 }
+
+// Reload reloads the pools of the Fireactions server from its configuration
+// file and returns the changes applied.
+func (c *Client) Reload(ctx context.Context) (*ReloadResult, *Response, error) {
+	req, err := c.newRequestWithContext(ctx, "POST", "/api/v1/reload", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type Root struct {
+		Reload *ReloadResult `json:"reload"`
+	}
+
+	var root Root
+	rsp, err := c.do(req, &root)
+	if err != nil {
+		return nil, rsp, err
+	}
+
+	return root.Reload, rsp, nil
+}
@@ -2,9 +2,11 @@ package fireactions
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -16,14 +18,18 @@ func TestClient_Reload(t *testing.T) {
 		}
 
 		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"message":"Pools reloaded successfully","reload":{"added":["new"],"removed":[],"changed":[{"pool":"test","fields":["runner.image"],"rolled":true}],"unchanged":[],"restart_required":[]}}`))
 	}))
 	defer server.Close()
 
 	client := NewClient(WithEndpoint(server.URL))
 
-	_, err := client.Reload(context.Background())
+	result, _, err := client.Reload(context.Background())
 
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"new"}, result.Added)
+	assert.Equal(t, "test", result.Changed[0].Pool)
+	assert.True(t, result.Changed[0].Rolled)
 }
 
 func TestClient_ScalePool(t *testing.T) {
@@ -32,15 +38,24 @@ func TestClient_ScalePool(t *testing.T) {
 			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
 		}
 
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"replicas":5}` {
+			t.Errorf("unexpected request body: %s", body)
+		}
+
 		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"result":{"pool":"test","replicas":4,"errors":["error"]}}`))
 	}))
 	defer server.Close()
 
 	client := NewClient(WithEndpoint(server.URL))
 
-	_, err := client.ScalePool(context.Background(), "test")
+	replicas := 5
+	result, _, err := client.ScalePool(context.Background(), "test", &ScalePoolRequest{Replicas: &replicas})
 
 	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Replicas)
+	assert.Equal(t, []string{"error"}, result.Errors)
 }
 
 func TestClient_PausePool(t *testing.T) {
@@ -112,3 +127,135 @@ func TestClient_ListPools(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestClient_ListRunners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/pools/test/runners" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"runners":[{"name":"test-1", "pool": "test", "state": "Idle", "ip_address": "10.0.0.2"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	runners, _, err := client.ListRunners(context.Background(), "test", nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, runners, 1)
+	assert.Equal(t, RunnerStateIdle, runners[0].State)
+}
+
+func TestClient_GetRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/runners/test-1" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"runner":{"name":"test-1", "pool": "test", "state": "Busy"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	runner, _, err := client.GetRunner(context.Background(), "test-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test-1", runner.Name)
+}
+
+func TestClient_DeleteRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/v1/runners/test-1" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		if r.URL.Query().Get("force") != "true" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	_, err := client.DeleteRunner(context.Background(), "test-1", true)
+
+	assert.NoError(t, err)
+}
+
+func TestClient_DrainPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/pools/test/drain" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		if r.URL.Query().Get("timeout") != "10m0s" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	_, err := client.DrainPool(context.Background(), "test", 10*time.Minute)
+
+	assert.NoError(t, err)
+}
+
+func TestClient_GetCapacity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/capacity" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"capacity":{"enabled":true,"vcpus":{"total":16,"available":12},"pools":[{"pool":"test","runners":2}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	capacity, _, err := client.GetCapacity(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), capacity.Vcpus.Available)
+	assert.Equal(t, 2, capacity.Pools[0].Runners)
+}
+
+func TestClient_StreamEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/events" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		if r.URL.Query().Get("pool") != "test" || r.URL.Query().Get("follow") != "true" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("id: 1\nevent: VMCreated\ndata: {\"id\":1,\"type\":\"VMCreated\",\"pool\":\"test\"}\n\n: keepalive\n\n"))
+		_, _ = w.Write([]byte("id: 2\nevent: VMExited\ndata: {\"id\":2,\"type\":\"VMExited\",\"pool\":\"test\",\"reason\":\"completed\"}\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+
+	var events []*Event
+	_, err := client.StreamEvents(context.Background(), &EventsOptions{Pool: "test", Follow: true}, func(event *Event) error {
+		events = append(events, event)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventTypeVMCreated, events[0].Type)
+	assert.Equal(t, "completed", events[1].Reason)
+}
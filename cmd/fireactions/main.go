@@ -11,5 +11,6 @@ func main() {
 	cli := commands.New()
 	if err := cli.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing command: %s\n", err.Error())
+		os.Exit(1)
 	}
 }
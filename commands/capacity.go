@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/hostinger/fireactions/helper/printer"
+	"github.com/spf13/cobra"
+)
+
+func newCapacityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "capacity",
+		Short:   "Show the capacity of the host and the capacity allocated to each pool",
+		RunE:    runCapacityCmd,
+		Args:    cobra.NoArgs,
+		GroupID: "pools",
+	}
+
+	return cmd
+}
+
+func runCapacityCmd(cmd *cobra.Command, _ []string) error {
+	capacity, _, err := client.GetCapacity(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("get capacity: %w", err)
+	}
+
+	printer.PrintText(capacity, cmd.OutOrStdout(), nil)
+	fmt.Fprintln(cmd.OutOrStdout())
+	printer.PrintText(capacity.Pools, cmd.OutOrStdout(), nil)
+	return nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hostinger/fireactions"
+	"github.com/hostinger/fireactions/commands/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCapacityCommand_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().GetCapacity(gomock.Any()).Return(&fireactions.Capacity{
+		Enabled:   true,
+		Vcpus:     fireactions.CapacityResource{Total: 16, Reserved: 2, OvercommitRatio: 2, Allocatable: 28, Allocated: 4, Available: 24},
+		MemoryMib: fireactions.CapacityResource{Total: 65536, Reserved: 4096, OvercommitRatio: 1, Allocatable: 61440, Allocated: 4096, Available: 57344},
+		Pools:     fireactions.PoolCapacities{{Pool: "pool-name", Priority: 10, Runners: 2, Vcpus: 4, MemoryMib: 4096}},
+	}, nil, nil)
+	client = mockClient
+
+	out := &bytes.Buffer{}
+	cmd := newCapacityCmd()
+	cmd.SetOut(out)
+	err := cmd.RunE(cmd, []string{})
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "vCPUs")
+	assert.Contains(t, out.String(), "57344")
+	assert.Contains(t, out.String(), "pool-name")
+}
+
+func TestCapacityCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().GetCapacity(gomock.Any()).Return(nil, nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newCapacityCmd()
+	err := cmd.RunE(cmd, []string{})
+	assert.Error(t, err)
+}
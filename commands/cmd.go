@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"time"
 
 	"github.com/hostinger/fireactions"
 	"github.com/spf13/cobra"
@@ -19,8 +20,14 @@ type fireactionsClient interface {
 	GetPool(ctx context.Context, name string) (*fireactions.Pool, *fireactions.Response, error)
 	PausePool(ctx context.Context, name string) (*fireactions.Response, error)
 	ResumePool(ctx context.Context, name string) (*fireactions.Response, error)
-	ScalePool(ctx context.Context, name string) (*fireactions.Response, error)
-	Reload(ctx context.Context) (*fireactions.Response, error)
+	DrainPool(ctx context.Context, name string, timeout time.Duration) (*fireactions.Response, error)
+	ScalePool(ctx context.Context, name string, request *fireactions.ScalePoolRequest) (*fireactions.ScalePoolResult, *fireactions.Response, error)
+	Reload(ctx context.Context) (*fireactions.ReloadResult, *fireactions.Response, error)
+	GetCapacity(ctx context.Context) (*fireactions.Capacity, *fireactions.Response, error)
+	StreamEvents(ctx context.Context, opts *fireactions.EventsOptions, fn func(*fireactions.Event) error) (*fireactions.Response, error)
+	ListRunners(ctx context.Context, pool string, opts *fireactions.ListOptions) (fireactions.Runners, *fireactions.Response, error)
+	GetRunner(ctx context.Context, name string) (*fireactions.Runner, *fireactions.Response, error)
+	DeleteRunner(ctx context.Context, name string, force bool) (*fireactions.Response, error)
 }
 
 // New returns a new root-level command.
@@ -48,10 +55,12 @@ func New() *cobra.Command {
 	})
 
 	cmd.AddCommand(newReloadCmd())
+	cmd.AddCommand(newEventsCmd())
 
 	cmd.AddGroup(&cobra.Group{ID: "main", Title: "Main application commands:"})
 	cmd.AddCommand(newServerCmd())
 	cmd.AddCommand(newRunnerCmd())
+	cmd.AddCommand(newConfigCmd())
 
 	cmd.AddGroup(&cobra.Group{ID: "pools", Title: "Pool management commands:"})
 	cmd.AddCommand(newPoolsListCmd())
@@ -59,6 +68,11 @@ func New() *cobra.Command {
 	cmd.AddCommand(newPoolsResumeCmd())
 	cmd.AddCommand(newPoolsPauseCmd())
 	cmd.AddCommand(newPoolsScaleCmd())
+	cmd.AddCommand(newPoolsDrainCmd())
+	cmd.AddCommand(newCapacityCmd())
+
+	cmd.AddGroup(&cobra.Group{ID: "runners", Title: "Runner management commands:"})
+	cmd.AddCommand(newRunnersCmd())
 
 	cmd.PersistentFlags().SortFlags = false
 	cmd.PersistentFlags().StringVarP(&endpoint, "endpoint", "e", "http://127.0.0.1:8080", "Endpoint to use for communicating with the Fireactions API.")
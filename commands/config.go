@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/hostinger/fireactions/server"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config",
+		Short:   "Render and validate the configuration file",
+		Args:    cobra.NoArgs,
+		GroupID: "main",
+	}
+
+	cmd.AddCommand(newConfigRenderCmd())
+	cmd.AddCommand(newConfigValidateCmd())
+
+	return cmd
+}
+
+func newConfigRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print the pools of the configuration file, with their templates and included files resolved",
+		Args:  cobra.NoArgs,
+		RunE:  runConfigRenderCmd,
+	}
+
+	cmd.Flags().StringP("config", "f", "/etc/fireactions/config.yaml", "Sets the configuration file path.")
+
+	return cmd
+}
+
+func runConfigRenderCmd(cmd *cobra.Command, _ []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	config, err := server.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	b, err := config.RenderPools()
+	if err != nil {
+		return fmt.Errorf("rendering pools: %w", err)
+	}
+
+	_, err = cmd.OutOrStdout().Write(b)
+	return err
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration file, checking the files, images, keys and networks it refers to",
+		Args:  cobra.NoArgs,
+		RunE:  runConfigValidateCmd,
+	}
+
+	cmd.Flags().StringP("config", "f", "/etc/fireactions/config.yaml", "Sets the configuration file path.")
+
+	return cmd
+}
+
+func runConfigValidateCmd(cmd *cobra.Command, _ []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	errs := server.CheckConfigFile(configFile)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(cmd.OutOrStdout(), err)
+		}
+
+		return fmt.Errorf("configuration has %d error(s)", len(errs))
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Configuration file %s is valid\n", configFile)
+	return nil
+}
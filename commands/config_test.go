@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigRenderCommand(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := newConfigRenderCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"-f", "../server/testdata/config1.yaml"})
+
+		err := cmd.Execute()
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "pools:")
+		assert.Contains(t, out.String(), "name: fireactions-2vcpu-2gb")
+		assert.NotContains(t, out.String(), "app_private_key")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		cmd := newConfigRenderCmd()
+		cmd.SetArgs([]string{"-f", "does-not-exist.yaml"})
+
+		err := cmd.Execute()
+		assert.Error(t, err)
+	})
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := newConfigValidateCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"-f", "../server/testdata/config1.yaml"})
+
+		err := cmd.Execute()
+		assert.ErrorContains(t, err, "error(s)")
+		assert.Contains(t, out.String(), "../server/testdata/config1.yaml:39: github.app_private_key: private key is not PEM encoded\n")
+	})
+
+	t.Run("LoadErrors", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("github:\n  app_id: abc\n  webhook_secret: ${FIREACTIONS_TEST_UNSET}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		out := &bytes.Buffer{}
+		cmd := newConfigValidateCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"-f", path})
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, "configuration has 2 error(s)")
+		assert.Contains(t, out.String(), path+":3: github.webhook_secret: environment variable FIREACTIONS_TEST_UNSET is not set\n")
+		assert.Contains(t, out.String(), path+":2: github.app_id: cannot unmarshal !!str `abc` into int64\n")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := newConfigValidateCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"-f", "does-not-exist.yaml"})
+
+		err := cmd.Execute()
+		assert.Error(t, err)
+		assert.Contains(t, out.String(), "does-not-exist.yaml: open file: ")
+	})
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/spf13/cobra"
+)
+
+func newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show the recent events of the server, e.g. runners created, started and exited",
+		Args:  cobra.NoArgs,
+		RunE:  runEventsCmd,
+	}
+
+	cmd.Flags().String("pool", "", "Only show the events of the given pool")
+	cmd.Flags().BoolP("follow", "f", false, "Keep streaming new events")
+	return cmd
+}
+
+func runEventsCmd(cmd *cobra.Command, _ []string) error {
+	pool, _ := cmd.Flags().GetString("pool")
+	follow, _ := cmd.Flags().GetBool("follow")
+
+	_, err := client.StreamEvents(cmd.Context(), &fireactions.EventsOptions{Pool: pool, Follow: follow}, func(event *fireactions.Event) error {
+		printEvent(cmd.OutOrStdout(), event)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("stream events: %w", err)
+	}
+
+	return nil
+}
+
+func printEvent(w io.Writer, event *fireactions.Event) {
+	source := event.Pool
+	if event.Runner != "" {
+		source = fmt.Sprintf("%s/%s", event.Pool, event.Runner)
+	}
+
+	var details []string
+	if event.Message != "" {
+		details = append(details, event.Message)
+	}
+
+	if event.Reason != "" {
+		details = append(details, fmt.Sprintf("reason=%s", event.Reason))
+	}
+
+	if event.DurationSeconds > 0 {
+		duration := time.Duration(event.DurationSeconds * float64(time.Second)).Round(time.Second)
+		details = append(details, fmt.Sprintf("duration=%s", duration))
+	}
+
+	if event.Error != "" {
+		details = append(details, fmt.Sprintf("error=%q", event.Error))
+	}
+
+	fmt.Fprintf(w, "%s  %-16s  %s  %s\n", event.Time.Format(time.RFC3339), event.Type, source, strings.Join(details, " "))
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/hostinger/fireactions/commands/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEventsCommand_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().StreamEvents(gomock.Any(), &fireactions.EventsOptions{Pool: "pool-name", Follow: true}, gomock.Any()).
+		DoAndReturn(func(_ interface{}, _ *fireactions.EventsOptions, fn func(*fireactions.Event) error) (*fireactions.Response, error) {
+			_ = fn(&fireactions.Event{ID: 1, Type: fireactions.EventTypeVMStarted, Time: time.Unix(0, 0).UTC(), Pool: "pool-name", Runner: "runner-1", Message: "IP address 10.0.0.2"})
+			_ = fn(&fireactions.Event{ID: 2, Type: fireactions.EventTypeVMExited, Time: time.Unix(60, 0).UTC(), Pool: "pool-name", Runner: "runner-1", Reason: "completed", DurationSeconds: 60.2})
+			return nil, nil
+		})
+	client = mockClient
+
+	out := &bytes.Buffer{}
+	cmd := newEventsCmd()
+	cmd.SetOut(out)
+	_ = cmd.Flags().Set("pool", "pool-name")
+	_ = cmd.Flags().Set("follow", "true")
+	err := cmd.RunE(cmd, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "1970-01-01T00:00:00Z  VMStarted         pool-name/runner-1  IP address 10.0.0.2\n"+
+		"1970-01-01T00:01:00Z  VMExited          pool-name/runner-1  reason=completed duration=1m0s\n", out.String())
+}
+
+func TestEventsCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().StreamEvents(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newEventsCmd()
+	err := cmd.RunE(cmd, []string{})
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	fireactions "github.com/hostinger/fireactions"
 	gomock "go.uber.org/mock/gomock"
@@ -39,6 +40,52 @@ func (m *Client) EXPECT() *ClientMockRecorder {
 	return m.recorder
 }
 
+// DeleteRunner mocks base method.
+func (m *Client) DeleteRunner(ctx context.Context, name string, force bool) (*fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRunner", ctx, name, force)
+	ret0, _ := ret[0].(*fireactions.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRunner indicates an expected call of DeleteRunner.
+func (mr *ClientMockRecorder) DeleteRunner(ctx, name, force any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRunner", reflect.TypeOf((*Client)(nil).DeleteRunner), ctx, name, force)
+}
+
+// DrainPool mocks base method.
+func (m *Client) DrainPool(ctx context.Context, id string, timeout time.Duration) (*fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrainPool", ctx, id, timeout)
+	ret0, _ := ret[0].(*fireactions.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DrainPool indicates an expected call of DrainPool.
+func (mr *ClientMockRecorder) DrainPool(ctx, id, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrainPool", reflect.TypeOf((*Client)(nil).DrainPool), ctx, id, timeout)
+}
+
+// GetCapacity mocks base method.
+func (m *Client) GetCapacity(ctx context.Context) (*fireactions.Capacity, *fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCapacity", ctx)
+	ret0, _ := ret[0].(*fireactions.Capacity)
+	ret1, _ := ret[1].(*fireactions.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetCapacity indicates an expected call of GetCapacity.
+func (mr *ClientMockRecorder) GetCapacity(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCapacity", reflect.TypeOf((*Client)(nil).GetCapacity), ctx)
+}
+
 // GetPool mocks base method.
 func (m *Client) GetPool(ctx context.Context, name string) (*fireactions.Pool, *fireactions.Response, error) {
 	m.ctrl.T.Helper()
@@ -55,6 +102,22 @@ func (mr *ClientMockRecorder) GetPool(ctx, name any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPool", reflect.TypeOf((*Client)(nil).GetPool), ctx, name)
 }
 
+// GetRunner mocks base method.
+func (m *Client) GetRunner(ctx context.Context, name string) (*fireactions.Runner, *fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunner", ctx, name)
+	ret0, _ := ret[0].(*fireactions.Runner)
+	ret1, _ := ret[1].(*fireactions.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRunner indicates an expected call of GetRunner.
+func (mr *ClientMockRecorder) GetRunner(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunner", reflect.TypeOf((*Client)(nil).GetRunner), ctx, name)
+}
+
 // ListPools mocks base method.
 func (m *Client) ListPools(ctx context.Context, opts *fireactions.ListOptions) (fireactions.Pools, *fireactions.Response, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +134,22 @@ func (mr *ClientMockRecorder) ListPools(ctx, opts any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPools", reflect.TypeOf((*Client)(nil).ListPools), ctx, opts)
 }
 
+// ListRunners mocks base method.
+func (m *Client) ListRunners(ctx context.Context, pool string, opts *fireactions.ListOptions) (fireactions.Runners, *fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRunners", ctx, pool, opts)
+	ret0, _ := ret[0].(fireactions.Runners)
+	ret1, _ := ret[1].(*fireactions.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListRunners indicates an expected call of ListRunners.
+func (mr *ClientMockRecorder) ListRunners(ctx, pool, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRunners", reflect.TypeOf((*Client)(nil).ListRunners), ctx, pool, opts)
+}
+
 // PausePool mocks base method.
 func (m *Client) PausePool(ctx context.Context, name string) (*fireactions.Response, error) {
 	m.ctrl.T.Helper()
@@ -87,12 +166,13 @@ func (mr *ClientMockRecorder) PausePool(ctx, name any) *gomock.Call {
 }
 
 // Reload mocks base method.
-func (m *Client) Reload(ctx context.Context) (*fireactions.Response, error) {
+func (m *Client) Reload(ctx context.Context) (*fireactions.ReloadResult, *fireactions.Response, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Reload", ctx)
-	ret0, _ := ret[0].(*fireactions.Response)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret0, _ := ret[0].(*fireactions.ReloadResult)
+	ret1, _ := ret[1].(*fireactions.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // Reload indicates an expected call of Reload.
@@ -117,16 +197,32 @@ func (mr *ClientMockRecorder) ResumePool(ctx, name any) *gomock.Call {
 }
 
 // ScalePool mocks base method.
-func (m *Client) ScalePool(ctx context.Context, name string) (*fireactions.Response, error) {
+func (m *Client) ScalePool(ctx context.Context, name string, request *fireactions.ScalePoolRequest) (*fireactions.ScalePoolResult, *fireactions.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ScalePool", ctx, name)
+	ret := m.ctrl.Call(m, "ScalePool", ctx, name, request)
+	ret0, _ := ret[0].(*fireactions.ScalePoolResult)
+	ret1, _ := ret[1].(*fireactions.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ScalePool indicates an expected call of ScalePool.
+func (mr *ClientMockRecorder) ScalePool(ctx, name, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScalePool", reflect.TypeOf((*Client)(nil).ScalePool), ctx, name, request)
+}
+
+// StreamEvents mocks base method.
+func (m *Client) StreamEvents(ctx context.Context, opts *fireactions.EventsOptions, fn func(*fireactions.Event) error) (*fireactions.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamEvents", ctx, opts, fn)
 	ret0, _ := ret[0].(*fireactions.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ScalePool indicates an expected call of ScalePool.
-func (mr *ClientMockRecorder) ScalePool(ctx, name any) *gomock.Call {
+// StreamEvents indicates an expected call of StreamEvents.
+func (mr *ClientMockRecorder) StreamEvents(ctx, opts, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScalePool", reflect.TypeOf((*Client)(nil).ScalePool), ctx, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamEvents", reflect.TypeOf((*Client)(nil).StreamEvents), ctx, opts, fn)
 }
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 
+	"github.com/hostinger/fireactions"
 	"github.com/hostinger/fireactions/helper/printer"
 	"github.com/spf13/cobra"
 )
@@ -60,21 +61,64 @@ func newPoolsScaleCmd() *cobra.Command {
 		GroupID: "pools",
 	}
 
-	cmd.Flags().Int("replicas", 1, "Number of replicas to scale to")
+	cmd.Flags().Int("replicas", 0, "Number of replicas to scale to")
+	cmd.Flags().Int("delta", 0, "Number of replicas to add (positive) or remove (negative)")
+	cmd.MarkFlagsMutuallyExclusive("replicas", "delta")
+	cmd.MarkFlagsOneRequired("replicas", "delta")
 	return cmd
 }
 
 func runPoolsScaleCmd(cmd *cobra.Command, args []string) error {
-	replicas, _ := cmd.Flags().GetInt("replicas")
-	for i := 0; i < replicas; i++ {
-		_, err := client.ScalePool(cmd.Context(), args[0])
-		if err != nil {
-			return fmt.Errorf("scale pool \"%s\": %w", args[0], err)
+	request := &fireactions.ScalePoolRequest{}
+	switch {
+	case cmd.Flags().Changed("replicas"):
+		replicas, _ := cmd.Flags().GetInt("replicas")
+		request.Replicas = &replicas
+	case cmd.Flags().Changed("delta"):
+		delta, _ := cmd.Flags().GetInt("delta")
+		request.Delta = &delta
+	default:
+		return fmt.Errorf("scale pool \"%s\": one of --replicas or --delta is required", args[0])
+	}
+
+	result, _, err := client.ScalePool(cmd.Context(), args[0], request)
+	if err != nil {
+		return fmt.Errorf("scale pool \"%s\": %w", args[0], err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Pool \"%s\" scaled to %d replicas\n", args[0], result.Replicas)
+	if len(result.Errors) > 0 {
+		for _, err := range result.Errors {
+			fmt.Fprintf(cmd.ErrOrStderr(), "  %s\n", err)
 		}
 
-		fmt.Printf("Pool \"%s\" scaled up by +1\n", args[0])
+		return fmt.Errorf("scale pool \"%s\": %d error(s) occurred", args[0], len(result.Errors))
+	}
+
+	return nil
+}
+
+func newPoolsDrainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "drain NAME",
+		Short:   "Drain a pool, removing its runners once they finish their jobs",
+		RunE:    runPoolsDrainCmd,
+		Args:    cobra.ExactArgs(1),
+		GroupID: "pools",
+	}
+
+	cmd.Flags().Duration("timeout", 0, "Time to wait for busy runners before stopping them forcefully (default: server default)")
+	return cmd
+}
+
+func runPoolsDrainCmd(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	_, err := client.DrainPool(cmd.Context(), args[0], timeout)
+	if err != nil {
+		return fmt.Errorf("drain pool \"%s\": %w", args[0], err)
 	}
 
+	fmt.Fprintf(cmd.OutOrStdout(), "Pool \"%s\" is draining\n", args[0])
 	return nil
 }
 
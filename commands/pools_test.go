@@ -3,6 +3,7 @@ package commands
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/hostinger/fireactions"
 	"github.com/hostinger/fireactions/commands/mocks"
@@ -66,26 +67,67 @@ func TestPoolsScaleCommand_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	replicas := 5
 	mockClient := mocks.NewClient(ctrl)
-	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name").Return(nil, nil)
+	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name", &fireactions.ScalePoolRequest{Replicas: &replicas}).
+		Return(&fireactions.ScalePoolResult{Pool: "pool-name", Replicas: 5}, nil, nil)
 	client = mockClient
 
 	cmd := newPoolsScaleCmd()
-	err := cmd.Flags().Set("replicas", "1")
+	err := cmd.Flags().Set("replicas", "5")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = newPoolsScaleCmd().RunE(cmd, []string{"pool-name", "1"})
+	err = cmd.RunE(cmd, []string{"pool-name"})
 	assert.Nil(t, err)
 }
 
+func TestPoolsScaleCommand_Delta(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	delta := -2
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name", &fireactions.ScalePoolRequest{Delta: &delta}).
+		Return(&fireactions.ScalePoolResult{Pool: "pool-name", Replicas: 3}, nil, nil)
+	client = mockClient
+
+	cmd := newPoolsScaleCmd()
+	err := cmd.Flags().Set("delta", "-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cmd.RunE(cmd, []string{"pool-name"})
+	assert.Nil(t, err)
+}
+
+func TestPoolsScaleCommand_PartialFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name", gomock.Any()).
+		Return(&fireactions.ScalePoolResult{Pool: "pool-name", Replicas: 4, Errors: []string{"error"}}, nil, nil)
+	client = mockClient
+
+	cmd := newPoolsScaleCmd()
+	err := cmd.Flags().Set("replicas", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cmd.RunE(cmd, []string{"pool-name"})
+	assert.Error(t, err)
+}
+
 func TestPoolsScaleCommand_Failure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockClient := mocks.NewClient(ctrl)
-	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name").Return(nil, errors.New("error"))
+	mockClient.EXPECT().ScalePool(gomock.Any(), "pool-name", gomock.Any()).Return(nil, nil, errors.New("error"))
 	client = mockClient
 
 	cmd := newPoolsScaleCmd()
@@ -98,6 +140,39 @@ func TestPoolsScaleCommand_Failure(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestPoolsScaleCommand_MissingFlags(t *testing.T) {
+	cmd := newPoolsScaleCmd()
+	err := cmd.RunE(cmd, []string{"pool-name"})
+	assert.Error(t, err)
+}
+
+func TestPoolsDrainCommand_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().DrainPool(gomock.Any(), "pool-name", 10*time.Minute).Return(nil, nil)
+	client = mockClient
+
+	cmd := newPoolsDrainCmd()
+	cmd.Flags().Set("timeout", "10m")
+	err := cmd.RunE(cmd, []string{"pool-name"})
+	assert.Nil(t, err)
+}
+
+func TestPoolsDrainCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().DrainPool(gomock.Any(), "pool-name", time.Duration(0)).Return(nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newPoolsDrainCmd()
+	err := cmd.RunE(cmd, []string{"pool-name"})
+	assert.Error(t, err)
+}
+
 func TestPoolsShowCommand_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/hostinger/fireactions/helper/printer"
 	"github.com/spf13/cobra"
 )
 
@@ -16,10 +20,25 @@ func newReloadCmd() *cobra.Command {
 }
 
 func runReloadCmd(cmd *cobra.Command, _ []string) error {
-	_, err := client.Reload(cmd.Context())
+	result, _, err := client.Reload(cmd.Context())
 	if err != nil {
 		return err
 	}
 
+	if result == nil {
+		return nil
+	}
+
+	printer.PrintText(result, cmd.OutOrStdout(), nil)
+	for _, change := range result.Changed {
+		if len(change.RestartRequired) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Pool \"%s\" changes require a restart: %s\n", change.Pool, strings.Join(change.RestartRequired, ", "))
+		}
+	}
+
+	if len(result.RestartRequired) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Server changes require a restart: %s\n", strings.Join(result.RestartRequired, ", "))
+	}
+
 	return nil
 }
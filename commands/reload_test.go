@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/hostinger/fireactions"
 	"github.com/hostinger/fireactions/commands/mocks"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -14,11 +16,30 @@ func TestRunReloadCmd_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockClient := mocks.NewClient(ctrl)
-	mockClient.EXPECT().Reload(gomock.Any()).Return(nil, nil)
+	mockClient.EXPECT().Reload(gomock.Any()).Return(&fireactions.ReloadResult{
+		Added:   []string{"pool-added"},
+		Removed: []string{"pool-removed"},
+		Changed: fireactions.PoolChanges{
+			{Pool: "pool-changed", Fields: []string{"max_runners", "runner.image"}, Rolled: true},
+			{Pool: "pool-network", Fields: []string{"network.interfaces"}, RestartRequired: []string{"network.interfaces"}},
+		},
+		Unchanged:       []string{"pool-unchanged"},
+		RestartRequired: []string{"bind_address"},
+	}, nil, nil)
 	client = mockClient
 
-	err := newReloadCmd().RunE(&cobra.Command{}, []string{})
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := newReloadCmd()
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+	err := cmd.RunE(cmd, []string{})
 	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "pool-added")
+	assert.Contains(t, out.String(), "pool-removed")
+	assert.Contains(t, out.String(), "max_runners, runner.image")
+	assert.Contains(t, out.String(), "pool-unchanged")
+	assert.Contains(t, errOut.String(), "Pool \"pool-network\" changes require a restart: network.interfaces")
+	assert.Contains(t, errOut.String(), "Server changes require a restart: bind_address")
 }
 
 func TestRunReloadCmd_Failure(t *testing.T) {
@@ -26,7 +47,7 @@ func TestRunReloadCmd_Failure(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockClient := mocks.NewClient(ctrl)
-	mockClient.EXPECT().Reload(gomock.Any()).Return(nil, assert.AnError)
+	mockClient.EXPECT().Reload(gomock.Any()).Return(nil, nil, assert.AnError)
 	client = mockClient
 
 	err := newReloadCmd().RunE(&cobra.Command{}, []string{})
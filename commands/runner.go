@@ -6,13 +6,23 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/hostinger/fireactions/helper/logger"
 	"github.com/hostinger/fireactions/runner"
 	"github.com/hostinger/fireactions/runner/mmds"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
+const (
+	defaultMetadataPollInterval = 100 * time.Millisecond
+
+	// defaultMetadataMaxAttempts bounds the attempts to get the runner
+	// configuration, unless the machine is a warm boot template.
+	defaultMetadataMaxAttempts = 50
+)
+
 func newRunnerCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "runner",
@@ -33,8 +43,10 @@ func runRunnerCmd(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("creating logger: %w", err)
 	}
 
-	mmds := mmds.NewClient()
-	metadata, err := mmds.GetMetadata(context.Background(), "fireactions")
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	metadata, err := waitForMetadata(ctx, mmds.NewClient(), logger)
 	if err != nil {
 		return fmt.Errorf("mmds: getting metadata: %w", err)
 	}
@@ -44,9 +56,107 @@ func runRunnerCmd(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("mmds: runner_jit_config: not found")
 	}
 
-	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	// Machines restored from a warm boot snapshot keep the network
+	// configuration of the snapshot, so it's replaced with their own.
+	if network, ok := metadata["network"].(map[string]interface{}); ok {
+		config, err := runner.NewNetworkConfig(network)
+		if err != nil {
+			return fmt.Errorf("mmds: %w", err)
+		}
+
+		if err := runner.ConfigureNetwork(ctx, config); err != nil {
+			return fmt.Errorf("configuring network: %w", err)
+		}
+
+		logger.Info().Msgf("Network configured with address %s", config.Address)
+	}
+
+	// Secondary interfaces aren't configured by the kernel from its arguments.
+	interfaces, _ := metadata["interfaces"].([]interface{})
+	for _, iface := range interfaces {
+		network, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		config, err := runner.NewNetworkConfig(network)
+		if err != nil {
+			return fmt.Errorf("mmds: %w", err)
+		}
+
+		if err := runner.ConfigureNetwork(ctx, config); err != nil {
+			return fmt.Errorf("configuring network: %w", err)
+		}
+
+		logger.Info().Msgf("Network interface %s configured with address %s", config.Interface, config.Address)
+	}
+
+	drives, _ := metadata["drives"].([]interface{})
+	for _, drive := range drives {
+		driveMetadata, ok := drive.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		config, err := runner.NewDriveConfig(driveMetadata)
+		if err != nil {
+			return fmt.Errorf("mmds: %w", err)
+		}
+
+		if err := runner.MountDrive(ctx, config); err != nil {
+			return fmt.Errorf("mounting drive: %w", err)
+		}
+
+		logger.Info().Msgf("Drive %s mounted at %s", config.Name, config.MountPath)
+	}
+
+	if err := runner.Announce(os.Stdout, runner.ConfiguredMarker); err != nil {
+		logger.Warn().Err(err).Msg("Failed to announce runner configuration")
+	}
 
 	runner := runner.New(runnerJITConfig, runner.WithLogger(logger), runner.WithStdout(os.Stdout), runner.WithStderr(os.Stderr))
 	return runner.Run(ctx)
 }
+
+// waitForMetadata polls the metadata until it contains the runner
+// configuration, giving up after defaultMetadataMaxAttempts attempts. The
+// warm boot template of a pool, whose metadata has warm_boot_template set,
+// waits until it's restored from the snapshot taken while waiting here, which
+// provides the configuration.
+func waitForMetadata(ctx context.Context, client *mmds.Client, logger *zerolog.Logger) (map[string]interface{}, error) {
+	template := false
+	for attempt := 1; ; attempt++ {
+		metadata, err := client.GetMetadata(ctx, "fireactions")
+		if err == nil {
+			if _, ok := metadata["runner_jit_config"]; ok {
+				return metadata, nil
+			}
+
+			if ok, _ := metadata["warm_boot_template"].(bool); ok && !template {
+				if err := runner.Announce(os.Stdout, runner.ReadyMarker); err != nil {
+					logger.Warn().Err(err).Msg("Failed to announce readiness")
+				}
+
+				template = true
+			}
+		}
+
+		if !template && attempt >= defaultMetadataMaxAttempts {
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, fmt.Errorf("runner_jit_config: not found")
+		}
+
+		if err != nil && attempt == 1 {
+			logger.Debug().Err(err).Msg("Failed to get metadata, retrying")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(defaultMetadataPollInterval):
+		}
+	}
+}
@@ -1 +1,86 @@
 package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hostinger/fireactions/runner/mmds"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// redirectTransport sends the requests to the MMDS address to a test server.
+type redirectTransport struct {
+	url *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.url.Scheme
+	req.URL.Host = t.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestMMDSClient(t *testing.T, metadata func(attempt int32) string) (*mmds.Client, *atomic.Int32) {
+	attempts := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			fmt.Fprint(w, "token")
+			return
+		}
+
+		fmt.Fprint(w, metadata(attempts.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mmds.NewClient(mmds.WithHTTPClient(&http.Client{Transport: &redirectTransport{url: serverURL}})), attempts
+}
+
+func TestWaitForMetadata(t *testing.T) {
+	logger := zerolog.Nop()
+
+	t.Run("Configured", func(t *testing.T) {
+		client, attempts := newTestMMDSClient(t, func(int32) string {
+			return `{"runner_jit_config": "config"}`
+		})
+
+		metadata, err := waitForMetadata(context.Background(), client, &logger)
+		assert.NoError(t, err)
+		assert.Equal(t, "config", metadata["runner_jit_config"])
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		client, attempts := newTestMMDSClient(t, func(int32) string {
+			return `{}`
+		})
+
+		_, err := waitForMetadata(context.Background(), client, &logger)
+		assert.EqualError(t, err, "runner_jit_config: not found")
+		assert.Equal(t, int32(defaultMetadataMaxAttempts), attempts.Load())
+	})
+
+	t.Run("WarmBootTemplate", func(t *testing.T) {
+		client, _ := newTestMMDSClient(t, func(attempt int32) string {
+			if attempt <= defaultMetadataMaxAttempts+10 {
+				return `{"warm_boot_template": true}`
+			}
+
+			return `{"runner_jit_config": "config"}`
+		})
+
+		metadata, err := waitForMetadata(context.Background(), client, &logger)
+		assert.NoError(t, err)
+		assert.Equal(t, "config", metadata["runner_jit_config"])
+	})
+}
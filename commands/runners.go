@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/hostinger/fireactions"
+	"github.com/hostinger/fireactions/helper/printer"
+	"github.com/spf13/cobra"
+)
+
+func newRunnersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "runners",
+		Short:   "Manage the runner virtual machines of the pools",
+		Args:    cobra.NoArgs,
+		GroupID: "runners",
+	}
+
+	cmd.AddCommand(newRunnersListCmd())
+	cmd.AddCommand(newRunnersShowCmd())
+	cmd.AddCommand(newRunnersRemoveCmd())
+
+	return cmd
+}
+
+func newRunnersListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list [POOL]",
+		Short:   "List all runners, optionally only the ones of the given pool",
+		RunE:    runRunnersListCmd,
+		Args:    cobra.MaximumNArgs(1),
+		Aliases: []string{"ls"},
+	}
+
+	return cmd
+}
+
+func runRunnersListCmd(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		runners, _, err := client.ListRunners(cmd.Context(), args[0], nil)
+		if err != nil {
+			return fmt.Errorf("list runners of pool \"%s\": %w", args[0], err)
+		}
+
+		printer.PrintText(runners, cmd.OutOrStdout(), nil)
+		return nil
+	}
+
+	pools, _, err := client.ListPools(cmd.Context(), nil)
+	if err != nil {
+		return fmt.Errorf("list pools: %w", err)
+	}
+
+	runners := fireactions.Runners{}
+	for _, pool := range pools {
+		poolRunners, _, err := client.ListRunners(cmd.Context(), pool.Name, nil)
+		if err != nil {
+			return fmt.Errorf("list runners of pool \"%s\": %w", pool.Name, err)
+		}
+
+		runners = append(runners, poolRunners...)
+	}
+
+	printer.PrintText(runners, cmd.OutOrStdout(), nil)
+	return nil
+}
+
+func newRunnersShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show NAME",
+		Short: "Retrieve a specific runner by name",
+		RunE:  runRunnersShowCmd,
+		Args:  cobra.ExactArgs(1),
+	}
+
+	return cmd
+}
+
+func runRunnersShowCmd(cmd *cobra.Command, args []string) error {
+	runner, _, err := client.GetRunner(cmd.Context(), args[0])
+	if err != nil {
+		return fmt.Errorf("show runner \"%s\": %w", args[0], err)
+	}
+
+	printer.PrintText(runner, cmd.OutOrStdout(), nil)
+	return nil
+}
+
+func newRunnersRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rm NAME",
+		Short:   "Shut down a specific runner by name",
+		RunE:    runRunnersRemoveCmd,
+		Args:    cobra.ExactArgs(1),
+		Aliases: []string{"delete"},
+	}
+
+	cmd.Flags().Bool("force", false, "Kill the runner virtual machine immediately instead of shutting it down gracefully")
+	return cmd
+}
+
+func runRunnersRemoveCmd(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	_, err := client.DeleteRunner(cmd.Context(), args[0], force)
+	if err != nil {
+		return fmt.Errorf("remove runner \"%s\": %w", args[0], err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hostinger/fireactions"
+	"github.com/hostinger/fireactions/commands/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunnersListCommand_Pool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().ListRunners(gomock.Any(), "pool-name", nil).Return(fireactions.Runners{}, nil, nil)
+	client = mockClient
+
+	cmd := newRunnersListCmd()
+	err := cmd.RunE(cmd, []string{"pool-name"})
+	assert.Nil(t, err)
+}
+
+func TestRunnersListCommand_AllPools(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().ListPools(gomock.Any(), nil).Return(fireactions.Pools{{Name: "pool-1"}, {Name: "pool-2"}}, nil, nil)
+	mockClient.EXPECT().ListRunners(gomock.Any(), "pool-1", nil).Return(fireactions.Runners{{Name: "runner-1"}}, nil, nil)
+	mockClient.EXPECT().ListRunners(gomock.Any(), "pool-2", nil).Return(fireactions.Runners{{Name: "runner-2"}}, nil, nil)
+	client = mockClient
+
+	cmd := newRunnersListCmd()
+	err := cmd.RunE(cmd, []string{})
+	assert.Nil(t, err)
+}
+
+func TestRunnersListCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().ListRunners(gomock.Any(), "pool-name", nil).Return(nil, nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newRunnersListCmd()
+	err := cmd.RunE(cmd, []string{"pool-name"})
+	assert.Error(t, err)
+}
+
+func TestRunnersShowCommand_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().GetRunner(gomock.Any(), "runner-name").Return(&fireactions.Runner{}, nil, nil)
+	client = mockClient
+
+	cmd := newRunnersShowCmd()
+	err := cmd.RunE(cmd, []string{"runner-name"})
+	assert.Nil(t, err)
+}
+
+func TestRunnersShowCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().GetRunner(gomock.Any(), "runner-name").Return(nil, nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newRunnersShowCmd()
+	err := cmd.RunE(cmd, []string{"runner-name"})
+	assert.Error(t, err)
+}
+
+func TestRunnersRemoveCommand_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().DeleteRunner(gomock.Any(), "runner-name", true).Return(nil, nil)
+	client = mockClient
+
+	cmd := newRunnersRemoveCmd()
+	err := cmd.Flags().Set("force", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cmd.RunE(cmd, []string{"runner-name"})
+	assert.Nil(t, err)
+}
+
+func TestRunnersRemoveCommand_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewClient(ctrl)
+	mockClient.EXPECT().DeleteRunner(gomock.Any(), "runner-name", false).Return(nil, errors.New("error"))
+	client = mockClient
+
+	cmd := newRunnersRemoveCmd()
+	err := cmd.RunE(cmd, []string{"runner-name"})
+	assert.Error(t, err)
+}
@@ -22,6 +22,8 @@ func newServerCmd() *cobra.Command {
 
 	cmd.Flags().SortFlags = false
 	cmd.Flags().StringP("config", "f", "/etc/fireactions/config.yaml", "Sets the configuration file path.")
+	cmd.Flags().Duration("drain-timeout", 0, "Sets how long to wait for busy runners to finish on shutdown. Zero stops them immediately.")
+	cmd.Flags().Bool("watch-config", false, "Reloads the configuration when the configuration file or an included file changes.")
 
 	return cmd
 }
@@ -42,7 +44,13 @@ func runServerCmd(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("creating logger: %w", err)
 	}
 
-	server, err := server.New(config, server.WithLogger(logger))
+	drainTimeout, _ := cmd.Flags().GetDuration("drain-timeout")
+	opts := []server.Opt{server.WithLogger(logger), server.WithDrainTimeout(drainTimeout)}
+	if watchConfig, _ := cmd.Flags().GetBool("watch-config"); watchConfig {
+		opts = append(opts, server.WithConfigWatch())
+	}
+
+	s, err := server.New(config, opts...)
 	if err != nil {
 		return fmt.Errorf("could not create server: %w", err)
 	}
@@ -50,5 +58,20 @@ func runServerCmd(cmd *cobra.Command, _ []string) error {
 	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	return server.Run(ctx)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+
+	go func() {
+		for {
+			select {
+			case <-hupCh:
+				s.TriggerReload(server.ReloadTriggerSignal)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return s.Run(ctx)
 }
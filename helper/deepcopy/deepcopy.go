@@ -27,4 +27,5 @@ func Map(m map[string]interface{}) map[string]interface{} {
 
 func init() {
 	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
 }
@@ -1,7 +1,11 @@
 package github
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/google/go-github/v63/github"
@@ -29,7 +33,62 @@ func NewClient(appID int64, appPrivateKey string) (*Client, error) {
 	return client, nil
 }
 
-// Installation returns a new GitHub client for the given installation ID.
+// Installation returns a new GitHub client for the given installation ID. The client uses the same base URL as c.
 func (c *Client) Installation(installationID int64) *github.Client {
-	return github.NewClient(&http.Client{Transport: ghinstallation.NewFromAppsTransport(c.transport, installationID)})
+	transport := ghinstallation.NewFromAppsTransport(c.transport, installationID)
+	transport.BaseURL = strings.TrimSuffix(c.BaseURL.String(), "/")
+
+	client := github.NewClient(&http.Client{Transport: transport})
+	client.BaseURL = c.BaseURL
+	return client
+}
+
+// FindEnterpriseInstallation finds the App installation on the enterprise with the given slug. GitHub doesn't
+// provide a direct lookup endpoint for enterprise installations, so all installations of the App are listed and
+// matched by the slug of the enterprise account, which github.User doesn't decode.
+func (c *Client) FindEnterpriseInstallation(ctx context.Context, enterprise string) (*github.Installation, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		req, err := c.NewRequest(http.MethodGet, fmt.Sprintf("app/installations?per_page=%d&page=%d", opts.PerPage, opts.Page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var installations []json.RawMessage
+		rsp, err := c.Do(ctx, req, &installations)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, raw := range installations {
+			var target struct {
+				TargetType string `json:"target_type"`
+				Account    struct {
+					Slug string `json:"slug"`
+				} `json:"account"`
+			}
+			if err := json.Unmarshal(raw, &target); err != nil {
+				return nil, err
+			}
+
+			if target.TargetType != "Enterprise" || !strings.EqualFold(target.Account.Slug, enterprise) {
+				continue
+			}
+
+			installation := &github.Installation{}
+			if err := json.Unmarshal(raw, installation); err != nil {
+				return nil, err
+			}
+
+			return installation, nil
+		}
+
+		if rsp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = rsp.NextPage
+	}
+
+	return nil, fmt.Errorf("no installation found for enterprise %s", enterprise)
 }
@@ -1,6 +1,10 @@
 package github
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 
@@ -36,3 +40,39 @@ func TestClientInstallation(t *testing.T) {
 	installation := client.Installation(12345)
 	assert.NotNil(t, installation)
 }
+
+func TestClientFindEnterpriseInstallation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/app/installations" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[
+			{"id": 1, "target_type": "Organization", "account": {"login": "hostinger"}},
+			{"id": 2, "target_type": "Enterprise", "account": {"slug": "hostinger-international", "name": "Hostinger"}}
+		]`))
+	}))
+	defer server.Close()
+
+	key, err := os.ReadFile("testdata/test.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(12345, string(key))
+	assert.NoError(t, err)
+
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	installation, err := client.FindEnterpriseInstallation(context.Background(), "hostinger-international")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), installation.GetID())
+
+	// The display name of the enterprise isn't its slug.
+	_, err = client.FindEnterpriseInstallation(context.Background(), "Hostinger")
+	assert.Error(t, err)
+
+	_, err = client.FindEnterpriseInstallation(context.Background(), "other")
+	assert.Error(t, err)
+}
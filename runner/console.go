@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// ReadyMarker is written to the console once the agent waits for the
+	// runner configuration. The server takes the warm boot snapshot of a pool
+	// when it reads it.
+	ReadyMarker = "fireactions: waiting for runner configuration"
+
+	// ConfiguredMarker is written to the console once the agent received the
+	// runner configuration. The server measures the boot latency of a runner
+	// until it reads it.
+	ConfiguredMarker = "fireactions: runner configuration received"
+)
+
+const (
+	defaultConsolePath = "/dev/console"
+)
+
+// Announce writes the marker to the console, which is captured by the server
+// in the runner log file. The agent stdout usually goes to the journal, so it
+// falls back to w only if the console can't be opened.
+func Announce(w io.Writer, marker string) error {
+	console, err := os.OpenFile(defaultConsolePath, os.O_WRONLY, 0)
+	if err == nil {
+		defer console.Close()
+		w = console
+	}
+
+	_, err = fmt.Fprintln(w, marker)
+	return err
+}
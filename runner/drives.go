@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DriveConfig represents an additional drive of the virtual machine to mount,
+// as provided by the server in the "drives" metadata key.
+type DriveConfig struct {
+	Name      string
+	Device    string
+	MountPath string
+	ReadOnly  bool
+}
+
+// NewDriveConfig creates a DriveConfig from an item of the "drives" metadata
+// key.
+func NewDriveConfig(metadata map[string]interface{}) (*DriveConfig, error) {
+	config := &DriveConfig{}
+	config.Name, _ = metadata["name"].(string)
+	config.Device, _ = metadata["device"].(string)
+	config.MountPath, _ = metadata["mount_path"].(string)
+	config.ReadOnly, _ = metadata["read_only"].(bool)
+
+	if config.Device == "" {
+		return nil, fmt.Errorf("drive %s: device: not found", config.Name)
+	}
+
+	if config.MountPath == "" {
+		return nil, fmt.Errorf("drive %s: mount_path: not found", config.Name)
+	}
+
+	return config, nil
+}
+
+// MountDrive mounts the drive at its mount path, creating the directory if
+// needed.
+func MountDrive(ctx context.Context, config *DriveConfig) error {
+	if err := os.MkdirAll(config.MountPath, 0755); err != nil {
+		return fmt.Errorf("creating mount path: %w", err)
+	}
+
+	args := []string{config.Device, config.MountPath}
+	if config.ReadOnly {
+		args = append([]string{"-o", "ro"}, args...)
+	}
+
+	output, err := exec.CommandContext(ctx, "mount", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mount %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
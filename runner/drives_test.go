@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDriveConfig(t *testing.T) {
+	config, err := NewDriveConfig(map[string]interface{}{
+		"name":       "toolcache",
+		"device":     "/dev/vdb",
+		"mount_path": "/opt/hostedtoolcache",
+		"read_only":  true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &DriveConfig{Name: "toolcache", Device: "/dev/vdb", MountPath: "/opt/hostedtoolcache", ReadOnly: true}, config)
+
+	_, err = NewDriveConfig(map[string]interface{}{"name": "scratch", "device": "/dev/vdc"})
+	assert.Error(t, err)
+
+	_, err = NewDriveConfig(map[string]interface{}{"name": "scratch", "mount_path": "/mnt/scratch"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// NetworkConfig represents the configuration of a network interface of the
+// virtual machine, as provided by the server in the metadata for secondary
+// interfaces and for machines restored from a warm boot snapshot, which keep
+// the network configuration of the snapshot.
+type NetworkConfig struct {
+	Interface  string
+	Address    string
+	Gateway    string
+	MacAddress string
+}
+
+// NewNetworkConfig creates a NetworkConfig from the "network" metadata key or
+// an item of the "interfaces" one.
+func NewNetworkConfig(metadata map[string]interface{}) (*NetworkConfig, error) {
+	config := &NetworkConfig{Interface: "eth0"}
+	if iface, ok := metadata["interface"].(string); ok && iface != "" {
+		config.Interface = iface
+	}
+
+	config.Address, _ = metadata["address"].(string)
+	config.Gateway, _ = metadata["gateway"].(string)
+	config.MacAddress, _ = metadata["mac_address"].(string)
+
+	if config.Address == "" {
+		return nil, fmt.Errorf("network: address: not found")
+	}
+
+	return config, nil
+}
+
+// ConfigureNetwork replaces the MAC address, the addresses and the default
+// route of the network interface with the given configuration.
+func ConfigureNetwork(ctx context.Context, config *NetworkConfig) error {
+	commands := [][]string{}
+	if config.MacAddress != "" {
+		commands = append(commands,
+			[]string{"link", "set", "dev", config.Interface, "down"},
+			[]string{"link", "set", "dev", config.Interface, "address", config.MacAddress})
+	}
+
+	commands = append(commands,
+		[]string{"link", "set", "dev", config.Interface, "up"},
+		[]string{"addr", "flush", "dev", config.Interface},
+		[]string{"addr", "add", config.Address, "dev", config.Interface})
+	if config.Gateway != "" {
+		commands = append(commands, []string{"route", "replace", "default", "via", config.Gateway, "dev", config.Interface})
+	}
+
+	for _, args := range commands {
+		output, err := exec.CommandContext(ctx, "ip", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("ip %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNetworkConfig(t *testing.T) {
+	config, err := NewNetworkConfig(map[string]interface{}{
+		"address":     "10.0.0.5/24",
+		"gateway":     "10.0.0.1",
+		"mac_address": "06:00:0a:00:00:05",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &NetworkConfig{Interface: "eth0", Address: "10.0.0.5/24", Gateway: "10.0.0.1", MacAddress: "06:00:0a:00:00:05"}, config)
+
+	_, err = NewNetworkConfig(map[string]interface{}{"gateway": "10.0.0.1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hostinger/fireactions"
+)
+
+// Capacity accounts for the vCPUs and the memory of the host allocated to
+// the runners of all pools, and admits new runners against it.
+type Capacity struct {
+	config      *CapacityConfig
+	vcpus       int64
+	memoryMib   int64
+	pools       map[string]*Pool
+	allocations map[string]*allocation
+	l           *sync.Mutex
+}
+
+// allocation is the capacity allocated to a runner machine.
+type allocation struct {
+	pool      string
+	vcpus     int64
+	memoryMib int64
+}
+
+// NewCapacity creates a new Capacity, detecting the total vCPUs and memory of
+// the host if not set in the configuration.
+func NewCapacity(config *CapacityConfig) (*Capacity, error) {
+	if config == nil {
+		config = &CapacityConfig{}
+	}
+
+	c := &Capacity{
+		config:      config,
+		vcpus:       config.Vcpus,
+		memoryMib:   config.MemoryMib,
+		pools:       make(map[string]*Pool),
+		allocations: make(map[string]*allocation),
+		l:           &sync.Mutex{},
+	}
+
+	if c.vcpus == 0 {
+		c.vcpus = int64(runtime.NumCPU())
+	}
+
+	if c.memoryMib == 0 {
+		memoryMib, err := getHostMemoryMib()
+		if err != nil {
+			return nil, fmt.Errorf("detecting host memory: %w", err)
+		}
+
+		c.memoryMib = memoryMib
+	}
+
+	if c.vcpus <= c.config.ReservedVcpus || c.memoryMib <= c.config.ReservedMemoryMib {
+		return nil, fmt.Errorf("reserved capacity exceeds the host capacity of %d vCPUs and %d MiB", c.vcpus, c.memoryMib)
+	}
+
+	metricCapacityAllocatableVcpus.Set(float64(c.getAllocatableVcpus()))
+	metricCapacityAllocatableMemoryMib.Set(float64(c.getAllocatableMemoryMib()))
+
+	return c, nil
+}
+
+// AddPool registers a pool, taking its priority into account when admitting
+// the runners of the other pools.
+func (c *Capacity) AddPool(pool *Pool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.pools[pool.getConfig().Name] = pool
+}
+
+// RemovePool unregisters a pool. The capacity allocated to its runners is
+// released as they exit.
+func (c *Capacity) RemovePool(name string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	delete(c.pools, name)
+}
+
+// Admit allocates the capacity of a runner machine of the pool with the
+// given ID, or returns fireactions.ErrInsufficientCapacity if admission
+// control is enabled and the host has not enough capacity left. The capacity
+// the pools of higher priority need to reach their minimum number of runners
+// is held back.
+func (c *Capacity) Admit(pool *Pool, id string) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	request := newAllocation(pool.getConfig())
+	if !c.config.Enabled {
+		c.allocations[id] = request
+		c.updateMetrics()
+		return nil
+	}
+
+	vcpus, memoryMib := c.getAllocated()
+	for _, other := range c.pools {
+		if other.getConfig().Priority <= pool.getConfig().Priority || !other.isActive.Load() || other.isDraining.Load() {
+			continue
+		}
+
+		deficit := int64(other.GetProfile().MinRunners - c.countAllocations(other.getConfig().Name))
+		if deficit <= 0 {
+			continue
+		}
+
+		held := newAllocation(other.getConfig())
+		vcpus += deficit * held.vcpus
+		memoryMib += deficit * held.memoryMib
+	}
+
+	if available := c.getAllocatableVcpus() - vcpus; request.vcpus > available {
+		metricCapacityRejections.WithLabelValues(pool.getConfig().Name).Inc()
+		return fmt.Errorf("%w: %d vCPUs requested, %d available", fireactions.ErrInsufficientCapacity, request.vcpus, max(available, 0))
+	}
+
+	if available := c.getAllocatableMemoryMib() - memoryMib; request.memoryMib > available {
+		metricCapacityRejections.WithLabelValues(pool.getConfig().Name).Inc()
+		return fmt.Errorf("%w: %d MiB of memory requested, %d available", fireactions.ErrInsufficientCapacity, request.memoryMib, max(available, 0))
+	}
+
+	c.allocations[id] = request
+	c.updateMetrics()
+	return nil
+}
+
+// Allocate allocates the capacity of a runner machine of the pool with the
+// given ID without admission control, e.g. when adopted after a restart.
+func (c *Capacity) Allocate(pool *Pool, id string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.allocations[id] = newAllocation(pool.getConfig())
+	c.updateMetrics()
+}
+
+// Release releases the capacity allocated to the runner machine with the
+// given ID.
+func (c *Capacity) Release(id string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	delete(c.allocations, id)
+	c.updateMetrics()
+}
+
+// Get returns the capacity of the host and the capacity allocated to each
+// pool.
+func (c *Capacity) Get() *fireactions.Capacity {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	vcpus, memoryMib := c.getAllocated()
+	capacity := &fireactions.Capacity{
+		Enabled: c.config.Enabled,
+		Vcpus: fireactions.CapacityResource{
+			Total:           c.vcpus,
+			Reserved:        c.config.ReservedVcpus,
+			OvercommitRatio: c.config.GetVcpuOvercommitRatio(),
+			Allocatable:     c.getAllocatableVcpus(),
+			Allocated:       vcpus,
+			Available:       max(c.getAllocatableVcpus()-vcpus, 0),
+		},
+		MemoryMib: fireactions.CapacityResource{
+			Total:           c.memoryMib,
+			Reserved:        c.config.ReservedMemoryMib,
+			OvercommitRatio: c.config.GetMemoryOvercommitRatio(),
+			Allocatable:     c.getAllocatableMemoryMib(),
+			Allocated:       memoryMib,
+			Available:       max(c.getAllocatableMemoryMib()-memoryMib, 0),
+		},
+		Pools: make(fireactions.PoolCapacities, 0, len(c.pools)),
+	}
+
+	for name, pool := range c.pools {
+		poolCapacity := &fireactions.PoolCapacity{Pool: name, Priority: pool.getConfig().Priority}
+		for _, allocation := range c.allocations {
+			if allocation.pool != name {
+				continue
+			}
+
+			poolCapacity.Runners++
+			poolCapacity.Vcpus += allocation.vcpus
+			poolCapacity.MemoryMib += allocation.memoryMib
+		}
+
+		capacity.Pools = append(capacity.Pools, poolCapacity)
+	}
+
+	sort.Slice(capacity.Pools, func(i, j int) bool {
+		if capacity.Pools[i].Priority != capacity.Pools[j].Priority {
+			return capacity.Pools[i].Priority > capacity.Pools[j].Priority
+		}
+
+		return capacity.Pools[i].Pool < capacity.Pools[j].Pool
+	})
+
+	return capacity
+}
+
+func newAllocation(config *PoolConfig) *allocation {
+	return &allocation{
+		pool:      config.Name,
+		vcpus:     config.Firecracker.MachineConfig.VcpuCount,
+		memoryMib: config.Firecracker.MachineConfig.MemSizeMib,
+	}
+}
+
+func (c *Capacity) getAllocatableVcpus() int64 {
+	return int64(float64(c.vcpus-c.config.ReservedVcpus) * c.config.GetVcpuOvercommitRatio())
+}
+
+func (c *Capacity) getAllocatableMemoryMib() int64 {
+	return int64(float64(c.memoryMib-c.config.ReservedMemoryMib) * c.config.GetMemoryOvercommitRatio())
+}
+
+func (c *Capacity) getAllocated() (int64, int64) {
+	var vcpus, memoryMib int64
+	for _, allocation := range c.allocations {
+		vcpus += allocation.vcpus
+		memoryMib += allocation.memoryMib
+	}
+
+	return vcpus, memoryMib
+}
+
+func (c *Capacity) countAllocations(pool string) int {
+	count := 0
+	for _, allocation := range c.allocations {
+		if allocation.pool == pool {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (c *Capacity) updateMetrics() {
+	vcpus, memoryMib := c.getAllocated()
+	metricCapacityAllocatedVcpus.Set(float64(vcpus))
+	metricCapacityAllocatedMemoryMib.Set(float64(memoryMib))
+}
+
+// getHostMemoryMib returns the total memory of the host from /proc/meminfo.
+func getHostMemoryMib() (int64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		kib, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing MemTotal: %w", err)
+		}
+
+		return kib / 1024, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCapacityPool(name string, priority, minRunners int) *Pool {
+	pool := &Pool{
+		runners:   map[string]*Runner{},
+		runnersMu: &sync.Mutex{},
+	}
+	pool.config.Store(&PoolConfig{
+		Name:        name,
+		MinRunners:  minRunners,
+		MaxRunners:  10,
+		Priority:    priority,
+		Firecracker: &FirecrackerConfig{MachineConfig: FirecrackerMachineConfig{VcpuCount: 2, MemSizeMib: 1024}},
+	})
+	pool.isActive.Store(true)
+
+	return pool
+}
+
+func TestCapacity_Admit(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{Enabled: true, Vcpus: 8, MemoryMib: 8192, ReservedVcpus: 2, ReservedMemoryMib: 2048})
+	assert.NoError(t, err)
+
+	pool := newTestCapacityPool("pool1", 0, 0)
+	capacity.AddPool(pool)
+
+	assert.NoError(t, capacity.Admit(pool, "vm1"))
+	assert.NoError(t, capacity.Admit(pool, "vm2"))
+	assert.NoError(t, capacity.Admit(pool, "vm3"))
+	assert.ErrorIs(t, capacity.Admit(pool, "vm4"), fireactions.ErrInsufficientCapacity)
+
+	capacity.Release("vm1")
+	assert.NoError(t, capacity.Admit(pool, "vm4"))
+
+	result := capacity.Get()
+	assert.Equal(t, fireactions.CapacityResource{Total: 8, Reserved: 2, OvercommitRatio: 1, Allocatable: 6, Allocated: 6, Available: 0}, result.Vcpus)
+	assert.Equal(t, fireactions.CapacityResource{Total: 8192, Reserved: 2048, OvercommitRatio: 1, Allocatable: 6144, Allocated: 3072, Available: 3072}, result.MemoryMib)
+	assert.Equal(t, fireactions.PoolCapacities{{Pool: "pool1", Runners: 3, Vcpus: 6, MemoryMib: 3072}}, result.Pools)
+}
+
+func TestCapacity_Admit_Overcommit(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{Enabled: true, Vcpus: 4, MemoryMib: 8192, VcpuOvercommitRatio: 2})
+	assert.NoError(t, err)
+
+	pool := newTestCapacityPool("pool1", 0, 0)
+	capacity.AddPool(pool)
+
+	for _, id := range []string{"vm1", "vm2", "vm3", "vm4"} {
+		assert.NoError(t, capacity.Admit(pool, id))
+	}
+	assert.ErrorIs(t, capacity.Admit(pool, "vm5"), fireactions.ErrInsufficientCapacity)
+}
+
+func TestCapacity_Admit_Disabled(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{Vcpus: 2, MemoryMib: 1024})
+	assert.NoError(t, err)
+
+	pool := newTestCapacityPool("pool1", 0, 0)
+	capacity.AddPool(pool)
+
+	assert.NoError(t, capacity.Admit(pool, "vm1"))
+	assert.NoError(t, capacity.Admit(pool, "vm2"))
+	assert.Equal(t, int64(4), capacity.Get().Vcpus.Allocated)
+}
+
+func TestCapacity_Admit_Priority(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{Enabled: true, Vcpus: 8, MemoryMib: 8192})
+	assert.NoError(t, err)
+
+	high := newTestCapacityPool("high", 10, 2)
+	low := newTestCapacityPool("low", 0, 0)
+	capacity.AddPool(high)
+	capacity.AddPool(low)
+
+	// 2 runners of the high priority pool are held back.
+	assert.NoError(t, capacity.Admit(low, "low1"))
+	assert.NoError(t, capacity.Admit(low, "low2"))
+	assert.ErrorIs(t, capacity.Admit(low, "low3"), fireactions.ErrInsufficientCapacity)
+
+	assert.NoError(t, capacity.Admit(high, "high1"))
+	assert.NoError(t, capacity.Admit(high, "high2"))
+	assert.ErrorIs(t, capacity.Admit(high, "high3"), fireactions.ErrInsufficientCapacity)
+
+	// Paused pools don't hold back capacity.
+	capacity.Release("high1")
+	capacity.Release("high2")
+	high.isActive.Store(false)
+	assert.NoError(t, capacity.Admit(low, "low3"))
+
+	result := capacity.Get()
+	assert.Equal(t, "high", result.Pools[0].Pool)
+	assert.Equal(t, "low", result.Pools[1].Pool)
+}
+
+func TestCapacity_Allocate(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{Enabled: true, Vcpus: 2, MemoryMib: 1024})
+	assert.NoError(t, err)
+
+	pool := newTestCapacityPool("pool1", 0, 0)
+	capacity.AddPool(pool)
+
+	capacity.Allocate(pool, "vm1")
+	capacity.Allocate(pool, "vm2")
+	assert.Equal(t, int64(4), capacity.Get().Vcpus.Allocated)
+	assert.Equal(t, int64(0), capacity.Get().Vcpus.Available)
+}
+
+func TestNewCapacity(t *testing.T) {
+	capacity, err := NewCapacity(&CapacityConfig{})
+	assert.NoError(t, err)
+	assert.Greater(t, capacity.Get().Vcpus.Total, int64(0))
+	assert.Greater(t, capacity.Get().MemoryMib.Total, int64(0))
+
+	_, err = NewCapacity(&CapacityConfig{Vcpus: 2, MemoryMib: 1024, ReservedVcpus: 2})
+	assert.Error(t, err)
+}
+
+func TestPool_Scale_InsufficientCapacity(t *testing.T) {
+	pool, _, _ := newTestPool(t, nil)
+	pool.getConfig().Firecracker.MachineConfig = FirecrackerMachineConfig{VcpuCount: 2, MemSizeMib: 1024}
+
+	capacity, err := NewCapacity(&CapacityConfig{Enabled: true, Vcpus: 4, MemoryMib: 8192})
+	assert.NoError(t, err)
+	capacity.AddPool(pool)
+	pool.capacity = capacity
+
+	result := pool.ScaleTo(context.Background(), 3)
+	assert.ErrorIs(t, result.Err(), fireactions.ErrInsufficientCapacity)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Replicas)
+
+	result = pool.ScaleTo(context.Background(), 0)
+	assert.NoError(t, result.Err())
+	assert.Eventually(t, func() bool { return capacity.Get().Vcpus.Allocated == 2 }, time.Second, 10*time.Millisecond)
+}
@@ -1,25 +1,64 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
 	"github.com/go-playground/validator/v10"
+	"github.com/hostinger/fireactions/helper/deepcopy"
 	"gopkg.in/yaml.v3"
 )
 
 // Config is the configuration for the Client.
 type Config struct {
-	BindAddress      string            `yaml:"bind_address" validate:"required,hostname_port"`
-	Metrics          *MetricsConfig    `yaml:"metrics"`
-	BasicAuthEnabled bool              `yaml:"basic_auth_enabled" validate:""`
-	BasicAuthUsers   map[string]string `yaml:"basic_auth_users" validate:"required_if=basic_auth_enabled true"`
-	GitHub           *GitHubConfig     `yaml:"github" validate:"required"`
-	Pools            []*PoolConfig     `yaml:"pools" validate:"required,min=1"`
-	LogLevel         string            `yaml:"log_level" validate:"required,oneof=debug info warn error fatal panic trace"`
-	Debug            bool              `yaml:"debug" validate:""`
+	BindAddress      string               `yaml:"bind_address" validate:"required,hostname_port"`
+	Metrics          *MetricsConfig       `yaml:"metrics"`
+	GC               *GCConfig            `yaml:"gc"`
+	Capacity         *CapacityConfig      `yaml:"capacity"`
+	Notifications    *NotificationsConfig `yaml:"notifications"`
+	BasicAuthEnabled bool                 `yaml:"basic_auth_enabled" validate:""`
+	BasicAuthUsers   map[string]string    `yaml:"basic_auth_users" validate:"required_if=basic_auth_enabled true"`
+	GitHub           *GitHubConfig        `yaml:"github" validate:"required"`
+	Pools            []*PoolConfig        `yaml:"pools" validate:"required,min=1,dive"`
+	LogLevel         string               `yaml:"log_level" validate:"required,oneof=debug info warn error fatal panic trace"`
+	Debug            bool                 `yaml:"debug" validate:""`
+	StatePath        string               `yaml:"state_path" validate:"required"`
+
+	// BasicAuthUsersFile is a file of username:password lines added to
+	// BasicAuthUsers, keeping the passwords out of the configuration file.
+	BasicAuthUsersFile string `yaml:"basic_auth_users_file"`
+
+	// Includes are glob patterns of files defining more pools, e.g.
+	// conf.d/*.yaml. Relative patterns are relative to the directory of the
+	// configuration file.
+	Includes []string `yaml:"includes"`
+
+	// Templates are named pool settings which pools, including the ones of
+	// included files, and other templates inherit with extends. They are
+	// resolved when loading the configuration.
+	Templates map[string]map[string]interface{} `yaml:"templates"`
 
 	path string
+
+	// positions are the positions of the settings in the configuration
+	// files, by their key in dotted notation, e.g. pools[0].runner.image.
+	positions map[string]configPosition
+}
+
+// configPosition is the position of a setting in a configuration file.
+type configPosition struct {
+	file string
+	line int
 }
 
 type MetricsConfig struct {
@@ -27,18 +66,421 @@ type MetricsConfig struct {
 	Address string `yaml:"address" validate:"required_if=enabled true,hostname_port"`
 }
 
+// GCConfig is the configuration of the garbage collector removing stale
+// Containerd leases and snapshots and pruning runner log files.
+type GCConfig struct {
+	Enabled           bool          `yaml:"enabled" validate:""`
+	Interval          time.Duration `yaml:"interval" validate:""`
+	LogMaxAge         time.Duration `yaml:"log_max_age" validate:""`
+	LogMaxFileSizeMB  int64         `yaml:"log_max_file_size_mb" validate:"min=0"`
+	LogMaxTotalSizeMB int64         `yaml:"log_max_total_size_mb" validate:"min=0"`
+}
+
+// validate reports the problems of the garbage collector settings. A
+// negative interval would make the garbage collector panic.
+func (c *GCConfig) validate(report func(key string, err error)) {
+	if c.Enabled && c.Interval <= 0 {
+		report("gc.interval", fmt.Errorf("interval must be positive"))
+	}
+
+	if c.LogMaxAge < 0 {
+		report("gc.log_max_age", fmt.Errorf("log_max_age must not be negative"))
+	}
+}
+
+// CapacityConfig is the configuration of the host capacity the runners of
+// all pools are admitted against.
+type CapacityConfig struct {
+	// Enabled rejects scaling up a pool when the host has not enough vCPUs
+	// or memory left for another runner.
+	Enabled bool `yaml:"enabled"`
+
+	// Vcpus and MemoryMib are the total vCPUs and memory of the host,
+	// defaulting to the detected ones.
+	Vcpus     int64 `yaml:"vcpus"`
+	MemoryMib int64 `yaml:"memory_mib"`
+
+	// ReservedVcpus and ReservedMemoryMib are kept for the host itself.
+	ReservedVcpus     int64 `yaml:"reserved_vcpus"`
+	ReservedMemoryMib int64 `yaml:"reserved_memory_mib"`
+
+	// VcpuOvercommitRatio and MemoryOvercommitRatio multiply the vCPUs and
+	// the memory available to the runners, defaulting to 1 (no overcommit).
+	VcpuOvercommitRatio   float64 `yaml:"vcpu_overcommit_ratio"`
+	MemoryOvercommitRatio float64 `yaml:"memory_overcommit_ratio"`
+}
+
+// GetVcpuOvercommitRatio returns the vCPU overcommit ratio, defaulting to 1.
+func (c *CapacityConfig) GetVcpuOvercommitRatio() float64 {
+	if c.VcpuOvercommitRatio <= 0 {
+		return 1
+	}
+
+	return c.VcpuOvercommitRatio
+}
+
+// GetMemoryOvercommitRatio returns the memory overcommit ratio, defaulting
+// to 1.
+func (c *CapacityConfig) GetMemoryOvercommitRatio() float64 {
+	if c.MemoryOvercommitRatio <= 0 {
+		return 1
+	}
+
+	return c.MemoryOvercommitRatio
+}
+
+// Validate validates the amounts and the overcommit ratios of the capacity
+// configuration.
+func (c *CapacityConfig) Validate() error {
+	if c.Vcpus < 0 || c.MemoryMib < 0 || c.ReservedVcpus < 0 || c.ReservedMemoryMib < 0 {
+		return fmt.Errorf("vcpus, memory_mib and the reserved amounts must not be negative")
+	}
+
+	if c.Vcpus > 0 && c.ReservedVcpus >= c.Vcpus {
+		return fmt.Errorf("reserved_vcpus must be less than vcpus")
+	}
+
+	if c.MemoryMib > 0 && c.ReservedMemoryMib >= c.MemoryMib {
+		return fmt.Errorf("reserved_memory_mib must be less than memory_mib")
+	}
+
+	if c.VcpuOvercommitRatio < 0 || c.MemoryOvercommitRatio < 0 {
+		return fmt.Errorf("overcommit ratios must not be negative")
+	}
+
+	return nil
+}
+
+// NotificationsConfig is the configuration of the notifications sent to
+// external sinks when a pool needs attention.
+type NotificationsConfig struct {
+	Sinks []*NotificationSinkConfig `yaml:"sinks"`
+
+	// ScaleFailureThreshold is the number of consecutive scale failures of a
+	// pool triggering a scale_failures notification.
+	ScaleFailureThreshold int `yaml:"scale_failure_threshold"`
+
+	// CrashLoopThreshold is the number of runner crashes of a pool within
+	// CrashLoopWindow triggering a crash_loop notification. A crash is a
+	// virtual machine failing, or exiting on its own within
+	// CrashLoopMinUptime.
+	CrashLoopThreshold int           `yaml:"crash_loop_threshold"`
+	CrashLoopWindow    time.Duration `yaml:"crash_loop_window"`
+	CrashLoopMinUptime time.Duration `yaml:"crash_loop_min_uptime"`
+}
+
+// NotificationSinkConfig is the configuration of a notification sink.
+type NotificationSinkConfig struct {
+	Name string `yaml:"name"`
+
+	// Type is either webhook, posting the notification as JSON or as the
+	// rendered Body template, or slack, posting a Slack message.
+	Type    string            `yaml:"type"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+
+	// URLFile is a file URL is read from instead, e.g. for Slack webhook
+	// URLs, which contain a secret.
+	URLFile string `yaml:"url_file"`
+
+	// Triggers are the notifications sent to the sink, defaulting to all.
+	Triggers []string `yaml:"triggers"`
+
+	// Timeout is the timeout of a delivery attempt. Failed deliveries are
+	// attempted up to MaxAttempts times, waiting Backoff after the first
+	// attempt and doubling it after each one.
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+}
+
+const (
+	// NotificationSinkWebhook posts the notifications to a generic webhook.
+	NotificationSinkWebhook = "webhook"
+
+	// NotificationSinkSlack posts the notifications to a Slack-compatible
+	// incoming webhook.
+	NotificationSinkSlack = "slack"
+
+	// NotificationTriggerScaleFailures is sent when a pool fails to scale
+	// several times in a row.
+	NotificationTriggerScaleFailures = "scale_failures"
+
+	// NotificationTriggerPoolPaused is sent when a pool is paused.
+	NotificationTriggerPoolPaused = "pool_paused"
+
+	// NotificationTriggerCrashLoop is sent when the runners of a pool keep
+	// crashing.
+	NotificationTriggerCrashLoop = "crash_loop"
+
+	defaultScaleFailureThreshold   = 3
+	defaultCrashLoopThreshold      = 3
+	defaultCrashLoopWindow         = 10 * time.Minute
+	defaultCrashLoopMinUptime      = 30 * time.Second
+	defaultNotificationTimeout     = 10 * time.Second
+	defaultNotificationMaxAttempts = 5
+	defaultNotificationBackoff     = 1 * time.Second
+	defaultNotificationMaxBackoff  = 1 * time.Minute
+)
+
+// GetScaleFailureThreshold returns the scale failure threshold, defaulting
+// to 3.
+func (c *NotificationsConfig) GetScaleFailureThreshold() int {
+	if c.ScaleFailureThreshold <= 0 {
+		return defaultScaleFailureThreshold
+	}
+
+	return c.ScaleFailureThreshold
+}
+
+// GetCrashLoopThreshold returns the crash loop threshold, defaulting to 3.
+func (c *NotificationsConfig) GetCrashLoopThreshold() int {
+	if c.CrashLoopThreshold <= 0 {
+		return defaultCrashLoopThreshold
+	}
+
+	return c.CrashLoopThreshold
+}
+
+// GetCrashLoopWindow returns the crash loop window, defaulting to 10
+// minutes.
+func (c *NotificationsConfig) GetCrashLoopWindow() time.Duration {
+	if c.CrashLoopWindow <= 0 {
+		return defaultCrashLoopWindow
+	}
+
+	return c.CrashLoopWindow
+}
+
+// GetCrashLoopMinUptime returns the crash loop minimum uptime, defaulting to
+// 30 seconds.
+func (c *NotificationsConfig) GetCrashLoopMinUptime() time.Duration {
+	if c.CrashLoopMinUptime <= 0 {
+		return defaultCrashLoopMinUptime
+	}
+
+	return c.CrashLoopMinUptime
+}
+
+// Validate validates the thresholds and the sinks of the notifications
+// configuration.
+func (c *NotificationsConfig) Validate() error {
+	if c.ScaleFailureThreshold < 0 || c.CrashLoopThreshold < 0 || c.CrashLoopWindow < 0 || c.CrashLoopMinUptime < 0 {
+		return fmt.Errorf("thresholds, crash_loop_window and crash_loop_min_uptime must not be negative")
+	}
+
+	names := make(map[string]struct{})
+	for _, sink := range c.Sinks {
+		if _, ok := names[sink.Name]; ok {
+			return fmt.Errorf("duplicate sink name %q", sink.Name)
+		}
+		names[sink.Name] = struct{}{}
+
+		if err := sink.Validate(); err != nil {
+			return fmt.Errorf("sink %s: %w", sink.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetTriggers returns the notifications sent to the sink, defaulting to all.
+func (c *NotificationSinkConfig) GetTriggers() []string {
+	if len(c.Triggers) == 0 {
+		return []string{NotificationTriggerScaleFailures, NotificationTriggerPoolPaused, NotificationTriggerCrashLoop}
+	}
+
+	return c.Triggers
+}
+
+// GetTimeout returns the timeout of a delivery attempt, defaulting to 10
+// seconds.
+func (c *NotificationSinkConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultNotificationTimeout
+	}
+
+	return c.Timeout
+}
+
+// GetMaxAttempts returns the maximum number of delivery attempts, defaulting
+// to 5.
+func (c *NotificationSinkConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return defaultNotificationMaxAttempts
+	}
+
+	return c.MaxAttempts
+}
+
+// GetBackoff returns the wait after the first failed delivery attempt,
+// defaulting to 1 second.
+func (c *NotificationSinkConfig) GetBackoff() time.Duration {
+	if c.Backoff <= 0 {
+		return defaultNotificationBackoff
+	}
+
+	return c.Backoff
+}
+
+// Validate validates the type, the URL, the body template and the triggers
+// of the sink.
+func (c *NotificationSinkConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch c.Type {
+	case NotificationSinkWebhook:
+		if _, err := template.New(c.Name).Funcs(notificationTemplateFuncs).Parse(c.Body); err != nil {
+			return fmt.Errorf("body: %w", err)
+		}
+	case NotificationSinkSlack:
+		if c.Body != "" {
+			return fmt.Errorf("body is only supported by %s sinks", NotificationSinkWebhook)
+		}
+	default:
+		return fmt.Errorf("unknown type %q, must be one of: %s, %s", c.Type, NotificationSinkWebhook, NotificationSinkSlack)
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL, got %q", c.URL)
+	}
+
+	for _, trigger := range c.Triggers {
+		switch trigger {
+		case NotificationTriggerScaleFailures, NotificationTriggerPoolPaused, NotificationTriggerCrashLoop:
+		default:
+			return fmt.Errorf("unknown trigger %q, must be one of: %s, %s, %s", trigger,
+				NotificationTriggerScaleFailures, NotificationTriggerPoolPaused, NotificationTriggerCrashLoop)
+		}
+	}
+
+	if c.Timeout < 0 || c.MaxAttempts < 0 || c.Backoff < 0 {
+		return fmt.Errorf("timeout, max_attempts and backoff must not be negative")
+	}
+
+	return nil
+}
+
 type GitHubConfig struct {
 	AppPrivateKey string `yaml:"app_private_key" validate:"required"`
 	AppID         int64  `yaml:"app_id" validate:"required"`
+	WebhookSecret string `yaml:"webhook_secret" validate:""`
+
+	// AppPrivateKeyFile and WebhookSecretFile are files AppPrivateKey and
+	// WebhookSecret are read from instead.
+	AppPrivateKeyFile string `yaml:"app_private_key_file"`
+	WebhookSecretFile string `yaml:"webhook_secret_file"`
 }
 
 type RunnerConfig struct {
-	Name            string   `yaml:"name" validate:"required"`
-	ImagePullPolicy string   `yaml:"image_pull_policy" validate:"required,oneof=always never ifnotpresent"`
-	Image           string   `yaml:"image" validate:"required"`
-	Organization    string   `yaml:"organization" validate:"required"`
-	GroupID         int64    `yaml:"group_id" validate:"required"`
-	Labels          []string `yaml:"labels" validate:"required"`
+	Name                 string        `yaml:"name" validate:"required"`
+	ImagePullPolicy      string        `yaml:"image_pull_policy"`
+	Image                string        `yaml:"image" validate:"required"`
+	ImageRefreshInterval time.Duration `yaml:"image_refresh_interval" validate:""`
+	Scope                string        `yaml:"scope"`
+	Organization         string        `yaml:"organization"`
+	Repository           string        `yaml:"repository"`
+	Enterprise           string        `yaml:"enterprise"`
+	GroupID              int64         `yaml:"group_id" validate:"required"`
+	Labels               []string      `yaml:"labels" validate:"required"`
+}
+
+const (
+	// RunnerScopeOrganization registers runners on an organization. This is
+	// the default scope.
+	RunnerScopeOrganization = "organization"
+
+	// RunnerScopeRepository registers runners on a single repository.
+	RunnerScopeRepository = "repository"
+
+	// RunnerScopeEnterprise registers runners on an enterprise.
+	RunnerScopeEnterprise = "enterprise"
+)
+
+const (
+	// ImagePullPolicyAlways resolves the image against the registry on every
+	// scale up and pulls it if the digest changed.
+	ImagePullPolicyAlways = "always"
+
+	// ImagePullPolicyNever never pulls the image, it must be present locally.
+	ImagePullPolicyNever = "never"
+
+	// ImagePullPolicyIfNotPresent pulls the image only if it's not present
+	// locally. This is the default image pull policy.
+	ImagePullPolicyIfNotPresent = "ifnotpresent"
+
+	defaultImageRefreshInterval = 5 * time.Minute
+)
+
+// GetImagePullPolicy returns the normalized (lowercase) image pull policy,
+// defaulting to ifnotpresent.
+func (c *RunnerConfig) GetImagePullPolicy() string {
+	if c.ImagePullPolicy == "" {
+		return ImagePullPolicyIfNotPresent
+	}
+
+	return strings.ToLower(c.ImagePullPolicy)
+}
+
+// GetImageRefreshInterval returns the image refresh interval, defaulting to 5
+// minutes.
+func (c *RunnerConfig) GetImageRefreshInterval() time.Duration {
+	if c.ImageRefreshInterval <= 0 {
+		return defaultImageRefreshInterval
+	}
+
+	return c.ImageRefreshInterval
+}
+
+// GetScope returns the scope the runners are registered on, defaulting to
+// organization.
+func (c *RunnerConfig) GetScope() string {
+	if c.Scope == "" {
+		return RunnerScopeOrganization
+	}
+
+	return c.Scope
+}
+
+// GetRepository returns the owner and the name of the repository the runners
+// are registered on.
+func (c *RunnerConfig) GetRepository() (string, string) {
+	owner, name, _ := strings.Cut(c.Repository, "/")
+	return owner, name
+}
+
+// Validate validates the image pull policy and the scope specific settings of
+// the runner configuration.
+func (c *RunnerConfig) Validate() error {
+	switch c.GetImagePullPolicy() {
+	case ImagePullPolicyAlways, ImagePullPolicyNever, ImagePullPolicyIfNotPresent:
+	default:
+		return fmt.Errorf("unknown image pull policy %q, must be one of: Always, Never, IfNotPresent", c.ImagePullPolicy)
+	}
+
+	switch c.GetScope() {
+	case RunnerScopeOrganization:
+		if c.Organization == "" {
+			return fmt.Errorf("organization is required when scope is %s", RunnerScopeOrganization)
+		}
+	case RunnerScopeRepository:
+		owner, name := c.GetRepository()
+		if owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("repository must be in the owner/name format when scope is %s, got %q", RunnerScopeRepository, c.Repository)
+		}
+	case RunnerScopeEnterprise:
+		if c.Enterprise == "" {
+			return fmt.Errorf("enterprise is required when scope is %s", RunnerScopeEnterprise)
+		}
+	default:
+		return fmt.Errorf("unknown scope %q, must be one of: %s, %s, %s", c.Scope, RunnerScopeOrganization, RunnerScopeRepository, RunnerScopeEnterprise)
+	}
+
+	return nil
 }
 
 type FirecrackerConfig struct {
@@ -47,11 +489,485 @@ type FirecrackerConfig struct {
 	KernelArgs      string                   `yaml:"kernel_args"`
 	MachineConfig   FirecrackerMachineConfig `yaml:"machine_config"`
 	Metadata        map[string]interface{}   `yaml:"metadata"`
+	WarmBoot        *WarmBootConfig          `yaml:"warm_boot"`
+	Snapshotter     string                   `yaml:"snapshotter"`
+	RootfsSizeMib   int64                    `yaml:"rootfs_size_mib"`
+	Drives          []*DriveConfig           `yaml:"drives"`
+
+	// RootfsRateLimiter limits the I/O of the root drive.
+	RootfsRateLimiter *RateLimiterConfig `yaml:"rootfs_rate_limiter"`
+
+	// Balloon adds a memory balloon device to the runners, allowing the host
+	// to reclaim their unused memory.
+	Balloon *BalloonConfig `yaml:"balloon"`
+
+	// Jailer launches the VMs through the Firecracker jailer.
+	Jailer *JailerConfig `yaml:"jailer"`
+}
+
+const (
+	// SnapshotterDevmapper uses the thin devices of a devmapper pool as root
+	// drives. This is the default snapshotter.
+	SnapshotterDevmapper = "devmapper"
+
+	// SnapshotterOverlayfs flattens the overlayfs snapshot of the image into
+	// an ext4 image file used as root drive.
+	SnapshotterOverlayfs = "overlayfs"
+
+	// SnapshotterErofs flattens the erofs snapshot of the image into an ext4
+	// image file used as root drive.
+	SnapshotterErofs = "erofs"
+
+	// SnapshotterNative flattens the native (plain copy) snapshot of the
+	// image into an ext4 image file used as root drive.
+	SnapshotterNative = "native"
+
+	defaultRootfsSizeMib = 10240
+)
+
+// GetSnapshotter returns the Containerd snapshotter the runner image is
+// unpacked with, defaulting to devmapper.
+func (c *FirecrackerConfig) GetSnapshotter() string {
+	if c.Snapshotter == "" {
+		return SnapshotterDevmapper
+	}
+
+	return c.Snapshotter
+}
+
+// GetRootfsSizeMib returns the size of the ext4 image file root drives,
+// defaulting to 10 GiB.
+func (c *FirecrackerConfig) GetRootfsSizeMib() int64 {
+	if c.RootfsSizeMib <= 0 {
+		return defaultRootfsSizeMib
+	}
+
+	return c.RootfsSizeMib
+}
+
+// Validate validates the snapshotter of the Firecracker configuration.
+func (c *FirecrackerConfig) Validate() error {
+	switch c.GetSnapshotter() {
+	case SnapshotterDevmapper, SnapshotterOverlayfs, SnapshotterErofs, SnapshotterNative:
+	default:
+		return fmt.Errorf("unknown snapshotter %q, must be one of: %s, %s, %s, %s",
+			c.Snapshotter, SnapshotterDevmapper, SnapshotterOverlayfs, SnapshotterErofs, SnapshotterNative)
+	}
+
+	if c.RootfsSizeMib < 0 {
+		return fmt.Errorf("rootfs_size_mib must not be negative")
+	}
+
+	if err := c.MachineConfig.Validate(); err != nil {
+		return fmt.Errorf("machine_config: %w", err)
+	}
+
+	if c.RootfsRateLimiter != nil {
+		if err := c.RootfsRateLimiter.Validate(); err != nil {
+			return fmt.Errorf("rootfs_rate_limiter: %w", err)
+		}
+	}
+
+	if c.Balloon != nil {
+		if c.Balloon.AmountMib < 0 || c.Balloon.AmountMib >= c.MachineConfig.MemSizeMib {
+			return fmt.Errorf("balloon: amount_mib must be between 0 and mem_size_mib")
+		}
+
+		if c.Balloon.StatsPollingInterval < 0 {
+			return fmt.Errorf("balloon: stats_polling_interval must not be negative")
+		}
+	}
+
+	if c.IsJailerEnabled() {
+		if err := c.Jailer.Validate(); err != nil {
+			return fmt.Errorf("jailer: %w", err)
+		}
+	}
+
+	names := make(map[string]struct{})
+	for i, drive := range c.Drives {
+		if err := drive.Validate(); err != nil {
+			return fmt.Errorf("drives[%d]: %w", i, err)
+		}
+
+		if _, ok := names[drive.Name]; ok {
+			return fmt.Errorf("drives[%d]: duplicate drive name %q", i, drive.Name)
+		}
+		names[drive.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// JailerConfig represents the Firecracker jailer configuration of a pool. The
+// jailer runs each VM in its own chroot and cgroup, as an unprivileged user,
+// so that a VM escape doesn't land in the context of the server.
+type JailerConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// BinaryPath is the path to the jailer binary.
+	BinaryPath string `yaml:"binary_path"`
+
+	// ChrootBaseDir is the directory the chroots are created in. The root
+	// drives, scratch drives and warm boot templates are hard linked into
+	// the chroots, so it must be on the same filesystem as
+	// /var/lib/fireactions.
+	ChrootBaseDir string `yaml:"chroot_base_dir"`
+
+	// UID and GID are the user and group the VMs run as.
+	UID int `yaml:"uid"`
+	GID int `yaml:"gid"`
+
+	// CgroupVersion is the cgroup version used by the jailer, 1 or 2.
+	CgroupVersion int `yaml:"cgroup_version"`
+
+	// Cgroups are the cgroup files set for each VM, e.g. cpu.max or
+	// memory.max.
+	Cgroups map[string]string `yaml:"cgroups"`
+}
+
+const (
+	defaultJailerBinaryPath    = "jailer"
+	defaultJailerChrootBaseDir = "/var/lib/fireactions/jailer"
+	defaultJailerCgroupVersion = 2
+)
+
+// IsJailerEnabled returns true if the VMs are launched through the jailer.
+func (c *FirecrackerConfig) IsJailerEnabled() bool {
+	return c.Jailer != nil && c.Jailer.Enabled
+}
+
+// GetBinaryPath returns the path to the jailer binary, defaulting to jailer.
+func (c *JailerConfig) GetBinaryPath() string {
+	if c.BinaryPath == "" {
+		return defaultJailerBinaryPath
+	}
+
+	return c.BinaryPath
+}
+
+// GetChrootBaseDir returns the directory the chroots are created in,
+// defaulting to /var/lib/fireactions/jailer.
+func (c *JailerConfig) GetChrootBaseDir() string {
+	if c.ChrootBaseDir == "" {
+		return defaultJailerChrootBaseDir
+	}
+
+	return c.ChrootBaseDir
+}
+
+// GetCgroupVersion returns the cgroup version, defaulting to 2.
+func (c *JailerConfig) GetCgroupVersion() int {
+	if c.CgroupVersion == 0 {
+		return defaultJailerCgroupVersion
+	}
+
+	return c.CgroupVersion
+}
+
+// Validate validates the jailer configuration.
+func (c *JailerConfig) Validate() error {
+	if c.UID <= 0 || c.GID <= 0 {
+		return fmt.Errorf("uid and gid must be set to an unprivileged user and group")
+	}
+
+	if version := c.GetCgroupVersion(); version != 1 && version != 2 {
+		return fmt.Errorf("unknown cgroup_version %d, must be 1 or 2", version)
+	}
+
+	if !filepath.IsAbs(c.GetChrootBaseDir()) {
+		return fmt.Errorf("chroot_base_dir must be absolute")
+	}
+
+	for file := range c.Cgroups {
+		if controller, _, ok := strings.Cut(file, "."); !ok || controller == "" || strings.Contains(file, "/") {
+			return fmt.Errorf("invalid cgroup file %q, must be <controller>.<file>", file)
+		}
+	}
+
+	return nil
+}
+
+// WarmBootConfig represents the warm boot configuration of a pool. With warm
+// boot, a template VM is booted once per runner image and snapshotted when
+// the runner agent waits for its configuration, and runners are restored from
+// the snapshot instead of booting the kernel and the userspace.
+type WarmBootConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	ReadyTimeout time.Duration `yaml:"ready_timeout"`
+}
+
+const (
+	defaultWarmBootReadyTimeout = 2 * time.Minute
+)
+
+// IsWarmBootEnabled returns true if the runners are restored from a warm boot
+// snapshot.
+func (c *FirecrackerConfig) IsWarmBootEnabled() bool {
+	return c.WarmBoot != nil && c.WarmBoot.Enabled
+}
+
+// GetReadyTimeout returns how long to wait for the runner agent of the
+// template VM to be ready, defaulting to 2 minutes.
+func (c *WarmBootConfig) GetReadyTimeout() time.Duration {
+	if c.ReadyTimeout <= 0 {
+		return defaultWarmBootReadyTimeout
+	}
+
+	return c.ReadyTimeout
+}
+
+// NetworkConfig represents the CNI network configuration of the runners of a
+// pool. The first interface is the primary one: its address is configured by
+// the kernel and it provides access to MMDS.
+type NetworkConfig struct {
+	ConfDir    string                    `yaml:"conf_dir"`
+	BinDirs    []string                  `yaml:"bin_dirs"`
+	Interfaces []*NetworkInterfaceConfig `yaml:"interfaces"`
+}
+
+// NetworkInterfaceConfig represents a network interface of the runners,
+// attached to a CNI network.
+type NetworkInterfaceConfig struct {
+	// NetworkName is the name of the CNI network configuration list.
+	NetworkName string `yaml:"network_name"`
+
+	// IfName is the name of the interface created by CNI in the network
+	// namespace of the runner.
+	IfName string `yaml:"if_name"`
+
+	// IPRange optionally restricts the addresses of the interface to a CIDR
+	// or to a first-last range, requested from the IPAM plugin with the IP
+	// CNI argument.
+	IPRange string `yaml:"ip_range"`
+
+	// RxRateLimiter and TxRateLimiter limit the traffic received and sent by
+	// the runner on the interface.
+	RxRateLimiter *RateLimiterConfig `yaml:"rx_rate_limiter"`
+	TxRateLimiter *RateLimiterConfig `yaml:"tx_rate_limiter"`
+}
+
+const (
+	defaultCNIConfDir     = "/etc/cni/net.d"
+	defaultCNIBinDir      = "/opt/cni/bin"
+	defaultCNINetworkName = "fireactions"
+)
+
+// GetNetwork returns the network configuration of the pool, with the defaults
+// applied: a single interface attached to the fireactions CNI network.
+func (c *PoolConfig) GetNetwork() *NetworkConfig {
+	network := &NetworkConfig{ConfDir: defaultCNIConfDir, BinDirs: []string{defaultCNIBinDir}}
+	if c.Network == nil || len(c.Network.Interfaces) == 0 {
+		network.Interfaces = []*NetworkInterfaceConfig{{NetworkName: defaultCNINetworkName}}
+	}
+
+	if c.Network != nil {
+		if c.Network.ConfDir != "" {
+			network.ConfDir = c.Network.ConfDir
+		}
+
+		if len(c.Network.BinDirs) > 0 {
+			network.BinDirs = c.Network.BinDirs
+		}
+
+		for _, iface := range c.Network.Interfaces {
+			network.Interfaces = append(network.Interfaces, &NetworkInterfaceConfig{NetworkName: iface.NetworkName, IfName: iface.IfName, IPRange: iface.IPRange,
+				RxRateLimiter: iface.RxRateLimiter, TxRateLimiter: iface.TxRateLimiter})
+		}
+	}
+
+	for i, iface := range network.Interfaces {
+		if iface.IfName == "" {
+			iface.IfName = fmt.Sprintf("eth%d", i)
+		}
+	}
+
+	return network
+}
+
+// Validate validates the network interfaces of the network configuration.
+func (c *NetworkConfig) Validate() error {
+	ifNames := make(map[string]struct{})
+	for i, iface := range c.Interfaces {
+		if iface.NetworkName == "" {
+			return fmt.Errorf("interfaces[%d]: network_name is required", i)
+		}
+
+		if _, ok := ifNames[iface.IfName]; ok {
+			return fmt.Errorf("interfaces[%d]: duplicate interface name %q", i, iface.IfName)
+		}
+		ifNames[iface.IfName] = struct{}{}
+
+		for name, limiter := range map[string]*RateLimiterConfig{"rx_rate_limiter": iface.RxRateLimiter, "tx_rate_limiter": iface.TxRateLimiter} {
+			if limiter == nil {
+				continue
+			}
+
+			if err := limiter.Validate(); err != nil {
+				return fmt.Errorf("interfaces[%d]: %s: %w", i, name, err)
+			}
+		}
+
+		if iface.IPRange == "" {
+			continue
+		}
+
+		if _, err := parseIPRange(iface.IPRange); err != nil {
+			return fmt.Errorf("interfaces[%d]: ip_range: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// DriveConfig represents an additional drive attached to the runners of a
+// pool, after the root drive.
+type DriveConfig struct {
+	// Name is the ID of the drive, also used as the file name of scratch
+	// drives.
+	Name string `yaml:"name"`
+
+	// Type is either cache, an image file shared read-only by all runners, or
+	// scratch, an empty ext4 drive created for each runner and removed when
+	// the runner exits.
+	Type string `yaml:"type"`
+
+	// Path is the path of the image file of cache drives.
+	Path string `yaml:"path"`
+
+	// SizeMib is the size of scratch drives.
+	SizeMib int64 `yaml:"size_mib"`
+
+	// MountPath is where the runner agent mounts the drive. The drive isn't
+	// mounted if empty.
+	MountPath string `yaml:"mount_path"`
+
+	RateLimiter *RateLimiterConfig `yaml:"rate_limiter"`
+}
+
+const (
+	DriveTypeCache   = "cache"
+	DriveTypeScratch = "scratch"
+)
+
+// IsReadOnly returns true if the drive is attached read-only.
+func (c *DriveConfig) IsReadOnly() bool {
+	return c.Type == DriveTypeCache
+}
+
+// Validate validates the drive configuration.
+func (c *DriveConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if c.Name == "rootfs" || strings.ContainsAny(c.Name, "/ ") {
+		return fmt.Errorf("invalid name %q", c.Name)
+	}
+
+	switch c.Type {
+	case DriveTypeCache:
+		if c.Path == "" {
+			return fmt.Errorf("path is required for cache drives")
+		}
+	case DriveTypeScratch:
+		if c.SizeMib <= 0 {
+			return fmt.Errorf("size_mib must be positive for scratch drives")
+		}
+	default:
+		return fmt.Errorf("unknown type %q, must be one of: %s, %s", c.Type, DriveTypeCache, DriveTypeScratch)
+	}
+
+	if c.MountPath != "" && !filepath.IsAbs(c.MountPath) {
+		return fmt.Errorf("mount_path must be absolute")
+	}
+
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Validate(); err != nil {
+			return fmt.Errorf("rate_limiter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RateLimiterConfig represents a Firecracker rate limiter, limiting the
+// bandwidth in bytes and the number of operations with token buckets.
+type RateLimiterConfig struct {
+	Bandwidth *TokenBucketConfig `yaml:"bandwidth"`
+	Ops       *TokenBucketConfig `yaml:"ops"`
+}
+
+// TokenBucketConfig represents a token bucket of Size tokens, refilled over
+// RefillTime, with an initial OneTimeBurst of extra tokens.
+type TokenBucketConfig struct {
+	Size         int64         `yaml:"size"`
+	OneTimeBurst int64         `yaml:"one_time_burst"`
+	RefillTime   time.Duration `yaml:"refill_time"`
+}
+
+// Validate validates the token buckets of the rate limiter.
+func (c *RateLimiterConfig) Validate() error {
+	for name, bucket := range map[string]*TokenBucketConfig{"bandwidth": c.Bandwidth, "ops": c.Ops} {
+		if bucket == nil {
+			continue
+		}
+
+		if bucket.Size <= 0 {
+			return fmt.Errorf("%s: size must be positive", name)
+		}
+
+		if bucket.OneTimeBurst < 0 {
+			return fmt.Errorf("%s: one_time_burst must not be negative", name)
+		}
+
+		if bucket.RefillTime < time.Millisecond {
+			return fmt.Errorf("%s: refill_time must be at least 1ms", name)
+		}
+	}
+
+	return nil
+}
+
+// BalloonConfig represents the memory balloon device of the runners.
+type BalloonConfig struct {
+	// AmountMib is the target size of the balloon, i.e. the memory taken
+	// from the guest.
+	AmountMib int64 `yaml:"amount_mib"`
+
+	// DeflateOnOom deflates the balloon when the guest runs out of memory.
+	DeflateOnOom bool `yaml:"deflate_on_oom"`
+
+	// StatsPollingInterval is the interval at which the balloon statistics
+	// are updated. The statistics are disabled if zero.
+	StatsPollingInterval time.Duration `yaml:"stats_polling_interval"`
 }
 
 type FirecrackerMachineConfig struct {
 	VcpuCount  int64 `yaml:"vcpu_count"`
 	MemSizeMib int64 `yaml:"mem_size_mib"`
+
+	// Smt enables simultaneous multithreading in the guest.
+	Smt bool `yaml:"smt"`
+
+	// CPUTemplate masks the CPU features exposed to the guest, either C3 or
+	// T2. Intel only.
+	CPUTemplate string `yaml:"cpu_template"`
+
+	// TrackDirtyPages enables dirty page tracking, required for diff
+	// snapshots.
+	TrackDirtyPages bool `yaml:"track_dirty_pages"`
+}
+
+// Validate validates the CPU template of the machine configuration.
+func (c *FirecrackerMachineConfig) Validate() error {
+	switch models.CPUTemplate(c.CPUTemplate) {
+	case "", models.CPUTemplateC3, models.CPUTemplateT2:
+	default:
+		return fmt.Errorf("unknown cpu_template %q, must be one of: %s, %s", c.CPUTemplate, models.CPUTemplateC3, models.CPUTemplateT2)
+	}
+
+	return nil
 }
 
 // DefaultConfig creates a new Config with default values.
@@ -59,12 +975,15 @@ func DefaultConfig() *Config {
 	c := &Config{
 		BindAddress:      ":8080",
 		Metrics:          &MetricsConfig{Enabled: true, Address: ":8081"},
+		GC:               &GCConfig{Enabled: true, Interval: 10 * time.Minute, LogMaxAge: 7 * 24 * time.Hour, LogMaxFileSizeMB: 100, LogMaxTotalSizeMB: 1024},
+		Capacity:         &CapacityConfig{Enabled: false},
 		BasicAuthEnabled: false,
 		BasicAuthUsers:   map[string]string{},
 		GitHub:           &GitHubConfig{AppPrivateKey: "", AppID: 0},
 		Pools:            []*PoolConfig{},
 		LogLevel:         "debug",
 		Debug:            false,
+		StatePath:        "/var/lib/fireactions/state.db",
 	}
 
 	return c
@@ -72,6 +991,21 @@ func DefaultConfig() *Config {
 
 // NewConfigFromFile creates a new Config from a file.
 func NewConfig(path string) (*Config, error) {
+	c, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	return c, nil
+}
+
+// LoadConfig loads a Config from a file without validating it.
+func LoadConfig(path string) (*Config, error) {
 	c := DefaultConfig()
 	c.path = path
 
@@ -80,27 +1014,679 @@ func NewConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	err = c.Validate()
+	return c, nil
+}
+
+// RenderPools returns the pools as YAML, with their templates resolved.
+// Unset settings are omitted.
+func (c *Config) RenderPools() ([]byte, error) {
+	b, err := yaml.Marshal(map[string]interface{}{"pools": c.Pools})
 	if err != nil {
-		return nil, fmt.Errorf("validate: %w", err)
+		return nil, err
 	}
 
-	return c, nil
+	var pools map[string]interface{}
+	if err := yaml.Unmarshal(b, &pools); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(pruneSettings(pools))
 }
 
-// LoadFromFile loads the configuration from a file.
+// pruneSettings removes the unset and zero settings, and the empty lists and
+// maps, from the value.
+func pruneSettings(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if v = pruneSettings(v); v == nil {
+				delete(value, key)
+				continue
+			}
+
+			value[key] = v
+		}
+
+		if len(value) == 0 {
+			return nil
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = pruneSettings(v)
+		}
+
+		if len(value) == 0 {
+			return nil
+		}
+	default:
+		if value == nil || reflect.ValueOf(value).IsZero() {
+			return nil
+		}
+	}
+
+	return value
+}
+
+// Load loads the configuration from its file and the included files,
+// expanding ${VAR} environment variables, then reads the secret files. All
+// the problems found are returned, joined.
 func (c *Config) Load() error {
-	file, err := os.OpenFile(c.path, os.O_RDONLY, 0)
+	var errs []error
+	c.load(func(err *ConfigError) { errs = append(errs, err) })
+
+	return errors.Join(errs...)
+}
+
+// load loads the configuration like Load, reporting the problems found with
+// their position. Loading goes on after a problem as long as the rest of the
+// configuration can be read, so that all of them are reported at once.
+func (c *Config) load(report func(err *ConfigError)) {
+	reportAt := func(file string, line int, err error) {
+		report(&ConfigError{File: file, Line: line, Key: c.getKeyAt(file, line), Err: err})
+	}
+	reportKey := func(key string, err error) {
+		position := c.getPosition(key)
+		report(&ConfigError{File: position.file, Line: position.line, Key: key, Err: err})
+	}
+	reportLine := func(line int, err error) { reportAt(c.path, line, err) }
+
+	c.positions = make(map[string]configPosition)
+	node := readConfigFile(c.path, reportLine)
+	if node == nil {
+		return
+	}
+
+	indexConfigNode(c.positions, c.path, "", node)
+	expandEnvNode(node, reportLine)
+
+	templates := map[string]map[string]interface{}{}
+	if node := getMappingValue(node, "templates"); node != nil {
+		if err := node.Decode(&templates); err != nil {
+			reportYAMLError(err, reportLine)
+			return
+		}
+	}
+
+	resolvePoolTemplates(node, templates, reportLine)
+
+	if err := node.Decode(c); err != nil {
+		// The other settings are still decoded after type errors.
+		reportYAMLError(err, reportLine)
+		if !isYAMLTypeError(err) {
+			return
+		}
+	}
+
+	for _, pattern := range c.getIncludePatterns() {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			reportKey("includes", err)
+			continue
+		}
+
+		for _, file := range files {
+			c.readIncludeFile(file, templates, reportAt)
+		}
+	}
+
+	c.loadSecretFiles(reportKey)
+}
+
+// checkIncludedPoolName returns an error naming the file defining the pool
+// with the given name, if it's already defined. Pools can't be redefined by
+// included files, which are merged in no particular order.
+func (c *Config) checkIncludedPoolName(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	for i, pool := range c.Pools {
+		if pool.Name != name {
+			continue
+		}
+
+		position := c.getPosition(fmt.Sprintf("pools[%d]", i))
+		return fmt.Errorf("duplicate pool name %q, already defined in %s:%d", name, position.file, position.line)
+	}
+
+	return nil
+}
+
+// includeConfig is the configuration of an included file.
+type includeConfig struct {
+	Pools []*PoolConfig `yaml:"pools"`
+}
+
+// envVarPattern matches the ${VAR} and ${VAR:-default} environment variable
+// references of configuration files, and $$ escaping a $.
+var envVarPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// yamlLinePattern matches the line prefix of the errors of the YAML parser
+// and decoder.
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// readConfigFile parses a configuration file, reporting the problems found
+// at their line. It returns nil if the file can't be parsed.
+func readConfigFile(path string, report func(line int, err error)) *yaml.Node {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		report(0, fmt.Errorf("open file: %w", err))
+		return nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		reportYAMLError(err, report)
+		return nil
+	}
+
+	return &node
+}
+
+// reportYAMLError reports each error of the YAML parser or decoder at its
+// line.
+func reportYAMLError(err error, report func(line int, err error)) {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		groups := yamlLinePattern.FindStringSubmatch(message)
+		if groups == nil {
+			report(0, errors.New(message))
+			continue
+		}
+
+		line, _ := strconv.Atoi(groups[1])
+		report(line, errors.New(groups[2]))
+	}
+}
+
+// isYAMLTypeError returns true if err only reports values of the wrong type,
+// which the YAML decoder skips.
+func isYAMLTypeError(err error) bool {
+	var typeErr *yaml.TypeError
+	return errors.As(err, &typeErr)
+}
+
+// readIncludeFile reads an included file, which may only define pools, and
+// adds its pools to the configuration. They are indexed after the ones
+// already loaded.
+func (c *Config) readIncludeFile(path string, templates map[string]map[string]interface{}, report func(file string, line int, err error)) {
+	reportLine := func(line int, err error) { report(path, line, err) }
+
+	node := readConfigFile(path, reportLine)
+	if node == nil {
+		return
+	}
+
+	if pools := getMappingValue(node, "pools"); pools != nil && pools.Kind == yaml.SequenceNode {
+		for i, pool := range pools.Content {
+			key := fmt.Sprintf("pools[%d]", len(c.Pools)+i)
+			c.positions[key] = configPosition{file: path, line: pool.Line}
+			indexConfigNode(c.positions, path, key, pool)
+		}
+	}
+
+	expandEnvNode(node, reportLine)
+	checkIncludeNode(node, reportLine)
+	resolvePoolTemplates(node, templates, reportLine)
+
+	include := &includeConfig{}
+	if err := node.Decode(include); err != nil {
+		reportYAMLError(err, reportLine)
+		if !isYAMLTypeError(err) {
+			return
+		}
+	}
+
+	// Duplicate pools are kept, so that the following ones keep the index
+	// of their position.
+	for _, pool := range include.Pools {
+		key := fmt.Sprintf("pools[%d].name", len(c.Pools))
+		if err := c.checkIncludedPoolName(pool.Name); err != nil {
+			reportLine(c.getPosition(key).line, err)
+		}
+
+		c.Pools = append(c.Pools, pool)
+	}
+}
+
+// indexConfigNode records the positions of the settings of the node, nested
+// in the setting with the given key.
+func indexConfigNode(positions map[string]configPosition, file, key string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			indexConfigNode(positions, file, key, child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := node.Content[i].Value
+			if key != "" {
+				child = key + "." + child
+			}
+
+			positions[child] = configPosition{file: file, line: node.Content[i].Line}
+			indexConfigNode(positions, file, child, node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", key, i)
+			positions[child] = configPosition{file: file, line: item.Line}
+			indexConfigNode(positions, file, child, item)
+		}
+	}
+}
+
+// getMappingValue returns the value of the key of the mapping of a document,
+// or nil if not set.
+func getMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// resolvePoolTemplates replaces the pools of a document extending a template
+// with the template, deeply merged with the settings of the pool, reporting
+// the pools which can't be resolved at their line.
+func resolvePoolTemplates(node *yaml.Node, templates map[string]map[string]interface{}, report func(line int, err error)) {
+	pools := getMappingValue(node, "pools")
+	if pools == nil || pools.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, pool := range pools.Content {
+		extends := getMappingValue(pool, "extends")
+		if extends == nil {
+			continue
+		}
+
+		var settings map[string]interface{}
+		if err := pool.Decode(&settings); err != nil {
+			reportYAMLError(err, report)
+			continue
+		}
+
+		template, err := resolveTemplate(templates, extends.Value, map[string]bool{})
+		if err != nil {
+			report(extends.Line, fmt.Errorf("pool %v: %w", settings["name"], err))
+			continue
+		}
+
+		delete(settings, "extends")
+		if err := pool.Encode(mergeSettings(template, settings)); err != nil {
+			report(pool.Line, err)
+		}
+	}
+}
+
+// resolveTemplate returns a copy of the settings of a template, merged into
+// the ones of the template it extends, if any.
+func resolveTemplate(templates map[string]map[string]interface{}, name string, seen map[string]bool) (map[string]interface{}, error) {
+	template, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+
+	if seen[name] {
+		return nil, fmt.Errorf("template %s extends itself", name)
+	}
+	seen[name] = true
+
+	template = deepcopy.Map(template)
+	extends, ok := template["extends"]
+	if !ok {
+		return template, nil
+	}
+
+	parent, ok := extends.(string)
+	if !ok {
+		return nil, fmt.Errorf("template %s: extends must be the name of a template", name)
+	}
+
+	base, err := resolveTemplate(templates, parent, seen)
 	if err != nil {
-		return fmt.Errorf("open file: %w", err)
+		return nil, err
 	}
 
-	defer file.Close()
+	delete(template, "extends")
+	return mergeSettings(base, template), nil
+}
+
+// mergeSettings merges the settings of override into base and returns base.
+// Nested settings are merged, other settings of override, including lists,
+// replace the ones of base.
+func mergeSettings(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		overrideMap, ok := value.(map[string]interface{})
+		baseMap, baseOk := base[key].(map[string]interface{})
+		if ok && baseOk {
+			base[key] = mergeSettings(baseMap, overrideMap)
+			continue
+		}
+
+		base[key] = value
+	}
 
-	return yaml.NewDecoder(file).Decode(c)
+	return base
 }
 
-// Validate validates the configuration.
+// expandEnvNode expands the environment variables referenced by the scalar
+// values of the node and its children, reporting all unset ones.
+func expandEnvNode(node *yaml.Node, report func(line int, err error)) {
+	if node.Kind == yaml.MappingNode {
+		// Keys are not expanded.
+		for i := 1; i < len(node.Content); i += 2 {
+			expandEnvNode(node.Content[i], report)
+		}
+
+		return
+	}
+
+	for _, child := range node.Content {
+		expandEnvNode(child, report)
+	}
+
+	if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "$") {
+		return
+	}
+
+	unset := false
+	value := envVarPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := envVarPattern.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(groups[1]); ok {
+			return value
+		}
+
+		if groups[2] != "" {
+			return groups[3]
+		}
+
+		report(node.Line, fmt.Errorf("environment variable %s is not set", groups[1]))
+		unset = true
+		return match
+	})
+	if unset {
+		return
+	}
+
+	if value != node.Value && node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		// Plain values are typed after expansion, e.g. max_runners: ${MAX}.
+		node.Tag = ""
+	}
+
+	node.Value = value
+}
+
+// checkIncludeNode reports the settings of an included file other than
+// pools.
+func checkIncludeNode(node *yaml.Node, report func(line int, err error)) {
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+
+	mapping := node.Content[0]
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if key := mapping.Content[i]; key.Value != "pools" {
+			report(key.Line, fmt.Errorf("%s can't be set in an included file, only pools", key.Value))
+		}
+	}
+}
+
+// getIncludePatterns returns the absolute glob patterns of the included
+// files.
+func (c *Config) getIncludePatterns() []string {
+	patterns := make([]string, 0, len(c.Includes))
+	for _, pattern := range c.Includes {
+		patterns = append(patterns, c.resolvePath(pattern))
+	}
+
+	return patterns
+}
+
+// resolvePath resolves a path relative to the directory of the configuration
+// file.
+func (c *Config) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(c.path), path)
+}
+
+// loadSecretFiles reads the secrets set with their *_file variant, reporting
+// the ones which can't be read with the key of their setting.
+func (c *Config) loadSecretFiles(report func(key string, err error)) {
+	if c.GitHub != nil {
+		if err := c.readSecretFile(c.GitHub.AppPrivateKeyFile, &c.GitHub.AppPrivateKey); err != nil {
+			report("github.app_private_key_file", err)
+		}
+
+		if err := c.readSecretFile(c.GitHub.WebhookSecretFile, &c.GitHub.WebhookSecret); err != nil {
+			report("github.webhook_secret_file", err)
+		}
+	}
+
+	if c.BasicAuthUsersFile != "" {
+		users, err := c.readBasicAuthUsersFile()
+		if err != nil {
+			report("basic_auth_users_file", err)
+		}
+
+		if c.BasicAuthUsers == nil {
+			c.BasicAuthUsers = make(map[string]string, len(users))
+		}
+
+		for username, password := range users {
+			if _, ok := c.BasicAuthUsers[username]; ok {
+				report("basic_auth_users_file", fmt.Errorf("user %s is also set in basic_auth_users", username))
+				continue
+			}
+
+			c.BasicAuthUsers[username] = password
+		}
+	}
+
+	if c.Notifications != nil {
+		for i, sink := range c.Notifications.Sinks {
+			if sink == nil {
+				continue
+			}
+
+			if err := c.readSecretFile(sink.URLFile, &sink.URL); err != nil {
+				report(fmt.Sprintf("notifications.sinks[%d].url_file", i), err)
+			}
+		}
+	}
+}
+
+// readSecretFile sets value to the content of the file at path, without the
+// surrounding whitespace, if path is set.
+func (c *Config) readSecretFile(path string, value *string) error {
+	if path == "" {
+		return nil
+	}
+
+	if *value != "" {
+		return fmt.Errorf("the value is also set inline")
+	}
+
+	b, err := os.ReadFile(c.resolvePath(path))
+	if err != nil {
+		return err
+	}
+
+	*value = strings.TrimSpace(string(b))
+	return nil
+}
+
+// readBasicAuthUsersFile reads the username:password lines of the basic
+// authentication users file. Empty lines and lines starting with # are
+// ignored.
+func (c *Config) readBasicAuthUsersFile() (map[string]string, error) {
+	b, err := os.ReadFile(c.resolvePath(c.BasicAuthUsersFile))
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]string)
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		username, password, ok := strings.Cut(line, ":")
+		if !ok || username == "" {
+			return nil, fmt.Errorf("line %d: expected username:password", i+1)
+		}
+
+		users[username] = password
+	}
+
+	return users, nil
+}
+
+// Validate validates the configuration, returning the first problem found.
+// Check reports all of them, with their position.
 func (c *Config) Validate() error {
-	return validator.New().Struct(c)
+	var err error
+	c.validate(func(key string, problem error) {
+		if err == nil {
+			err = fmt.Errorf("%s: %w", key, problem)
+		}
+	})
+
+	return err
+}
+
+// validate reports the problems of the configuration, with the key of the
+// setting they were found in.
+func (c *Config) validate(report func(key string, err error)) {
+	c.validateStruct(report)
+
+	if c.GC != nil {
+		c.GC.validate(report)
+	}
+
+	if c.Capacity != nil {
+		if err := c.Capacity.Validate(); err != nil {
+			report("capacity", err)
+		}
+	}
+
+	if c.Notifications != nil {
+		if err := c.Notifications.Validate(); err != nil {
+			report("notifications", err)
+		}
+	}
+
+	names := make(map[string]struct{}, len(c.Pools))
+	for i, pool := range c.Pools {
+		if pool == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("pools[%d]", i)
+		if _, ok := names[pool.Name]; ok && pool.Name != "" {
+			report(key+".name", fmt.Errorf("duplicate pool name %q", pool.Name))
+		}
+		names[pool.Name] = struct{}{}
+
+		pool.validate(key, report)
+	}
+}
+
+// validate reports the problems of the settings of the pool with the given
+// key.
+func (c *PoolConfig) validate(key string, report func(key string, err error)) {
+	if c.MinRunners > c.MaxRunners {
+		report(key+".min_runners", fmt.Errorf("min_runners %d is greater than max_runners %d", c.MinRunners, c.MaxRunners))
+	}
+
+	if err := c.validateSchedules(); err != nil {
+		report(key+".schedules", err)
+	}
+
+	if err := c.GetNetwork().Validate(); err != nil {
+		report(key+".network", err)
+	}
+
+	if c.Firecracker != nil {
+		if err := c.Firecracker.Validate(); err != nil {
+			report(key+".firecracker", err)
+		}
+	}
+
+	if c.Runner != nil {
+		if err := c.Runner.Validate(); err != nil {
+			report(key+".runner", err)
+		}
+	}
+}
+
+// validateStruct reports the failed validation tags of the settings.
+func (c *Config) validateStruct(report func(key string, err error)) {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		return name
+	})
+
+	err := validate.Struct(c)
+	if err == nil {
+		return
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		report("", err)
+		return
+	}
+
+	for _, fieldErr := range fieldErrs {
+		// The namespace starts with the name of the Config type.
+		_, key, _ := strings.Cut(fieldErr.Namespace(), ".")
+		report(key, describeFieldError(fieldErr))
+	}
+}
+
+// describeFieldError returns the failed validation tag of a setting as an
+// error.
+func describeFieldError(err validator.FieldError) error {
+	switch err.Tag() {
+	case "required", "required_if":
+		return fmt.Errorf("%s is required", err.Field())
+	case "min":
+		if err.Kind() == reflect.Slice || err.Kind() == reflect.Map {
+			return fmt.Errorf("%s must have at least %s item(s)", err.Field(), err.Param())
+		}
+
+		return fmt.Errorf("%s must be at least %s", err.Field(), err.Param())
+	case "oneof":
+		return fmt.Errorf("%s must be one of: %s, got %q", err.Field(), strings.ReplaceAll(err.Param(), " ", ", "), err.Value())
+	case "hostname_port":
+		return fmt.Errorf("%s must be a host:port address, got %q", err.Field(), err.Value())
+	default:
+		return fmt.Errorf("%s failed the %s validation", err.Field(), err.Tag())
+	}
 }
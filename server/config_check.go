@@ -0,0 +1,236 @@
+package server
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/distribution/reference"
+)
+
+// ConfigError is a problem of a setting of the configuration, found by Check
+// or while loading the configuration files.
+type ConfigError struct {
+	// File and Line are the position of the setting in the configuration
+	// files. Line is 0 if unknown, e.g. for a missing setting.
+	File string
+	Line int
+
+	// Key is the key of the setting in dotted notation, e.g.
+	// pools[0].runner.image. It's empty if the problem isn't specific to a
+	// setting, e.g. a syntax error.
+	Key string
+
+	Err error
+}
+
+// Error returns the problem prefixed with the position and the key of the
+// setting.
+func (e *ConfigError) Error() string {
+	position := e.File
+	if e.Line != 0 {
+		position = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %s", position, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", position, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Check runs the checks of Validate, and deeper checks of the host and of the
+// values of the settings which otherwise only fail when the pools start: the
+// kernel images and binaries exist, the runner images are valid references,
+// the GitHub App private key is a PEM encoded RSA key and the CNI networks
+// are configured. Unlike Validate, all the problems found are returned.
+func (c *Config) Check() []*ConfigError {
+	var errs []*ConfigError
+	report := func(key string, err error) {
+		position := c.getPosition(key)
+		errs = append(errs, &ConfigError{File: position.file, Line: position.line, Key: key, Err: err})
+	}
+
+	c.validate(report)
+
+	if c.GitHub != nil && c.GitHub.AppPrivateKey != "" {
+		key := "github.app_private_key"
+		if c.GitHub.AppPrivateKeyFile != "" {
+			key = "github.app_private_key_file"
+		}
+
+		if err := checkPrivateKey(c.GitHub.AppPrivateKey); err != nil {
+			report(key, err)
+		}
+	}
+
+	for i, pool := range c.Pools {
+		if pool == nil {
+			continue
+		}
+
+		pool.check(fmt.Sprintf("pools[%d]", i), report)
+	}
+
+	return errs
+}
+
+// CheckConfigFile loads the configuration file and checks it like Check,
+// returning all the problems found, including the ones found while loading
+// it, e.g. values of the wrong type, unset environment variables or secret
+// files which can't be read. The checks of Check only run once the
+// configuration loads without problems.
+func CheckConfigFile(path string) []*ConfigError {
+	c := DefaultConfig()
+	c.path = path
+
+	var errs []*ConfigError
+	c.load(func(err *ConfigError) { errs = append(errs, err) })
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return c.Check()
+}
+
+// check runs the deeper checks of the settings of the pool with the given
+// key, which Validate doesn't run.
+func (c *PoolConfig) check(key string, report func(key string, err error)) {
+	// The networks of an invalid network configuration can't be loaded.
+	network := c.GetNetwork()
+	if network.Validate() == nil {
+		for i, iface := range network.Interfaces {
+			// The default network isn't set in the configuration.
+			ifaceKey := key
+			if c.Network != nil && len(c.Network.Interfaces) > 0 {
+				ifaceKey = fmt.Sprintf("%s.network.interfaces[%d].network_name", key, i)
+			}
+
+			if _, err := libcni.LoadConfList(network.ConfDir, iface.NetworkName); err != nil {
+				report(ifaceKey, fmt.Errorf("network %s: %w", iface.NetworkName, err))
+			}
+		}
+	}
+
+	if c.Firecracker != nil {
+		c.Firecracker.check(key+".firecracker", report)
+	}
+
+	if c.Runner != nil && c.Runner.Image != "" {
+		if _, err := reference.ParseDockerRef(c.Runner.Image); err != nil {
+			report(key+".runner.image", fmt.Errorf("invalid image reference %q: %w", c.Runner.Image, err))
+		}
+	}
+}
+
+// check runs the deeper checks of the Firecracker settings with the given key.
+func (c *FirecrackerConfig) check(key string, report func(key string, err error)) {
+	if c.BinaryPath != "" {
+		if _, err := exec.LookPath(c.BinaryPath); err != nil {
+			report(key+".binary_path", err)
+		}
+	}
+
+	if c.KernelImagePath == "" {
+		report(key+".kernel_image_path", fmt.Errorf("kernel_image_path is required"))
+	} else if info, err := os.Stat(c.KernelImagePath); err != nil {
+		report(key+".kernel_image_path", err)
+	} else if info.IsDir() {
+		report(key+".kernel_image_path", fmt.Errorf("%s is a directory", c.KernelImagePath))
+	}
+
+	if c.IsJailerEnabled() {
+		if _, err := exec.LookPath(c.Jailer.GetBinaryPath()); err != nil {
+			report(key+".jailer.binary_path", err)
+		}
+	}
+}
+
+// checkPrivateKey returns an error if the key isn't a PEM encoded RSA private
+// key, as GitHub Apps use.
+func checkPrivateKey(key string) error {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return fmt.Errorf("private key is not PEM encoded")
+	}
+
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parsing private key: %w", err)
+	}
+
+	if _, ok := parsed.(*rsa.PrivateKey); !ok {
+		return fmt.Errorf("private key is not an RSA key")
+	}
+
+	return nil
+}
+
+// getPosition returns the position of the setting with the given key, or of
+// the closest setting it's nested in, e.g. the pool for a setting inherited
+// from a template.
+func (c *Config) getPosition(key string) configPosition {
+	for key != "" {
+		if position, ok := c.positions[key]; ok {
+			return position
+		}
+
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			break
+		}
+
+		key = key[:i]
+	}
+
+	return configPosition{file: c.path}
+}
+
+// getKeyAt returns the key of the setting at the line of the file, or an
+// empty string if unknown. The setting itself is preferred over the items of
+// a list on the same line, and a setting over the list item it starts.
+func (c *Config) getKeyAt(file string, line int) string {
+	if line == 0 {
+		return ""
+	}
+
+	var found string
+	for key, position := range c.positions {
+		if position.file != file || position.line != line {
+			continue
+		}
+
+		if found == "" || isBetterKey(key, found) {
+			found = key
+		}
+	}
+
+	return found
+}
+
+// isBetterKey returns true if key names the setting at a line better than
+// other, see getKeyAt.
+func isBetterKey(key, other string) bool {
+	if isItem, isOtherItem := strings.HasSuffix(key, "]"), strings.HasSuffix(other, "]"); isItem != isOtherItem {
+		return isOtherItem
+	}
+
+	if len(key) != len(other) {
+		return len(key) > len(other)
+	}
+
+	return key < other
+}
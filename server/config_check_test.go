@@ -0,0 +1,254 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Check(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"vmlinux":                  "",
+		"github.key":               string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"cni/fireactions.conflist": `{"cniVersion": "1.0.0", "name": "fireactions", "plugins": [{"type": "bridge"}]}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pool := `
+- name: %s
+  extends: base
+  runner:
+    name: %s
+`
+
+	writeConfig := func(t *testing.T, pools string, include string) string {
+		config := `
+bind_address: 127.0.0.1:8080
+github:
+  app_id: 12345
+  app_private_key_file: github.key
+templates:
+  base:
+    min_runners: 1
+    max_runners: 2
+    runner:
+      image: ghcr.io/hostinger/fireactions/runner:latest
+      image_pull_policy: ifnotpresent
+      organization: hostinger
+      group_id: 1
+      labels: [self-hosted]
+    firecracker:
+      kernel_image_path: ` + filepath.Join(dir, "vmlinux") + `
+    network:
+      conf_dir: ` + filepath.Join(dir, "cni") + `
+includes:
+- conf.d/*.yaml
+pools:` + pools
+
+		path := filepath.Join(dir, "config.yaml")
+		if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.MkdirAll(filepath.Join(dir, "conf.d"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "conf.d", "a.yaml"), []byte("pools:"+include), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		path := writeConfig(t, strings.ReplaceAll(pool, "%s", "a"), strings.ReplaceAll(pool, "%s", "b"))
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Empty(t, config.Check())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		path := writeConfig(t, `
+- name: a
+  extends: base
+  min_runners: 3
+  runner:
+    name: a
+    image: ghcr.io/hostinger/fireactions/runner:LATEST:1
+  firecracker:
+    kernel_image_path: `+filepath.Join(dir, "missing")+`
+- name: b
+  extends: base
+  network:
+    interfaces:
+    - network_name: storage
+  runner:
+    name: b
+    image_pull_policy: sometimes
+`, strings.ReplaceAll(pool, "%s", "c"))
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := config.Check()
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+
+		assert.ElementsMatch(t, []string{
+			path + ":25: pools[0].min_runners: min_runners 3 is greater than max_runners 2",
+			path + ":28: pools[0].runner.image: invalid image reference \"ghcr.io/hostinger/fireactions/runner:LATEST:1\": invalid reference format",
+			path + ":30: pools[0].firecracker.kernel_image_path: stat " + filepath.Join(dir, "missing") + ": no such file or directory",
+			path + ":35: pools[1].network.interfaces[0].network_name: network storage: no net configuration with name \"storage\" in " + filepath.Join(dir, "cni"),
+			path + ":36: pools[1].runner: unknown image pull policy \"sometimes\", must be one of: Always, Never, IfNotPresent",
+		}, messages)
+	})
+
+	t.Run("InvalidPool", func(t *testing.T) {
+		path := writeConfig(t, `
+- extends: base
+  runner:
+    name: a
+- name: b
+  extends: base
+  runner:
+    name: b
+    image: ""
+- name: c
+  extends: base
+  min_runners: -1
+  runner:
+    name: c
+- name: c
+  extends: base
+  runner:
+    name: c
+`, strings.ReplaceAll(pool, "%s", "d"))
+
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messages := []string{}
+		for _, err := range config.Check() {
+			messages = append(messages, err.Error())
+		}
+
+		assert.ElementsMatch(t, []string{
+			path + ":23: pools[0].name: name is required",
+			path + ":30: pools[1].runner.image: image is required",
+			path + ":33: pools[2].min_runners: min_runners must be at least 0",
+			path + ":36: pools[3].name: duplicate pool name \"c\"",
+		}, messages)
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		config, err := LoadConfig("testdata/config1.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config.BindAddress = ""
+		config.GitHub.AppID = 0
+
+		messages := []string{}
+		for _, err := range config.Check() {
+			messages = append(messages, err.Error())
+		}
+
+		assert.Contains(t, messages, "testdata/config1.yaml:2: bind_address: bind_address is required")
+		assert.Contains(t, messages, "testdata/config1.yaml:41: github.app_id: app_id is required")
+	})
+
+	t.Run("InvalidPrivateKey", func(t *testing.T) {
+		config, err := NewConfig("testdata/config1.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := config.Check()
+		if assert.NotEmpty(t, errs) {
+			assert.Equal(t, "testdata/config1.yaml", errs[0].File)
+			assert.Equal(t, 39, errs[0].Line)
+			assert.Equal(t, "github.app_private_key", errs[0].Key)
+			assert.EqualError(t, errs[0].Err, "private key is not PEM encoded")
+		}
+	})
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	config := `bind_address: 127.0.0.1:8080
+github:
+  app_id: abc
+  app_private_key_file: missing.key
+  webhook_secret: ${FIREACTIONS_TEST_UNSET}
+includes:
+- conf.d/*.yaml
+pools:
+- name: a
+  extends: missing
+`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "conf.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "conf.d", "a.yaml"), []byte("pools:\n- name: a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []string{}
+	for _, err := range CheckConfigFile(filepath.Join(dir, "config.yaml")) {
+		messages = append(messages, strings.ReplaceAll(err.Error(), dir+"/", ""))
+	}
+
+	assert.Equal(t, []string{
+		"config.yaml:5: github.webhook_secret: environment variable FIREACTIONS_TEST_UNSET is not set",
+		"config.yaml:10: pools[0].extends: pool a: template missing not found",
+		"config.yaml:3: github.app_id: cannot unmarshal !!str `abc` into int64",
+		"conf.d/a.yaml:2: pools[1].name: duplicate pool name \"a\", already defined in config.yaml:9",
+		"config.yaml:4: github.app_private_key_file: open missing.key: no such file or directory",
+	}, messages)
+
+	errs := CheckConfigFile(filepath.Join(dir, "missing.yaml"))
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "", errs[0].Key)
+		assert.ErrorIs(t, errs[0], os.ErrNotExist)
+	}
+}
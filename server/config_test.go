@@ -1,7 +1,11 @@
 package server
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,4 +17,647 @@ func TestNewConfig(t *testing.T) {
 	}
 
 	assert.Equal(t, "testdata/config1.yaml", config.path)
+	assert.Equal(t, ImagePullPolicyAlways, config.Pools[0].Runner.GetImagePullPolicy())
+	assert.Equal(t, 10*time.Minute, config.Pools[0].Runner.GetImageRefreshInterval())
+	assert.Equal(t, ImagePullPolicyIfNotPresent, config.Pools[1].Runner.GetImagePullPolicy())
+	assert.Equal(t, defaultImageRefreshInterval, config.Pools[1].Runner.GetImageRefreshInterval())
+	assert.True(t, config.Pools[0].Firecracker.IsWarmBootEnabled())
+	assert.Equal(t, 90*time.Second, config.Pools[0].Firecracker.WarmBoot.GetReadyTimeout())
+	assert.False(t, config.Pools[1].Firecracker.IsWarmBootEnabled())
+	assert.False(t, config.Pools[0].Firecracker.IsJailerEnabled())
+	assert.True(t, config.Pools[1].Firecracker.IsJailerEnabled())
+	assert.Equal(t, defaultJailerChrootBaseDir, config.Pools[1].Firecracker.Jailer.GetChrootBaseDir())
+	assert.Equal(t, map[string]string{"cpu.max": "200000 100000", "memory.max": "5G"}, config.Pools[1].Firecracker.Jailer.Cgroups)
+	assert.True(t, config.Capacity.Enabled)
+	assert.Equal(t, 2.0, config.Capacity.GetVcpuOvercommitRatio())
+	assert.Equal(t, 1.0, config.Capacity.GetMemoryOvercommitRatio())
+	assert.Equal(t, 10, config.Pools[0].Priority)
+	assert.Equal(t, 0, config.Pools[1].Priority)
+	assert.Equal(t, 5, config.Notifications.GetScaleFailureThreshold())
+	assert.Equal(t, defaultCrashLoopWindow, config.Notifications.GetCrashLoopWindow())
+	assert.Equal(t, []string{NotificationTriggerScaleFailures, NotificationTriggerCrashLoop}, config.Notifications.Sinks[0].GetTriggers())
+	assert.Len(t, config.Notifications.Sinks[1].GetTriggers(), 3)
+	assert.Equal(t, 10, config.Notifications.Sinks[1].GetMaxAttempts())
+	assert.Equal(t, defaultNotificationBackoff, config.Notifications.Sinks[1].GetBackoff())
+}
+
+func TestConfig_Load(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pool := `
+- name: %s
+  max_runners: ${MAX_RUNNERS}
+  min_runners: 1
+  runner:
+    name: %s
+    image: ghcr.io/hostinger/fireactions/runner:latest
+    image_pull_policy: ifnotpresent
+    organization: ${ORGANIZATION:-hostinger}
+    group_id: 1
+    labels: [self-hosted]
+  firecracker:
+    kernel_args: "console=ttyS0 $${HOME}"
+`
+
+	newConfigDir := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.yaml"), `
+bind_address: ${BIND_ADDRESS}
+basic_auth_enabled: true
+basic_auth_users:
+  admin: ${ADMIN_PASSWORD}
+basic_auth_users_file: users
+github:
+  app_id: 12345
+  app_private_key_file: secrets/github.key
+  webhook_secret: "${WEBHOOK_SECRET}"
+notifications:
+  sinks:
+  - name: ops
+    type: slack
+    url_file: slack-url
+includes:
+- conf.d/*.yaml
+pools:`+strings.ReplaceAll(pool, "%s", "main"))
+		writeFile(t, filepath.Join(dir, "users"), "# users\nuser1:password1\n\nuser2:pass:word2\n")
+		writeFile(t, filepath.Join(dir, "secrets", "github.key"), "KEY\n")
+		writeFile(t, filepath.Join(dir, "slack-url"), "https://hooks.slack.com/services/T000/B000/XXXX\n")
+		writeFile(t, filepath.Join(dir, "conf.d", "b.yaml"), "pools:"+strings.ReplaceAll(pool, "%s", "b"))
+		writeFile(t, filepath.Join(dir, "conf.d", "a.yaml"), "pools:"+strings.ReplaceAll(pool, "%s", "a"))
+		writeFile(t, filepath.Join(dir, "conf.d", "ignored.yml"), "pools:"+strings.ReplaceAll(pool, "%s", "ignored"))
+		return dir
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Setenv("BIND_ADDRESS", "127.0.0.1:8080")
+		t.Setenv("ADMIN_PASSWORD", "secret")
+		t.Setenv("WEBHOOK_SECRET", "webhook")
+		t.Setenv("MAX_RUNNERS", "5")
+		dir := newConfigDir(t)
+
+		config, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "127.0.0.1:8080", config.BindAddress)
+		assert.Equal(t, map[string]string{"admin": "secret", "user1": "password1", "user2": "pass:word2"}, config.BasicAuthUsers)
+		assert.Equal(t, "KEY", config.GitHub.AppPrivateKey)
+		assert.Equal(t, "webhook", config.GitHub.WebhookSecret)
+		assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXXX", config.Notifications.Sinks[0].URL)
+		assert.Len(t, config.Pools, 3)
+		for i, name := range []string{"main", "a", "b"} {
+			assert.Equal(t, name, config.Pools[i].Name)
+			assert.Equal(t, 5, config.Pools[i].MaxRunners)
+			assert.Equal(t, "hostinger", config.Pools[i].Runner.Organization)
+			assert.Equal(t, "console=ttyS0 ${HOME}", config.Pools[i].Firecracker.KernelArgs)
+		}
+	})
+
+	t.Run("UnsetVariable", func(t *testing.T) {
+		t.Setenv("BIND_ADDRESS", "127.0.0.1:8080")
+		t.Setenv("MAX_RUNNERS", "5")
+		dir := newConfigDir(t)
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.ErrorContains(t, err, "config.yaml:5: basic_auth_users.admin: environment variable ADMIN_PASSWORD is not set")
+		assert.ErrorContains(t, err, "config.yaml:10: github.webhook_secret: environment variable WEBHOOK_SECRET is not set")
+	})
+
+	t.Run("InvalidInclude", func(t *testing.T) {
+		t.Setenv("BIND_ADDRESS", "127.0.0.1:8080")
+		t.Setenv("ADMIN_PASSWORD", "secret")
+		t.Setenv("WEBHOOK_SECRET", "webhook")
+		t.Setenv("MAX_RUNNERS", "5")
+		dir := newConfigDir(t)
+		writeFile(t, filepath.Join(dir, "conf.d", "c.yaml"), "bind_address: 0.0.0.0:8080\n")
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.ErrorContains(t, err, "bind_address can't be set in an included file")
+	})
+
+	t.Run("DuplicatePool", func(t *testing.T) {
+		t.Setenv("BIND_ADDRESS", "127.0.0.1:8080")
+		t.Setenv("ADMIN_PASSWORD", "secret")
+		t.Setenv("WEBHOOK_SECRET", "webhook")
+		t.Setenv("MAX_RUNNERS", "5")
+		dir := newConfigDir(t)
+		writeFile(t, filepath.Join(dir, "conf.d", "c.yaml"), "pools:"+strings.ReplaceAll(pool, "%s", "a"))
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.EqualError(t, err, filepath.Join(dir, "conf.d", "c.yaml")+":2: pools[3].name: duplicate pool name \"a\", already defined in "+filepath.Join(dir, "conf.d", "a.yaml")+":2")
+	})
+
+	t.Run("SecretSetTwice", func(t *testing.T) {
+		t.Setenv("BIND_ADDRESS", "127.0.0.1:8080")
+		t.Setenv("ADMIN_PASSWORD", "secret")
+		t.Setenv("WEBHOOK_SECRET", "webhook")
+		t.Setenv("MAX_RUNNERS", "5")
+		dir := newConfigDir(t)
+		writeFile(t, filepath.Join(dir, "users"), "admin:password\n")
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.ErrorContains(t, err, "user admin is also set in basic_auth_users")
+	})
+}
+
+func TestConfig_Load_Templates(t *testing.T) {
+	writeConfig := func(t *testing.T, content string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		return dir
+	}
+
+	templates := `
+bind_address: 127.0.0.1:8080
+github:
+  app_id: 12345
+  app_private_key: KEY
+templates:
+  base:
+    min_runners: 1
+    max_runners: 2
+    runner:
+      image: ghcr.io/hostinger/fireactions/runner:latest
+      image_pull_policy: ifnotpresent
+      organization: hostinger
+      group_id: 1
+      labels: [self-hosted, fireactions]
+    firecracker:
+      kernel_args: console=ttyS0
+      machine_config:
+        vcpu_count: 2
+        mem_size_mib: 2048
+  large:
+    extends: base
+    max_runners: 10
+    firecracker:
+      machine_config:
+        mem_size_mib: 8192
+`
+
+	t.Run("Success", func(t *testing.T) {
+		dir := writeConfig(t, templates+`
+includes:
+- conf.d/*.yaml
+pools:
+- name: small
+  extends: base
+  runner:
+    name: small
+    labels: [self-hosted, small]
+- name: large
+  extends: large
+  runner:
+    name: large
+`)
+		if err := os.MkdirAll(filepath.Join(dir, "conf.d"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "conf.d", "a.yaml"), []byte("pools:\n- name: included\n  extends: base\n  runner:\n    name: included\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, config.Pools, 3)
+
+		small := config.Pools[0]
+		assert.Equal(t, 2, small.MaxRunners)
+		assert.Equal(t, "small", small.Runner.Name)
+		assert.Equal(t, "hostinger", small.Runner.Organization)
+		assert.Equal(t, []string{"self-hosted", "small"}, small.Runner.Labels)
+		assert.Equal(t, int64(2048), small.Firecracker.MachineConfig.MemSizeMib)
+
+		large := config.Pools[1]
+		assert.Equal(t, 1, large.MinRunners)
+		assert.Equal(t, 10, large.MaxRunners)
+		assert.Equal(t, []string{"self-hosted", "fireactions"}, large.Runner.Labels)
+		assert.Equal(t, int64(2), large.Firecracker.MachineConfig.VcpuCount)
+		assert.Equal(t, int64(8192), large.Firecracker.MachineConfig.MemSizeMib)
+
+		included := config.Pools[2]
+		assert.Equal(t, "included", included.Name)
+		assert.Equal(t, "ghcr.io/hostinger/fireactions/runner:latest", included.Runner.Image)
+
+		b, err := config.RenderPools()
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), "mem_size_mib: 8192")
+		assert.NotContains(t, string(b), "extends")
+		assert.NotContains(t, string(b), "app_private_key")
+	})
+
+	t.Run("UnknownTemplate", func(t *testing.T) {
+		dir := writeConfig(t, templates+`
+pools:
+- name: small
+  extends: medium
+  runner:
+    name: small
+`)
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.ErrorContains(t, err, "pool small: template medium not found")
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		dir := writeConfig(t, templates+`
+  a:
+    extends: b
+  b:
+    extends: a
+pools:
+- name: small
+  extends: a
+  runner:
+    name: small
+`)
+
+		_, err := NewConfig(filepath.Join(dir, "config.yaml"))
+		assert.ErrorContains(t, err, "template a extends itself")
+	})
+}
+
+func TestConfig_Validate_Notifications(t *testing.T) {
+	tests := []struct {
+		name    string
+		sink    *NotificationSinkConfig
+		wantErr bool
+	}{
+		{name: "Webhook", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "http://localhost:9000/hook"}},
+		{name: "WebhookBody", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "http://localhost:9000/hook", Body: `{"text": {{ json .Message }}}`}},
+		{name: "Slack", sink: &NotificationSinkConfig{Name: "test", Type: "slack", URL: "https://hooks.slack.com/services/T000", Triggers: []string{"pool_paused"}}},
+		{name: "NoName", sink: &NotificationSinkConfig{Type: "webhook", URL: "http://localhost:9000/hook"}, wantErr: true},
+		{name: "UnknownType", sink: &NotificationSinkConfig{Name: "test", Type: "email", URL: "http://localhost:9000/hook"}, wantErr: true},
+		{name: "InvalidURL", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "localhost:9000"}, wantErr: true},
+		{name: "InvalidBody", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "http://localhost:9000/hook", Body: "{{ .Message"}, wantErr: true},
+		{name: "SlackBody", sink: &NotificationSinkConfig{Name: "test", Type: "slack", URL: "http://localhost:9000/hook", Body: "{}"}, wantErr: true},
+		{name: "UnknownTrigger", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "http://localhost:9000/hook", Triggers: []string{"job_queued"}}, wantErr: true},
+		{name: "NegativeMaxAttempts", sink: &NotificationSinkConfig{Name: "test", Type: "webhook", URL: "http://localhost:9000/hook", MaxAttempts: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config.Notifications = &NotificationsConfig{Sinks: []*NotificationSinkConfig{tt.sink}}
+
+			err = config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Notifications.Sinks[1].Name = config.Notifications.Sinks[0].Name
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_Validate_GC(t *testing.T) {
+	tests := []struct {
+		name    string
+		gc      *GCConfig
+		wantErr string
+	}{
+		{name: "Nil"},
+		{name: "Default", gc: DefaultConfig().GC},
+		{name: "Disabled", gc: &GCConfig{Enabled: false}},
+		{name: "ZeroInterval", gc: &GCConfig{Enabled: true}, wantErr: "gc.interval: interval must be positive"},
+		{name: "NegativeInterval", gc: &GCConfig{Enabled: true, Interval: -time.Minute}, wantErr: "gc.interval: interval must be positive"},
+		{name: "NegativeLogMaxAge", gc: &GCConfig{Enabled: true, Interval: time.Minute, LogMaxAge: -time.Hour}, wantErr: "gc.log_max_age: log_max_age must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config.GC = tt.gc
+
+			err = config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Capacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity *CapacityConfig
+		wantErr  bool
+	}{
+		{name: "Nil"},
+		{name: "Detected", capacity: &CapacityConfig{Enabled: true, ReservedVcpus: 2, ReservedMemoryMib: 4096}},
+		{name: "Overcommit", capacity: &CapacityConfig{Enabled: true, Vcpus: 16, MemoryMib: 65536, VcpuOvercommitRatio: 4, MemoryOvercommitRatio: 1.5}},
+		{name: "Negative", capacity: &CapacityConfig{Enabled: true, Vcpus: -1}, wantErr: true},
+		{name: "ReservedVcpus", capacity: &CapacityConfig{Enabled: true, Vcpus: 2, ReservedVcpus: 2}, wantErr: true},
+		{name: "ReservedMemory", capacity: &CapacityConfig{Enabled: true, MemoryMib: 1024, ReservedMemoryMib: 2048}, wantErr: true},
+		{name: "NegativeRatio", capacity: &CapacityConfig{Enabled: true, MemoryOvercommitRatio: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config.Capacity = tt.capacity
+
+			err = config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Pool(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(config *Config)
+		wantErr string
+	}{
+		{name: "EmptyName", modify: func(config *Config) { config.Pools[0].Name = "" }, wantErr: "pools[0].name: name is required"},
+		{name: "MissingImage", modify: func(config *Config) { config.Pools[0].Runner.Image = "" }, wantErr: "pools[0].runner.image: image is required"},
+		{name: "NegativeMinRunners", modify: func(config *Config) { config.Pools[0].MinRunners = -1 }, wantErr: "pools[0].min_runners: min_runners must be at least 0"},
+		{name: "MinRunnersGreaterThanMax", modify: func(config *Config) { config.Pools[0].MinRunners = config.Pools[0].MaxRunners + 1 }},
+		{name: "DuplicateName", modify: func(config *Config) { config.Pools[1].Name = config.Pools[0].Name }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.modify(config)
+			err = config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	t.Run("ScaleToZero", func(t *testing.T) {
+		config, err := NewConfig("testdata/config1.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config.Pools[0].MinRunners = 0
+		assert.NoError(t, config.Validate())
+	})
+}
+
+func TestConfig_Validate_ImagePullPolicy(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, policy := range []string{"Always", "never", "IfNotPresent", ""} {
+		config.Pools[0].Runner.ImagePullPolicy = policy
+		assert.NoError(t, config.Validate(), policy)
+	}
+
+	config.Pools[0].Runner.ImagePullPolicy = "sometimes"
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_Validate_Snapshotter(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, SnapshotterDevmapper, config.Pools[0].Firecracker.GetSnapshotter())
+	assert.Equal(t, SnapshotterOverlayfs, config.Pools[1].Firecracker.GetSnapshotter())
+	assert.Equal(t, int64(defaultRootfsSizeMib), config.Pools[0].Firecracker.GetRootfsSizeMib())
+	assert.Equal(t, int64(20480), config.Pools[1].Firecracker.GetRootfsSizeMib())
+
+	for _, snapshotter := range []string{"devmapper", "overlayfs", "erofs", "native", ""} {
+		config.Pools[0].Firecracker.Snapshotter = snapshotter
+		assert.NoError(t, config.Validate(), snapshotter)
+	}
+
+	config.Pools[0].Firecracker.Snapshotter = "zfs"
+	assert.Error(t, config.Validate())
+
+	config.Pools[0].Firecracker.Snapshotter = ""
+	config.Pools[0].Firecracker.RootfsSizeMib = -1
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_Validate_Drives(t *testing.T) {
+	tests := []struct {
+		name    string
+		drive   *DriveConfig
+		wantErr bool
+	}{
+		{name: "Cache", drive: &DriveConfig{Name: "cache", Type: "cache", Path: "/var/lib/cache.ext4", MountPath: "/opt/cache"}},
+		{name: "CacheMissingPath", drive: &DriveConfig{Name: "cache", Type: "cache"}, wantErr: true},
+		{name: "Scratch", drive: &DriveConfig{Name: "scratch", Type: "scratch", SizeMib: 1024}},
+		{name: "ScratchMissingSize", drive: &DriveConfig{Name: "scratch", Type: "scratch"}, wantErr: true},
+		{name: "MissingName", drive: &DriveConfig{Type: "scratch", SizeMib: 1024}, wantErr: true},
+		{name: "RootfsName", drive: &DriveConfig{Name: "rootfs", Type: "scratch", SizeMib: 1024}, wantErr: true},
+		{name: "DuplicateName", drive: &DriveConfig{Name: "toolcache", Type: "scratch", SizeMib: 1024}, wantErr: true},
+		{name: "UnknownType", drive: &DriveConfig{Name: "tmp", Type: "tmpfs"}, wantErr: true},
+		{name: "RelativeMountPath", drive: &DriveConfig{Name: "scratch", Type: "scratch", SizeMib: 1024, MountPath: "scratch"}, wantErr: true},
+		{name: "RateLimiter", drive: &DriveConfig{Name: "scratch", Type: "scratch", SizeMib: 1024,
+			RateLimiter: &RateLimiterConfig{Ops: &TokenBucketConfig{Size: 1000, RefillTime: time.Second}}}},
+		{name: "RateLimiterMissingRefillTime", drive: &DriveConfig{Name: "scratch", Type: "scratch", SizeMib: 1024,
+			RateLimiter: &RateLimiterConfig{Ops: &TokenBucketConfig{Size: 1000}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, config.Pools[0].Firecracker.Drives, 2)
+			config.Pools[0].Firecracker.Drives = config.Pools[0].Firecracker.Drives[:1]
+			config.Pools[0].Firecracker.Drives = append(config.Pools[0].Firecracker.Drives, tt.drive)
+
+			err = config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_ResourceControls(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firecracker := config.Pools[1].Firecracker
+	assert.Equal(t, FirecrackerMachineConfig{VcpuCount: 2, MemSizeMib: 4096, Smt: true, CPUTemplate: "T2"}, firecracker.MachineConfig)
+	assert.Equal(t, &TokenBucketConfig{Size: 2000, OneTimeBurst: 10000, RefillTime: time.Second}, firecracker.RootfsRateLimiter.Ops)
+	assert.Equal(t, &BalloonConfig{AmountMib: 1024, DeflateOnOom: true, StatsPollingInterval: 5 * time.Second}, firecracker.Balloon)
+	assert.Equal(t, int64(125000000), config.Pools[1].GetNetwork().Interfaces[0].TxRateLimiter.Bandwidth.Size)
+	assert.NoError(t, config.Validate())
+
+	firecracker.MachineConfig.CPUTemplate = "T2S"
+	assert.Error(t, config.Validate())
+
+	firecracker.MachineConfig.CPUTemplate = "C3"
+	firecracker.Balloon.AmountMib = 4096
+	assert.Error(t, config.Validate())
+
+	firecracker.Balloon.AmountMib = 1024
+	firecracker.RootfsRateLimiter.Ops.Size = 0
+	assert.Error(t, config.Validate())
+
+	firecracker.RootfsRateLimiter.Ops.Size = 2000
+	config.Pools[1].Network.Interfaces[0].TxRateLimiter.Bandwidth.RefillTime = 0
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_Validate_Jailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		jailer  *JailerConfig
+		wantErr bool
+	}{
+		{name: "Disabled", jailer: &JailerConfig{}},
+		{name: "Enabled", jailer: &JailerConfig{Enabled: true, UID: 1000, GID: 1000, Cgroups: map[string]string{"memory.max": "4G"}}},
+		{name: "CgroupVersion1", jailer: &JailerConfig{Enabled: true, UID: 1000, GID: 1000, CgroupVersion: 1}},
+		{name: "UnknownCgroupVersion", jailer: &JailerConfig{Enabled: true, UID: 1000, GID: 1000, CgroupVersion: 3}, wantErr: true},
+		{name: "Root", jailer: &JailerConfig{Enabled: true}, wantErr: true},
+		{name: "RelativeChrootBaseDir", jailer: &JailerConfig{Enabled: true, UID: 1000, GID: 1000, ChrootBaseDir: "jailer"}, wantErr: true},
+		{name: "InvalidCgroupFile", jailer: &JailerConfig{Enabled: true, UID: 1000, GID: 1000, Cgroups: map[string]string{"../memory.max": "4G"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config.Pools[0].Firecracker.Jailer = tt.jailer
+
+			err = config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Network(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	network := config.Pools[0].GetNetwork()
+	assert.Equal(t, defaultCNIConfDir, network.ConfDir)
+	assert.Equal(t, []string{defaultCNIBinDir}, network.BinDirs)
+	assert.Equal(t, []*NetworkInterfaceConfig{{NetworkName: defaultCNINetworkName, IfName: "eth0"}}, network.Interfaces)
+
+	network = config.Pools[1].GetNetwork()
+	assert.Equal(t, []*NetworkInterfaceConfig{
+		{NetworkName: "fireactions-vlan10", IfName: "eth0", IPRange: "10.10.0.0/24",
+			TxRateLimiter: &RateLimiterConfig{Bandwidth: &TokenBucketConfig{Size: 125000000, RefillTime: time.Second}}},
+		{NetworkName: "storage", IfName: "eth1"},
+	}, network.Interfaces)
+	assert.Empty(t, config.Pools[1].Network.Interfaces[0].IfName)
+	assert.NoError(t, config.Validate())
+
+	config.Pools[1].Network.Interfaces[1].IfName = "eth0"
+	assert.Error(t, config.Validate())
+
+	config.Pools[1].Network.Interfaces[1].IfName = ""
+	config.Pools[1].Network.Interfaces[1].NetworkName = ""
+	assert.Error(t, config.Validate())
+
+	config.Pools[1].Network.Interfaces[1].NetworkName = "storage"
+	config.Pools[1].Network.Interfaces[0].IPRange = "10.10.0.0/33"
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_Validate_RunnerScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *RunnerConfig
+		wantErr bool
+	}{
+		{name: "DefaultScope", runner: &RunnerConfig{Organization: "hostinger"}},
+		{name: "DefaultScopeMissingOrganization", runner: &RunnerConfig{}, wantErr: true},
+		{name: "Organization", runner: &RunnerConfig{Scope: "organization", Organization: "hostinger"}},
+		{name: "Repository", runner: &RunnerConfig{Scope: "repository", Repository: "hostinger/fireactions"}},
+		{name: "RepositoryMissingOwner", runner: &RunnerConfig{Scope: "repository", Repository: "fireactions"}, wantErr: true},
+		{name: "RepositoryTooManyParts", runner: &RunnerConfig{Scope: "repository", Repository: "hostinger/fireactions/x"}, wantErr: true},
+		{name: "Enterprise", runner: &RunnerConfig{Scope: "enterprise", Enterprise: "hostinger"}},
+		{name: "EnterpriseMissingName", runner: &RunnerConfig{Scope: "enterprise", Organization: "hostinger"}, wantErr: true},
+		{name: "UnknownScope", runner: &RunnerConfig{Scope: "user", Organization: "hostinger"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig("testdata/config1.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.runner.Name = "test"
+			tt.runner.Image = config.Pools[0].Runner.Image
+			tt.runner.GroupID = config.Pools[0].Runner.GroupID
+			tt.runner.Labels = config.Pools[0].Runner.Labels
+			config.Pools[0].Runner = tt.runner
+
+			err = config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
 }
@@ -5,19 +5,27 @@ import (
 )
 
 func convertPool(p *Pool) *fireactions.Pool {
+	profile := p.GetProfile()
 	pool := &fireactions.Pool{
-		Name:       p.config.Name,
-		MaxRunners: p.config.MaxRunners,
-		MinRunners: p.config.MinRunners,
+		Name:       p.getConfig().Name,
+		MaxRunners: profile.MaxRunners,
+		MinRunners: profile.MinRunners,
 		CurRunners: p.GetCurrentSize(),
+		Profile:    profile.Name,
 	}
 
-	if p.isActive {
+	switch {
+	case p.isDraining.Load():
+		pool.Status = fireactions.PoolStatus{
+			State:   fireactions.PoolStateDraining,
+			Message: "Pool is draining",
+		}
+	case p.isActive.Load():
 		pool.Status = fireactions.PoolStatus{
 			State:   fireactions.PoolStateActive,
 			Message: "Pool is active",
 		}
-	} else {
+	default:
 		pool.Status = fireactions.PoolStatus{
 			State:   fireactions.PoolStatePaused,
 			Message: "Pool is paused",
@@ -35,3 +43,41 @@ func convertPools(pools []*Pool) fireactions.Pools {
 
 	return convertedPools
 }
+
+func convertRunner(r *Runner) *fireactions.Runner {
+	runner := &fireactions.Runner{
+		Name:       r.Name,
+		Pool:       r.Pool,
+		State:      r.GetState(),
+		VMID:       r.VMID,
+		SocketPath: r.SocketPath,
+		LogPath:    r.LogPath,
+		IPAddress:  r.GetIPAddress(),
+		StartedAt:  r.StartedAt,
+	}
+
+	return runner
+}
+
+func convertRunners(runners []*Runner) fireactions.Runners {
+	convertedRunners := make(fireactions.Runners, 0, len(runners))
+	for _, runner := range runners {
+		convertedRunners = append(convertedRunners, convertRunner(runner))
+	}
+
+	return convertedRunners
+}
+
+func convertScaleResult(pool string, r *ScaleResult) *fireactions.ScalePoolResult {
+	result := &fireactions.ScalePoolResult{
+		Pool:     pool,
+		Replicas: r.Replicas,
+		Errors:   make([]string, 0, len(r.Errors)),
+	}
+
+	for _, err := range r.Errors {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	return result
+}
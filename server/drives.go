@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+)
+
+// getDrivesDir returns the directory of the scratch drives of the machine.
+func (p *firecrackerProvider) getDrivesDir(id string) string {
+	return filepath.Join(p.dir, "drives", id)
+}
+
+// createDrives creates the scratch drives of the machine and returns the
+// additional drives to attach, along with the metadata telling the runner
+// agent where to mount them. Drives are attached in order after the root
+// drive, so the first one is /dev/vdb.
+func (p *firecrackerProvider) createDrives(ctx context.Context, id string, config *FirecrackerConfig) ([]models.Drive, []interface{}, error) {
+	drives := make([]models.Drive, 0, len(config.Drives))
+	metadata := make([]interface{}, 0, len(config.Drives))
+	for i, drive := range config.Drives {
+		path := drive.Path
+		if drive.Type == DriveTypeScratch {
+			path = filepath.Join(p.getDrivesDir(id), fmt.Sprintf("%s.ext4", drive.Name))
+			if err := createScratchDrive(ctx, path, drive.SizeMib); err != nil {
+				return nil, nil, fmt.Errorf("drive %s: %w", drive.Name, err)
+			}
+		}
+
+		drives = append(drives, models.Drive{
+			DriveID:      firecracker.String(drive.Name),
+			PathOnHost:   firecracker.String(path),
+			IsRootDevice: firecracker.Bool(false),
+			IsReadOnly:   firecracker.Bool(drive.IsReadOnly()),
+			RateLimiter:  newRateLimiter(drive.RateLimiter),
+		})
+
+		if drive.MountPath == "" {
+			continue
+		}
+
+		metadata = append(metadata, map[string]interface{}{
+			"name":       drive.Name,
+			"device":     fmt.Sprintf("/dev/vd%c", 'b'+i),
+			"mount_path": drive.MountPath,
+			"read_only":  drive.IsReadOnly(),
+		})
+	}
+
+	return drives, metadata, nil
+}
+
+// createScratchDrive creates an empty sparse ext4 image file of the given
+// size at path.
+func createScratchDrive(ctx context.Context, path string, sizeMib int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating drives directory: %w", err)
+	}
+
+	output, err := exec.CommandContext(ctx, "mkfs.ext4", "-q", "-F", path, fmt.Sprintf("%dM", sizeMib)).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("mkfs.ext4: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// newBalloonHandler returns a Firecracker handler adding the memory balloon
+// device of the configuration, if set.
+func newBalloonHandler(config *BalloonConfig) (firecracker.Handler, bool) {
+	if config == nil {
+		return firecracker.Handler{}, false
+	}
+
+	return firecracker.NewCreateBalloonHandler(config.AmountMib, config.DeflateOnOom, int64(config.StatsPollingInterval.Seconds())), true
+}
+
+// newRateLimiter returns the Firecracker rate limiter of the configuration,
+// or nil if not set.
+func newRateLimiter(config *RateLimiterConfig) *models.RateLimiter {
+	if config == nil {
+		return nil
+	}
+
+	return &models.RateLimiter{Bandwidth: newTokenBucket(config.Bandwidth), Ops: newTokenBucket(config.Ops)}
+}
+
+func newTokenBucket(config *TokenBucketConfig) *models.TokenBucket {
+	if config == nil {
+		return nil
+	}
+
+	return &models.TokenBucket{
+		Size:         firecracker.Int64(config.Size),
+		OneTimeBurst: firecracker.Int64(config.OneTimeBurst),
+		RefillTime:   firecracker.Int64(config.RefillTime.Milliseconds()),
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirecrackerProvider_CreateDrives(t *testing.T) {
+	p := &firecrackerProvider{dir: t.TempDir()}
+	config := &FirecrackerConfig{Drives: []*DriveConfig{
+		{Name: "toolcache", Type: DriveTypeCache, Path: "/var/lib/fireactions/toolcache.ext4", MountPath: "/opt/hostedtoolcache"},
+		{Name: "gocache", Type: DriveTypeCache, Path: "/var/lib/fireactions/gocache.ext4",
+			RateLimiter: &RateLimiterConfig{Bandwidth: &TokenBucketConfig{Size: 1024, RefillTime: time.Second}}},
+	}}
+
+	drives, metadata, err := p.createDrives(context.Background(), "runner-1", config)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Drive{
+		{
+			DriveID:      firecracker.String("toolcache"),
+			PathOnHost:   firecracker.String("/var/lib/fireactions/toolcache.ext4"),
+			IsRootDevice: firecracker.Bool(false),
+			IsReadOnly:   firecracker.Bool(true),
+		},
+		{
+			DriveID:      firecracker.String("gocache"),
+			PathOnHost:   firecracker.String("/var/lib/fireactions/gocache.ext4"),
+			IsRootDevice: firecracker.Bool(false),
+			IsReadOnly:   firecracker.Bool(true),
+			RateLimiter: &models.RateLimiter{Bandwidth: &models.TokenBucket{
+				Size:         firecracker.Int64(1024),
+				OneTimeBurst: firecracker.Int64(0),
+				RefillTime:   firecracker.Int64(1000),
+			}},
+		},
+	}, drives)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "toolcache", "device": "/dev/vdb", "mount_path": "/opt/hostedtoolcache", "read_only": true},
+	}, metadata)
+}
+
+func TestNewBalloonHandler(t *testing.T) {
+	_, ok := newBalloonHandler(nil)
+	assert.False(t, ok)
+
+	handler, ok := newBalloonHandler(&BalloonConfig{AmountMib: 512, StatsPollingInterval: 5 * time.Second})
+	assert.True(t, ok)
+	assert.Equal(t, firecracker.CreateBalloonHandlerName, handler.Name)
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(nil))
+
+	limiter := newRateLimiter(&RateLimiterConfig{Ops: &TokenBucketConfig{Size: 100, OneTimeBurst: 50, RefillTime: 500 * time.Millisecond}})
+	assert.Nil(t, limiter.Bandwidth)
+	assert.Equal(t, int64(100), *limiter.Ops.Size)
+	assert.Equal(t, int64(50), *limiter.Ops.OneTimeBurst)
+	assert.Equal(t, int64(500), *limiter.Ops.RefillTime)
+}
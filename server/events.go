@@ -0,0 +1,164 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hostinger/fireactions"
+)
+
+const (
+	// defaultEventHistorySize is the number of recent events replayed to new
+	// subscribers.
+	defaultEventHistorySize = 1000
+
+	// defaultEventBufferSize is the number of events buffered per subscriber.
+	// Subscribers falling further behind are disconnected.
+	defaultEventBufferSize = 256
+
+	// defaultEventKeepaliveInterval is how often a comment is sent on idle
+	// event streams, keeping proxies from closing them.
+	defaultEventKeepaliveInterval = 15 * time.Second
+
+	// eventMessageScaleUp and eventMessageScaleDown are the messages of the
+	// ScaleFailed events telling the direction of the scaling.
+	eventMessageScaleUp   = "scaling up"
+	eventMessageScaleDown = "scaling down"
+)
+
+// EventBus publishes the events of the server to its subscribers and keeps
+// the most recent ones.
+type EventBus struct {
+	history     []*fireactions.Event
+	subscribers map[*EventSubscription]struct{}
+	lastID      uint64
+	closed      bool
+	l           *sync.Mutex
+}
+
+// EventSubscription receives the events of an EventBus, optionally only the
+// ones of a pool.
+type EventSubscription struct {
+	// History holds the recorded events published before the subscription,
+	// the following ones are sent on C. C is closed when the subscription
+	// is closed, the bus is closed or the subscriber falls behind.
+	History []*fireactions.Event
+	C       <-chan *fireactions.Event
+
+	pool string
+	ch   chan *fireactions.Event
+	bus  *EventBus
+}
+
+// NewEventBus creates a new EventBus.
+func NewEventBus() *EventBus {
+	b := &EventBus{
+		history:     make([]*fireactions.Event, 0, defaultEventHistorySize),
+		subscribers: make(map[*EventSubscription]struct{}),
+		l:           &sync.Mutex{},
+	}
+
+	return b
+}
+
+// Publish numbers, timestamps and sends the event to the subscribers.
+// Publishing on a nil or closed EventBus is a no-op.
+func (b *EventBus) Publish(event *fireactions.Event) {
+	if b == nil {
+		return
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if len(b.history) == defaultEventHistorySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, event)
+
+	for subscription := range b.subscribers {
+		if !subscription.matches(event) {
+			continue
+		}
+
+		select {
+		case subscription.ch <- event:
+		default:
+			b.unsubscribe(subscription)
+		}
+	}
+}
+
+// Subscribe subscribes to the events of the pool, or all events if empty.
+// The recorded events published after the one with ID lastID are returned
+// in the History of the subscription, which must be closed once done.
+func (b *EventBus) Subscribe(pool string, lastID uint64) *EventSubscription {
+	ch := make(chan *fireactions.Event, defaultEventBufferSize)
+	subscription := &EventSubscription{C: ch, pool: pool, ch: ch, bus: b}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	for _, event := range b.history {
+		if event.ID > lastID && subscription.matches(event) {
+			subscription.History = append(subscription.History, event)
+		}
+	}
+
+	if b.closed {
+		close(ch)
+		return subscription
+	}
+
+	b.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// Close closes the subscriptions of the bus, ending the event streams.
+func (b *EventBus) Close() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.closed = true
+	for subscription := range b.subscribers {
+		b.unsubscribe(subscription)
+	}
+}
+
+func (b *EventBus) isClosed() bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	return b.closed
+}
+
+func (b *EventBus) unsubscribe(subscription *EventSubscription) {
+	if _, ok := b.subscribers[subscription]; !ok {
+		return
+	}
+
+	delete(b.subscribers, subscription)
+	close(subscription.ch)
+}
+
+// Close closes the subscription.
+func (s *EventSubscription) Close() {
+	s.bus.l.Lock()
+	defer s.bus.l.Unlock()
+
+	s.bus.unsubscribe(s)
+}
+
+func (s *EventSubscription) matches(event *fireactions.Event) bool {
+	return s.pool == "" || s.pool == event.Pool
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool1"})
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool2"})
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypeConfigReloaded})
+
+	all := bus.Subscribe("", 0)
+	defer all.Close()
+	assert.Len(t, all.History, 3)
+	assert.Equal(t, uint64(1), all.History[0].ID)
+	assert.False(t, all.History[0].Time.IsZero())
+
+	pool1 := bus.Subscribe("pool1", 0)
+	defer pool1.Close()
+	assert.Len(t, pool1.History, 1)
+
+	resumed := bus.Subscribe("", 2)
+	defer resumed.Close()
+	assert.Len(t, resumed.History, 1)
+	assert.Equal(t, fireactions.EventTypeConfigReloaded, resumed.History[0].Type)
+
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated, Pool: "pool2"})
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated, Pool: "pool1"})
+
+	event := <-all.C
+	assert.Equal(t, uint64(4), event.ID)
+	event = <-pool1.C
+	assert.Equal(t, uint64(5), event.ID)
+}
+
+func TestEventBus_History(t *testing.T) {
+	bus := NewEventBus()
+	for i := 0; i < defaultEventHistorySize+10; i++ {
+		bus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated})
+	}
+
+	subscription := bus.Subscribe("", 0)
+	defer subscription.Close()
+	assert.Len(t, subscription.History, defaultEventHistorySize)
+	assert.Equal(t, uint64(11), subscription.History[0].ID)
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	subscription := bus.Subscribe("", 0)
+	for i := 0; i < defaultEventBufferSize+1; i++ {
+		bus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated})
+	}
+
+	count := 0
+	for range subscription.C {
+		count++
+	}
+	assert.Equal(t, defaultEventBufferSize, count)
+
+	subscription.Close()
+}
+
+func TestEventBus_Close(t *testing.T) {
+	bus := NewEventBus()
+	subscription := bus.Subscribe("", 0)
+	bus.Close()
+
+	_, ok := <-subscription.C
+	assert.False(t, ok)
+	subscription.Close()
+
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated})
+	_, ok = <-bus.Subscribe("", 0).C
+	assert.False(t, ok)
+
+	var nilBus *EventBus
+	nilBus.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated})
+}
+
+func TestPool_Events(t *testing.T) {
+	pool, provider, _ := newTestPool(t, nil)
+	pool.events = NewEventBus()
+	subscription := pool.events.Subscribe("", 0)
+	defer subscription.Close()
+
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+	assert.Equal(t, fireactions.EventTypeJITConfigIssued, (<-subscription.C).Type)
+	assert.Equal(t, fireactions.EventTypeVMCreated, (<-subscription.C).Type)
+	assert.Equal(t, fireactions.EventTypeVMStarted, (<-subscription.C).Type)
+
+	runner := pool.ListRunners()[0]
+	machine, _ := provider.GetMachine(runner.VMID)
+	machine.Exit()
+
+	select {
+	case event := <-subscription.C:
+		assert.Equal(t, fireactions.EventTypeVMExited, event.Type)
+		assert.Equal(t, runner.Name, event.Runner)
+		assert.Equal(t, fireactions.ExitReasonCompleted, event.Reason)
+		assert.Greater(t, event.DurationSeconds, 0.0)
+	case <-time.After(time.Second):
+		t.Fatal("VMExited event not published")
+	}
+
+	pool.Pause()
+	assert.Equal(t, fireactions.EventTypePoolPaused, (<-subscription.C).Type)
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gcGracePeriod protects the machines of runners that are still being
+// created, as they are registered in the pool only once the machine is
+// configured.
+const gcGracePeriod = 5 * time.Minute
+
+// GCResult represents the outcome of a garbage collection run of a pool.
+type GCResult struct {
+	Machines    int
+	Logs        int
+	RotatedLogs int
+	Bytes       int64
+}
+
+// CollectGarbage removes the resources of the machines that are no longer
+// running, and rotates and prunes the runner log files according to config.
+func (p *Pool) CollectGarbage(ctx context.Context, config *GCConfig) (*GCResult, error) {
+	result := &GCResult{}
+
+	machines, err := p.listStaleMachines(ctx, gcGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, machine := range machines {
+		if err := p.provider.Remove(ctx, machine.ID); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to remove machine %s", machine.ID)
+			continue
+		}
+
+		result.Machines++
+	}
+
+	logs, err := pruneLogs(p.GetDir(), p.isLive, config)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Logs, result.RotatedLogs, result.Bytes = logs.Logs, logs.RotatedLogs, logs.Bytes
+
+	metricGCReclaimed.WithLabelValues(p.getConfig().Name, "machine").Add(float64(result.Machines))
+	metricGCReclaimed.WithLabelValues(p.getConfig().Name, "log").Add(float64(result.Logs))
+	metricGCRotatedLogs.WithLabelValues(p.getConfig().Name).Add(float64(result.RotatedLogs))
+	metricGCReclaimedBytes.WithLabelValues(p.getConfig().Name).Add(float64(result.Bytes))
+
+	return result, nil
+}
+
+// listStaleMachines returns the machines known to the provider that were
+// created more than minAge ago and don't belong to a runner of the pool.
+func (p *Pool) listStaleMachines(ctx context.Context, minAge time.Duration) ([]*MachineInfo, error) {
+	machines, err := p.provider.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := make([]*MachineInfo, 0)
+	for _, machine := range machines {
+		if p.isLive(machine.ID) || time.Since(machine.CreatedAt) < minAge {
+			continue
+		}
+
+		stale = append(stale, machine)
+	}
+
+	return stale, nil
+}
+
+// isLive returns true if the runner with the given name belongs to the pool.
+func (p *Pool) isLive(name string) bool {
+	_, err := p.GetRunner(name)
+	return err == nil
+}
+
+// pruneLogs rotates the log files of live runners that grew larger than the
+// maximum file size, then removes the log files of exited runners that are
+// older than the maximum age, and finally the oldest ones until the logs in
+// dir fit in the maximum total size.
+func pruneLogs(dir string, isLive func(name string) bool, config *GCConfig) (*GCResult, error) {
+	result := &GCResult{}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log*"))
+	if err != nil {
+		return nil, fmt.Errorf("listing logs: %w", err)
+	}
+
+	type logFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var totalSize int64
+	removable := make([]logFile, 0, len(paths))
+	rotatedPaths := make(map[string]struct{})
+	for _, path := range paths {
+		// Glob returns sorted paths, so <name>.log.1 comes right after the
+		// <name>.log it may have just been rotated from.
+		if _, ok := rotatedPaths[path]; ok {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		name, rotated := strings.CutSuffix(filepath.Base(path), ".log.1")
+		if !rotated {
+			name = strings.TrimSuffix(filepath.Base(path), ".log")
+		}
+
+		size := info.Size()
+		if isLive(name) && !rotated {
+			if config.LogMaxFileSizeMB > 0 && size > config.LogMaxFileSizeMB<<20 {
+				if err := rotateLog(path); err != nil {
+					return nil, fmt.Errorf("rotating log %s: %w", path, err)
+				}
+
+				result.RotatedLogs++
+				rotatedPaths[path+".1"] = struct{}{}
+				totalSize += size
+				removable = append(removable, logFile{path: path + ".1", size: size, modTime: info.ModTime()})
+				continue
+			}
+
+			totalSize += size
+			continue
+		}
+
+		if config.LogMaxAge > 0 && time.Since(info.ModTime()) > config.LogMaxAge {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("removing log %s: %w", path, err)
+			}
+
+			result.Logs++
+			result.Bytes += size
+			continue
+		}
+
+		totalSize += size
+		removable = append(removable, logFile{path: path, size: size, modTime: info.ModTime()})
+	}
+
+	if config.LogMaxTotalSizeMB <= 0 {
+		return result, nil
+	}
+
+	sort.Slice(removable, func(i, j int) bool { return removable[i].modTime.Before(removable[j].modTime) })
+	for _, log := range removable {
+		if totalSize <= config.LogMaxTotalSizeMB<<20 {
+			break
+		}
+
+		if err := os.Remove(log.path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("removing log %s: %w", log.path, err)
+		}
+
+		result.Logs++
+		result.Bytes += log.size
+		totalSize -= log.size
+	}
+
+	return result, nil
+}
+
+// rotateLog copies the log file to <path>.1 and truncates it. The Firecracker
+// process keeps writing to the same file, which is opened in append mode.
+func rotateLog(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".1")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	return os.Truncate(path, 0)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneLogs(t *testing.T) {
+	dir := t.TempDir()
+
+	writeLog := func(name string, size int, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	live := writeLog("live.log", 3<<20, 0)
+	expired := writeLog("expired.log", 1<<20, 48*time.Hour)
+	oldest := writeLog("oldest.log", 1<<20, 3*time.Hour)
+	newest := writeLog("newest.log", 1<<20, 1*time.Hour)
+
+	isLive := func(name string) bool { return name == "live" }
+	result, err := pruneLogs(dir, isLive, &GCConfig{LogMaxAge: 24 * time.Hour, LogMaxFileSizeMB: 2, LogMaxTotalSizeMB: 4})
+	assert.NoError(t, err)
+
+	// live.log is rotated, expired.log is too old and oldest.log is removed
+	// to fit live.log.1 (3MiB) and newest.log (1MiB) in 4MiB.
+	assert.Equal(t, 1, result.RotatedLogs)
+	assert.Equal(t, 2, result.Logs)
+	assert.Equal(t, int64(2<<20), result.Bytes)
+
+	assert.FileExists(t, live)
+	assert.FileExists(t, live+".1")
+	assert.FileExists(t, newest)
+	assert.NoFileExists(t, expired)
+	assert.NoFileExists(t, oldest)
+
+	info, err := os.Stat(live)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestPruneLogs_Disabled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pruneLogs(dir, func(string) bool { return false }, &GCConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, &GCResult{}, result)
+	assert.FileExists(t, path)
+}
@@ -1,12 +1,30 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hostinger/fireactions"
+
+	githubv63 "github.com/google/go-github/v63/github"
 )
 
+// requestContext returns the context of the request without its cancellation,
+// for the operations that must complete even if the client disconnects. The
+// gin.Context itself is reused once the handler returns, so it mustn't be
+// passed to the operations that outlive it, e.g. the GitHub API requests.
+func requestContext(ctx *gin.Context) context.Context {
+	return context.WithoutCancel(ctx.Request.Context())
+}
+
 func getHealthzHandler() gin.HandlerFunc {
 	f := func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
@@ -55,12 +73,43 @@ func getPoolHandler(p PoolManager) gin.HandlerFunc {
 func scalePoolHandler(p PoolManager) gin.HandlerFunc {
 	f := func(ctx *gin.Context) {
 		id := ctx.Param("id")
-		if err := p.ScalePool(ctx, id, 1); err != nil {
+
+		var request fireactions.ScalePoolRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var result *ScaleResult
+		var err error
+		switch {
+		case request.Replicas != nil && request.Delta != nil:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "only one of replicas or delta can be set"})
+			return
+		case request.Replicas != nil:
+			if *request.Replicas < 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "replicas must not be negative"})
+				return
+			}
+
+			result, err = p.ScalePoolTo(requestContext(ctx), id, *request.Replicas)
+		case request.Delta != nil:
+			result, err = p.ScalePool(requestContext(ctx), id, *request.Delta)
+		default:
+			result, err = p.ScalePool(requestContext(ctx), id, 1)
+		}
+
+		if err != nil {
+			if errors.Is(err, fireactions.ErrPoolNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "Pool scaled successfully"})
+		ctx.JSON(http.StatusOK, gin.H{"result": convertScaleResult(id, result)})
 	}
 
 	return f
@@ -94,14 +143,284 @@ func resumePoolHandler(p PoolManager) gin.HandlerFunc {
 	return f
 }
 
+func drainPoolHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		timeout, err := time.ParseDuration(ctx.DefaultQuery("timeout", defaultDrainTimeout.String()))
+		if err != nil || timeout < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid timeout parameter: %s", ctx.Query("timeout"))})
+			return
+		}
+
+		if err := p.DrainPool(requestContext(ctx), id, timeout); err != nil {
+			if errors.Is(err, fireactions.ErrPoolNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "Pool drain requested"})
+	}
+
+	return f
+}
+
 func reloadHandler(p PoolManager) gin.HandlerFunc {
 	f := func(ctx *gin.Context) {
-		if err := p.Reload(ctx); err != nil {
+		result, err := p.Reload(requestContext(ctx))
+		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "Pools reloaded successfully"})
+		ctx.JSON(http.StatusOK, gin.H{"message": "Pools reloaded successfully", "reload": result})
+	}
+
+	return f
+}
+
+func getCapacityHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		capacity, err := p.GetCapacity(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"capacity": capacity})
+	}
+
+	return f
+}
+
+// getEventsHandler streams the events of the server as Server-Sent Events:
+// the recorded ones first, after the one of the Last-Event-ID header if set,
+// then the new ones until the client disconnects, unless follow is false.
+func getEventsHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		follow, err := strconv.ParseBool(ctx.DefaultQuery("follow", "true"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid follow parameter: %s", ctx.Query("follow"))})
+			return
+		}
+
+		var lastID uint64
+		if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+			lastID, err = strconv.ParseUint(header, 10, 64)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid Last-Event-ID header: %s", header)})
+				return
+			}
+		}
+
+		subscription, err := p.SubscribeEvents(ctx, ctx.Query("pool"), lastID)
+		if err != nil {
+			if errors.Is(err, fireactions.ErrPoolNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer subscription.Close()
+
+		// The stream outlives the write timeout of the server.
+		_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("X-Accel-Buffering", "no")
+		ctx.Status(http.StatusOK)
+
+		for _, event := range subscription.History {
+			writeEvent(ctx.Writer, event)
+		}
+		ctx.Writer.Flush()
+
+		if !follow {
+			return
+		}
+
+		keepalive := time.NewTicker(defaultEventKeepaliveInterval)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscription.C:
+				if !ok {
+					return
+				}
+
+				writeEvent(ctx.Writer, event)
+			case <-keepalive.C:
+				_, _ = io.WriteString(ctx.Writer, ": keepalive\n\n")
+			case <-ctx.Request.Context().Done():
+				return
+			}
+
+			ctx.Writer.Flush()
+		}
+	}
+
+	return f
+}
+
+func writeEvent(w io.Writer, event *fireactions.Event) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
+func listRunnersHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		runners, err := p.ListRunners(ctx, id)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"runners": convertRunners(runners)})
+	}
+
+	return f
+}
+
+func getRunnerHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		name := ctx.Param("name")
+		runner, err := p.GetRunner(ctx, name)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"runner": convertRunner(runner)})
+	}
+
+	return f
+}
+
+func deleteRunnerHandler(p PoolManager) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		name := ctx.Param("name")
+		force, err := strconv.ParseBool(ctx.DefaultQuery("force", "false"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid force parameter: %s", err.Error())})
+			return
+		}
+
+		if err := p.DeleteRunner(requestContext(ctx), name, force); err != nil {
+			if errors.Is(err, fireactions.ErrRunnerNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !force {
+			ctx.JSON(http.StatusAccepted, gin.H{"message": "Runner shutdown requested"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Runner deleted successfully"})
+	}
+
+	return f
+}
+
+func githubWebhookHandler(p PoolManager, getSecret func() string) gin.HandlerFunc {
+	f := func(ctx *gin.Context) {
+		secret := getSecret()
+		if secret == "" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "GitHub webhooks are disabled"})
+			return
+		}
+
+		payload, err := githubv63.ValidatePayload(ctx.Request, []byte(secret))
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		event, err := githubv63.ParseWebHook(githubv63.WebHookType(ctx.Request), payload)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch event := event.(type) {
+		case *githubv63.PingEvent:
+			ctx.JSON(http.StatusOK, gin.H{"message": "pong"})
+		case *githubv63.WorkflowJobEvent:
+			switch event.GetAction() {
+			case "queued":
+			case "in_progress", "completed":
+				runner, err := p.GetRunner(ctx, event.GetWorkflowJob().GetRunnerName())
+				if err != nil {
+					ctx.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+					return
+				}
+
+				if event.GetAction() == "in_progress" {
+					runner.SetState(fireactions.RunnerStateBusy)
+				} else {
+					runner.SetState(fireactions.RunnerStateExiting)
+				}
+
+				ctx.JSON(http.StatusOK, gin.H{"message": "Runner state updated", "runner": runner.Name})
+				return
+			default:
+				ctx.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+				return
+			}
+
+			pools, err := p.ListPools(ctx)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			sort.Slice(pools, func(i, j int) bool { return pools[i].getConfig().Name < pools[j].getConfig().Name })
+
+			var pool *Pool
+			for _, candidate := range pools {
+				if !candidate.isActive.Load() || candidate.isDraining.Load() || !candidate.MatchesLabels(event.GetWorkflowJob().Labels) {
+					continue
+				}
+
+				if candidate.GetCurrentSize() >= candidate.GetProfile().MaxRunners {
+					continue
+				}
+
+				pool = candidate
+				break
+			}
+
+			if pool == nil {
+				ctx.JSON(http.StatusOK, gin.H{"message": "No matching pool with free capacity found"})
+				return
+			}
+
+			result, err := p.ScalePool(requestContext(ctx), pool.getConfig().Name, 1)
+			if err == nil {
+				err = result.Err()
+			}
+
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{"message": "Pool scaled successfully", "pool": pool.getConfig().Name})
+		default:
+			ctx.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		}
 	}
 
 	return f
@@ -1,13 +1,22 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/firecracker-microvm/firecracker-go-sdk"
 	"github.com/gin-gonic/gin"
+	"github.com/hostinger/fireactions"
 	"go.uber.org/mock/gomock"
 )
 
@@ -105,15 +114,16 @@ func TestGetPoolHandler(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		m := newMockPoolManager(mockCtrl)
-		m.EXPECT().GetPool(gomock.Any(), "test").Return(&Pool{
-			machines: make(map[string]*firecracker.Machine),
-			config: &PoolConfig{
-				Name:       "test",
-				MaxRunners: 0,
-				MinRunners: 0,
-			},
-			isActive: false,
-		}, nil)
+		pool := &Pool{
+			runners:   make(map[string]*Runner),
+			runnersMu: &sync.Mutex{},
+		}
+		pool.config.Store(&PoolConfig{
+			Name:       "test",
+			MaxRunners: 0,
+			MinRunners: 0,
+		})
+		m.EXPECT().GetPool(gomock.Any(), "test").Return(pool, nil)
 
 		router := gin.New()
 		router.GET("/api/v1/pools/:id", getPoolHandler(m))
@@ -131,7 +141,7 @@ func TestGetPoolHandler(t *testing.T) {
 			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
 		}
 
-		expectedBody := `{"pool":{"name":"test","max_runners":0,"min_runners":0,"cur_runners":0,"status":{"state":"Paused","message":"Pool is paused"}}}`
+		expectedBody := `{"pool":{"name":"test","max_runners":0,"min_runners":0,"cur_runners":0,"profile":"default","status":{"state":"Paused","message":"Pool is paused"}}}`
 		if rec.Body.String() != expectedBody {
 			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
 		}
@@ -163,50 +173,104 @@ func TestScalePoolHandler(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	t.Run("Success", func(t *testing.T) {
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req, err := http.NewRequest("POST", "/api/v1/pools/test/scale", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("Default", func(t *testing.T) {
 		m := newMockPoolManager(mockCtrl)
-		m.EXPECT().ScalePool(gomock.Any(), "test", 1).Return(nil)
+		m.EXPECT().ScalePool(gomock.Any(), "test", 1).Return(&ScaleResult{Replicas: 2}, nil)
 
 		router := gin.New()
 		router.POST("/api/v1/pools/:id/scale", scalePoolHandler(m))
 
-		req, err := http.NewRequest("POST", "/api/v1/pools/test/scale", nil)
-		if err != nil {
-			t.Fatal(err)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, newRequest(t, ""))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
 		}
 
+		expectedBody := `{"result":{"pool":"test","replicas":2,"errors":[]}}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("Replicas", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ScalePoolTo(gomock.Any(), "test", 5).Return(&ScaleResult{Replicas: 4, Errors: []error{errors.New("error")}}, nil)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/scale", scalePoolHandler(m))
+
 		rec := httptest.NewRecorder()
 
-		router.ServeHTTP(rec, req)
+		router.ServeHTTP(rec, newRequest(t, `{"replicas":5}`))
 
 		if rec.Code != http.StatusOK {
 			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
 		}
 
-		expectedBody := `{"message":"Pool scaled successfully"}`
+		expectedBody := `{"result":{"pool":"test","replicas":4,"errors":["error"]}}`
 		if rec.Body.String() != expectedBody {
 			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
 		}
 	})
 
-	t.Run("Error", func(t *testing.T) {
+	t.Run("Delta", func(t *testing.T) {
 		m := newMockPoolManager(mockCtrl)
-		m.EXPECT().ScalePool(gomock.Any(), "test", 1).Return(errors.New("error"))
+		m.EXPECT().ScalePool(gomock.Any(), "test", -2).Return(&ScaleResult{Replicas: 3}, nil)
 
 		router := gin.New()
 		router.POST("/api/v1/pools/:id/scale", scalePoolHandler(m))
 
-		req, err := http.NewRequest("POST", "/api/v1/pools/test/scale", nil)
-		if err != nil {
-			t.Fatal(err)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, newRequest(t, `{"delta":-2}`))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		for _, body := range []string{`{"replicas":5,"delta":1}`, `{"replicas":-1}`, `{"replicas":"five"}`} {
+			m := newMockPoolManager(mockCtrl)
+
+			router := gin.New()
+			router.POST("/api/v1/pools/:id/scale", scalePoolHandler(m))
+
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, newRequest(t, body))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d for body %s, but got %d", http.StatusBadRequest, body, rec.Code)
+			}
 		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ScalePoolTo(gomock.Any(), "test", 5).Return(nil, fireactions.ErrPoolNotFound)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/scale", scalePoolHandler(m))
 
 		rec := httptest.NewRecorder()
 
-		router.ServeHTTP(rec, req)
+		router.ServeHTTP(rec, newRequest(t, `{"replicas":5}`))
 
-		if rec.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status code %d, but got %d", http.StatusInternalServerError, rec.Code)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
 		}
 	})
 }
@@ -315,13 +379,112 @@ func TestResumePoolHandler(t *testing.T) {
 	})
 }
 
+func TestDrainPoolHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Success", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DrainPool(gomock.Any(), "test", 10*time.Minute).Return(nil)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/drain", drainPoolHandler(m))
+
+		req, err := http.NewRequest("POST", "/api/v1/pools/test/drain?timeout=10m", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected status code %d, but got %d", http.StatusAccepted, rec.Code)
+		}
+
+		expectedBody := `{"message":"Pool drain requested"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("DefaultTimeout", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DrainPool(gomock.Any(), "test", defaultDrainTimeout).Return(nil)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/drain", drainPoolHandler(m))
+
+		req, err := http.NewRequest("POST", "/api/v1/pools/test/drain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected status code %d, but got %d", http.StatusAccepted, rec.Code)
+		}
+	})
+
+	t.Run("InvalidTimeout", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/drain", drainPoolHandler(m))
+
+		req, err := http.NewRequest("POST", "/api/v1/pools/test/drain?timeout=soon", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DrainPool(gomock.Any(), "test", defaultDrainTimeout).Return(fireactions.ErrPoolNotFound)
+
+		router := gin.New()
+		router.POST("/api/v1/pools/:id/drain", drainPoolHandler(m))
+
+		req, err := http.NewRequest("POST", "/api/v1/pools/test/drain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
 func TestReloadHandler(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	t.Run("Success", func(t *testing.T) {
 		m := newMockPoolManager(mockCtrl)
-		m.EXPECT().Reload(gomock.Any()).Return(nil)
+		m.EXPECT().Reload(gomock.Any()).Return(&fireactions.ReloadResult{
+			Added:           []string{"new"},
+			Removed:         []string{},
+			Changed:         fireactions.PoolChanges{{Pool: "test", Fields: []string{"runner.image"}, Rolled: true}},
+			Unchanged:       []string{},
+			RestartRequired: []string{},
+		}, nil)
 
 		router := gin.New()
 		router.POST("/api/v1/reload", reloadHandler(m))
@@ -339,7 +502,7 @@ func TestReloadHandler(t *testing.T) {
 			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
 		}
 
-		expectedBody := `{"message":"Pools reloaded successfully"}`
+		expectedBody := `{"message":"Pools reloaded successfully","reload":{"added":["new"],"removed":[],"changed":[{"pool":"test","fields":["runner.image"],"rolled":true}],"unchanged":[],"restart_required":[]}}`
 		if rec.Body.String() != expectedBody {
 			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
 		}
@@ -347,7 +510,7 @@ func TestReloadHandler(t *testing.T) {
 
 	t.Run("Error", func(t *testing.T) {
 		m := newMockPoolManager(mockCtrl)
-		m.EXPECT().Reload(gomock.Any()).Return(errors.New("error"))
+		m.EXPECT().Reload(gomock.Any()).Return(nil, errors.New("error"))
 
 		router := gin.New()
 		router.POST("/api/v1/reload", reloadHandler(m))
@@ -367,6 +530,556 @@ func TestReloadHandler(t *testing.T) {
 	})
 }
 
+func TestGetCapacityHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Success", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetCapacity(gomock.Any()).Return(&fireactions.Capacity{
+			Enabled: true,
+			Vcpus:   fireactions.CapacityResource{Total: 4, Allocatable: 4, Allocated: 2, Available: 2},
+			Pools:   fireactions.PoolCapacities{{Pool: "pool1", Runners: 1, Vcpus: 2}},
+		}, nil)
+
+		router := gin.New()
+		router.GET("/api/v1/capacity", getCapacityHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/capacity", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"capacity":{"enabled":true,"vcpus":{"total":4,"reserved":0,"overcommit_ratio":0,"allocatable":4,"allocated":2,"available":2},"memory_mib":{"total":0,"reserved":0,"overcommit_ratio":0,"allocatable":0,"allocated":0,"available":0},"pools":[{"pool":"pool1","priority":0,"runners":1,"vcpus":2,"memory_mib":0}]}}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetCapacity(gomock.Any()).Return(nil, errors.New("error"))
+
+		router := gin.New()
+		router.GET("/api/v1/capacity", getCapacityHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/capacity", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code %d, but got %d", http.StatusInternalServerError, rec.Code)
+		}
+	})
+}
+
+func TestGetEventsHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("History", func(t *testing.T) {
+		bus := NewEventBus()
+		bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool1", Time: time.Unix(0, 0).UTC()})
+		bus.Publish(&fireactions.Event{Type: fireactions.EventTypeConfigReloaded, Time: time.Unix(0, 0).UTC()})
+
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().SubscribeEvents(gomock.Any(), "", uint64(1)).DoAndReturn(func(_ context.Context, pool string, lastID uint64) (*EventSubscription, error) {
+			return bus.Subscribe(pool, lastID), nil
+		})
+
+		router := gin.New()
+		router.GET("/api/v1/events", getEventsHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/events?follow=false", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Last-Event-ID", "1")
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := "id: 2\nevent: ConfigReloaded\ndata: {\"id\":2,\"type\":\"ConfigReloaded\",\"time\":\"1970-01-01T00:00:00Z\"}\n\n"
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+
+		if rec.Header().Get("Content-Type") != "text/event-stream" {
+			t.Errorf("Expected content type text/event-stream, but got %s", rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("Follow", func(t *testing.T) {
+		bus := NewEventBus()
+
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().SubscribeEvents(gomock.Any(), "pool1", uint64(0)).DoAndReturn(func(_ context.Context, pool string, lastID uint64) (*EventSubscription, error) {
+			subscription := bus.Subscribe(pool, lastID)
+			go func() {
+				bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool2"})
+				bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool1"})
+				bus.Close()
+			}()
+
+			return subscription, nil
+		})
+
+		router := gin.New()
+		router.GET("/api/v1/events", getEventsHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/events?pool=pool1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if !strings.Contains(rec.Body.String(), "id: 2\nevent: PoolPaused\n") || strings.Contains(rec.Body.String(), "id: 1\n") {
+			t.Errorf("Unexpected response body %s", rec.Body.String())
+		}
+	})
+
+	t.Run("PoolNotFound", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().SubscribeEvents(gomock.Any(), "pool3", uint64(0)).Return(nil, fireactions.ErrPoolNotFound)
+
+		router := gin.New()
+		router.GET("/api/v1/events", getEventsHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/events?pool=pool3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("InvalidFollow", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+
+		router := gin.New()
+		router.GET("/api/v1/events", getEventsHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/events?follow=maybe", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestListRunnersHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Success", func(t *testing.T) {
+		runner := newRunner("test-1", "test", "/var/lib/fireactions/pools/test/test-1.sock", "/var/lib/fireactions/pools/test/test-1.log")
+		runner.StartedAt = time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+		runner.IPAddress = "10.0.0.2"
+
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ListRunners(gomock.Any(), "test").Return([]*Runner{runner}, nil)
+
+		router := gin.New()
+		router.GET("/api/v1/pools/:id/runners", listRunnersHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/pools/test/runners", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"runners":[{"name":"test-1","pool":"test","state":"Starting","vmid":"test-1","socket_path":"/var/lib/fireactions/pools/test/test-1.sock","log_path":"/var/lib/fireactions/pools/test/test-1.log","ip_address":"10.0.0.2","started_at":"2024-07-01T10:00:00Z"}]}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ListRunners(gomock.Any(), "test").Return(nil, fireactions.ErrPoolNotFound)
+
+		router := gin.New()
+		router.GET("/api/v1/pools/:id/runners", listRunnersHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/pools/test/runners", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestGetRunnerHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Success", func(t *testing.T) {
+		runner := newRunner("test-1", "test", "", "")
+		runner.StartedAt = time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+		runner.SetState(fireactions.RunnerStateBusy)
+
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetRunner(gomock.Any(), "test-1").Return(runner, nil)
+
+		router := gin.New()
+		router.GET("/api/v1/runners/:name", getRunnerHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/runners/test-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"runner":{"name":"test-1","pool":"test","state":"Busy","vmid":"test-1","socket_path":"","log_path":"","ip_address":"","started_at":"2024-07-01T10:00:00Z"}}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetRunner(gomock.Any(), "test-1").Return(nil, fireactions.ErrRunnerNotFound)
+
+		router := gin.New()
+		router.GET("/api/v1/runners/:name", getRunnerHandler(m))
+
+		req, err := http.NewRequest("GET", "/api/v1/runners/test-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestDeleteRunnerHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Graceful", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DeleteRunner(gomock.Any(), "test-1", false).Return(nil)
+
+		router := gin.New()
+		router.DELETE("/api/v1/runners/:name", deleteRunnerHandler(m))
+
+		req, err := http.NewRequest("DELETE", "/api/v1/runners/test-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected status code %d, but got %d", http.StatusAccepted, rec.Code)
+		}
+
+		expectedBody := `{"message":"Runner shutdown requested"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DeleteRunner(gomock.Any(), "test-1", true).Return(nil)
+
+		router := gin.New()
+		router.DELETE("/api/v1/runners/:name", deleteRunnerHandler(m))
+
+		req, err := http.NewRequest("DELETE", "/api/v1/runners/test-1?force=true", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"message":"Runner deleted successfully"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("InvalidForce", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+
+		router := gin.New()
+		router.DELETE("/api/v1/runners/:name", deleteRunnerHandler(m))
+
+		req, err := http.NewRequest("DELETE", "/api/v1/runners/test-1?force=maybe", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().DeleteRunner(gomock.Any(), "test-1", false).Return(fireactions.ErrRunnerNotFound)
+
+		router := gin.New()
+		router.DELETE("/api/v1/runners/:name", deleteRunnerHandler(m))
+
+		req, err := http.NewRequest("DELETE", "/api/v1/runners/test-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestGitHubWebhookHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	newPool := func(name string, labels []string, isActive bool) *Pool {
+		pool := &Pool{
+			runners:   make(map[string]*Runner),
+			runnersMu: &sync.Mutex{},
+		}
+		pool.config.Store(&PoolConfig{
+			Name:       name,
+			MaxRunners: 1,
+			MinRunners: 1,
+			Runner:     &RunnerConfig{Labels: labels},
+		})
+		pool.isActive.Store(isActive)
+
+		return pool
+	}
+
+	newRequest := func(t *testing.T, event, file, secret string) *http.Request {
+		payload, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("POST", "/webhooks/github", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", signWebhookPayload(payload, secret))
+		return req
+	}
+
+	t.Run("Queued", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ListPools(gomock.Any()).Return([]*Pool{
+			newPool("other", []string{"self-hosted", "other"}, true),
+			newPool("test", []string{"self-hosted", "fireactions-2vcpu-2gb", "fireactions"}, true),
+		}, nil)
+		m.EXPECT().ScalePool(gomock.Any(), "test", 1).Return(&ScaleResult{Replicas: 1}, nil)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_queued.json", "secret"))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"message":"Pool scaled successfully","pool":"test"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("QueuedPausedPool", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ListPools(gomock.Any()).Return([]*Pool{
+			newPool("test", []string{"self-hosted", "fireactions-2vcpu-2gb"}, false),
+		}, nil)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_queued.json", "secret"))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"message":"No matching pool with free capacity found"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("InProgress", func(t *testing.T) {
+		runner := newRunner("fireactions-2vcpu-2gb-abcdef", "test", "", "")
+		runner.SetState(fireactions.RunnerStateIdle)
+
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetRunner(gomock.Any(), "fireactions-2vcpu-2gb-abcdef").Return(runner, nil)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_in_progress.json", "secret"))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"message":"Runner state updated","runner":"fireactions-2vcpu-2gb-abcdef"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+
+		if runner.GetState() != fireactions.RunnerStateBusy {
+			t.Errorf("Expected runner state %s, but got %s", fireactions.RunnerStateBusy, runner.GetState())
+		}
+	})
+
+	t.Run("InProgressUnknownRunner", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().GetRunner(gomock.Any(), "fireactions-2vcpu-2gb-abcdef").Return(nil, fireactions.ErrRunnerNotFound)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_in_progress.json", "secret"))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, rec.Code)
+		}
+
+		expectedBody := `{"message":"Event ignored"}`
+		if rec.Body.String() != expectedBody {
+			t.Errorf("Expected response body %s, but got %s", expectedBody, rec.Body.String())
+		}
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_queued.json", "wrong"))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, but got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := newMockPoolManager(mockCtrl)
+		m.EXPECT().ListPools(gomock.Any()).Return([]*Pool{
+			newPool("test", []string{"self-hosted", "fireactions-2vcpu-2gb"}, true),
+		}, nil)
+		m.EXPECT().ScalePool(gomock.Any(), "test", 1).Return(&ScaleResult{Errors: []error{errors.New("error")}}, nil)
+
+		router := gin.New()
+		router.POST("/webhooks/github", githubWebhookHandler(m, func() string { return "secret" }))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest(t, "workflow_job", "testdata/workflow_job_queued.json", "secret"))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code %d, but got %d", http.StatusInternalServerError, rec.Code)
+		}
+	})
+}
+
+func signWebhookPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 func init() {
 	gin.SetMode(gin.TestMode)
 }
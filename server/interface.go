@@ -1,13 +1,25 @@
 package server
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/hostinger/fireactions"
+)
 
 // PoolManager is an interface for managing pools.
 type PoolManager interface {
 	ListPools(ctx context.Context) ([]*Pool, error)
 	GetPool(ctx context.Context, id string) (*Pool, error)
-	ScalePool(ctx context.Context, id string, delta int) error
+	ScalePool(ctx context.Context, id string, delta int) (*ScaleResult, error)
+	ScalePoolTo(ctx context.Context, id string, replicas int) (*ScaleResult, error)
 	PausePool(ctx context.Context, id string) error
 	ResumePool(ctx context.Context, id string) error
-	Reload(ctx context.Context) error
+	DrainPool(ctx context.Context, id string, timeout time.Duration) error
+	Reload(ctx context.Context) (*fireactions.ReloadResult, error)
+	ListRunners(ctx context.Context, poolID string) ([]*Runner, error)
+	GetRunner(ctx context.Context, name string) (*Runner, error)
+	DeleteRunner(ctx context.Context, name string, force bool) error
+	GetCapacity(ctx context.Context) (*fireactions.Capacity, error)
+	SubscribeEvents(ctx context.Context, poolID string, lastID uint64) (*EventSubscription, error)
 }
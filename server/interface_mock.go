@@ -3,7 +3,9 @@ package server
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	fireactions "github.com/hostinger/fireactions"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -30,6 +32,49 @@ func (m *mockPoolManager) EXPECT() *mockPoolManagerMockRecorder {
 	return m.recorder
 }
 
+// DeleteRunner mocks base method.
+func (m *mockPoolManager) DeleteRunner(ctx context.Context, name string, force bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRunner", ctx, name, force)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRunner indicates an expected call of DeleteRunner.
+func (mr *mockPoolManagerMockRecorder) DeleteRunner(ctx, name, force any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRunner", reflect.TypeOf((*mockPoolManager)(nil).DeleteRunner), ctx, name, force)
+}
+
+// DrainPool mocks base method.
+func (m *mockPoolManager) DrainPool(ctx context.Context, id string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrainPool", ctx, id, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DrainPool indicates an expected call of DrainPool.
+func (mr *mockPoolManagerMockRecorder) DrainPool(ctx, id, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrainPool", reflect.TypeOf((*mockPoolManager)(nil).DrainPool), ctx, id, timeout)
+}
+
+// GetCapacity mocks base method.
+func (m *mockPoolManager) GetCapacity(ctx context.Context) (*fireactions.Capacity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCapacity", ctx)
+	ret0, _ := ret[0].(*fireactions.Capacity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCapacity indicates an expected call of GetCapacity.
+func (mr *mockPoolManagerMockRecorder) GetCapacity(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCapacity", reflect.TypeOf((*mockPoolManager)(nil).GetCapacity), ctx)
+}
+
 // GetPool mocks base method.
 func (m *mockPoolManager) GetPool(ctx context.Context, id string) (*Pool, error) {
 	m.ctrl.T.Helper()
@@ -45,6 +90,21 @@ func (mr *mockPoolManagerMockRecorder) GetPool(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPool", reflect.TypeOf((*mockPoolManager)(nil).GetPool), ctx, id)
 }
 
+// GetRunner mocks base method.
+func (m *mockPoolManager) GetRunner(ctx context.Context, name string) (*Runner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunner", ctx, name)
+	ret0, _ := ret[0].(*Runner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRunner indicates an expected call of GetRunner.
+func (mr *mockPoolManagerMockRecorder) GetRunner(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunner", reflect.TypeOf((*mockPoolManager)(nil).GetRunner), ctx, name)
+}
+
 // ListPools mocks base method.
 func (m *mockPoolManager) ListPools(ctx context.Context) ([]*Pool, error) {
 	m.ctrl.T.Helper()
@@ -60,6 +120,21 @@ func (mr *mockPoolManagerMockRecorder) ListPools(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPools", reflect.TypeOf((*mockPoolManager)(nil).ListPools), ctx)
 }
 
+// ListRunners mocks base method.
+func (m *mockPoolManager) ListRunners(ctx context.Context, poolID string) ([]*Runner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRunners", ctx, poolID)
+	ret0, _ := ret[0].([]*Runner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRunners indicates an expected call of ListRunners.
+func (mr *mockPoolManagerMockRecorder) ListRunners(ctx, poolID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRunners", reflect.TypeOf((*mockPoolManager)(nil).ListRunners), ctx, poolID)
+}
+
 // PausePool mocks base method.
 func (m *mockPoolManager) PausePool(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
@@ -75,11 +150,12 @@ func (mr *mockPoolManagerMockRecorder) PausePool(ctx, id any) *gomock.Call {
 }
 
 // Reload mocks base method.
-func (m *mockPoolManager) Reload(ctx context.Context) error {
+func (m *mockPoolManager) Reload(ctx context.Context) (*fireactions.ReloadResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Reload", ctx)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*fireactions.ReloadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Reload indicates an expected call of Reload.
@@ -103,11 +179,12 @@ func (mr *mockPoolManagerMockRecorder) ResumePool(ctx, id any) *gomock.Call {
 }
 
 // ScalePool mocks base method.
-func (m *mockPoolManager) ScalePool(ctx context.Context, id string, delta int) error {
+func (m *mockPoolManager) ScalePool(ctx context.Context, id string, delta int) (*ScaleResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ScalePool", ctx, id, delta)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*ScaleResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ScalePool indicates an expected call of ScalePool.
@@ -115,3 +192,33 @@ func (mr *mockPoolManagerMockRecorder) ScalePool(ctx, id, delta any) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScalePool", reflect.TypeOf((*mockPoolManager)(nil).ScalePool), ctx, id, delta)
 }
+
+// ScalePoolTo mocks base method.
+func (m *mockPoolManager) ScalePoolTo(ctx context.Context, id string, replicas int) (*ScaleResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScalePoolTo", ctx, id, replicas)
+	ret0, _ := ret[0].(*ScaleResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScalePoolTo indicates an expected call of ScalePoolTo.
+func (mr *mockPoolManagerMockRecorder) ScalePoolTo(ctx, id, replicas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScalePoolTo", reflect.TypeOf((*mockPoolManager)(nil).ScalePoolTo), ctx, id, replicas)
+}
+
+// SubscribeEvents mocks base method.
+func (m *mockPoolManager) SubscribeEvents(ctx context.Context, poolID string, lastID uint64) (*EventSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeEvents", ctx, poolID, lastID)
+	ret0, _ := ret[0].(*EventSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeEvents indicates an expected call of SubscribeEvents.
+func (mr *mockPoolManagerMockRecorder) SubscribeEvents(ctx, poolID, lastID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeEvents", reflect.TypeOf((*mockPoolManager)(nil).SubscribeEvents), ctx, poolID, lastID)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+)
+
+const (
+	// jailerSocketPath is the path of the API socket in the chroot.
+	jailerSocketPath = "/firecracker.sock"
+
+	// jailerKernelPath, jailerMemFilePath and jailerStatePath are the paths
+	// of the kernel and the snapshot files in the chroot.
+	jailerKernelPath  = "vmlinux"
+	jailerMemFilePath = "memory"
+	jailerStatePath   = "vmstate"
+
+	// jailerCgroup is the parent cgroup of the VMs created by the jailer.
+	jailerCgroup = "firecracker"
+	cgroupRoot   = "/sys/fs/cgroup"
+)
+
+// chrootStrategy is a no-op firecracker.HandlersAdapter. The files are linked
+// into the chroot by the handler of newJailerLinkHandler, added once the
+// handlers are final, as restoring from a snapshot replaces them.
+type chrootStrategy struct{}
+
+func (chrootStrategy) AdaptHandlers(*firecracker.Handlers) error { return nil }
+
+// getJailerDir returns the directory of the chroot of the machine, which the
+// jailer creates under the name of the Firecracker binary.
+func (p *firecrackerProvider) getJailerDir(id string) string {
+	return filepath.Join(p.jailer.GetChrootBaseDir(), filepath.Base(p.execFile), id)
+}
+
+// getJailerRoot returns the root of the chroot of the machine.
+func (p *firecrackerProvider) getJailerRoot(id string) string {
+	return filepath.Join(p.getJailerDir(id), "root")
+}
+
+// jail configures the machine to be launched through the jailer, in the
+// network namespace set up by CNI, and returns the jailer command. The API
+// socket is created in the chroot.
+func (p *firecrackerProvider) jail(config *firecracker.Config, stdout io.Writer) *exec.Cmd {
+	config.SocketPath = jailerSocketPath
+	config.NetNS = filepath.Join(defaultNetNSDir, config.VMID)
+	config.JailerCfg = &firecracker.JailerConfig{
+		ID:             config.VMID,
+		UID:            firecracker.Int(p.jailer.UID),
+		GID:            firecracker.Int(p.jailer.GID),
+		NumaNode:       firecracker.Int(0),
+		ExecFile:       p.execFile,
+		JailerBinary:   p.jailer.GetBinaryPath(),
+		ChrootBaseDir:  p.jailer.GetChrootBaseDir(),
+		CgroupVersion:  strconv.Itoa(p.jailer.GetCgroupVersion()),
+		ChrootStrategy: chrootStrategy{},
+		Stdout:         stdout,
+		Stderr:         stdout,
+	}
+
+	cmd := exec.Command(p.jailer.GetBinaryPath(), getJailerArgs(config.VMID, p.execFile, config.NetNS, p.jailer)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stdout
+
+	return cmd
+}
+
+// getJailerArgs returns the arguments of the jailer, followed by the ones of
+// Firecracker.
+func getJailerArgs(id, execFile, netNS string, config *JailerConfig) []string {
+	args := []string{
+		"--id", id,
+		"--uid", strconv.Itoa(config.UID),
+		"--gid", strconv.Itoa(config.GID),
+		"--exec-file", execFile,
+		"--chroot-base-dir", config.GetChrootBaseDir(),
+		"--netns", netNS,
+		"--cgroup-version", strconv.Itoa(config.GetCgroupVersion()),
+	}
+
+	files := make([]string, 0, len(config.Cgroups))
+	for file := range config.Cgroups {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		args = append(args, "--cgroup", fmt.Sprintf("%s=%s", file, config.Cgroups[file]))
+	}
+
+	return append(args, "--", "--api-sock", jailerSocketPath)
+}
+
+// newJailerLinkHandler returns a Firecracker handler that links the kernel,
+// the drives and the snapshot files of the machine into its chroot, once the
+// jailer has created it, and points the configuration at them.
+func (p *firecrackerProvider) newJailerLinkHandler() firecracker.Handler {
+	return firecracker.Handler{
+		Name: "fireactions.LinkFilesToJail",
+		Fn: func(ctx context.Context, m *firecracker.Machine) error {
+			root := p.getJailerRoot(m.Cfg.VMID)
+
+			if m.Cfg.KernelImagePath != "" {
+				if err := p.linkToJail(m.Cfg.KernelImagePath, filepath.Join(root, jailerKernelPath), false, true); err != nil {
+					return fmt.Errorf("linking kernel: %w", err)
+				}
+				m.Cfg.KernelImagePath = jailerKernelPath
+			}
+
+			for i, drive := range m.Cfg.Drives {
+				id := firecracker.StringValue(drive.DriveID)
+				if err := p.linkToJail(firecracker.StringValue(drive.PathOnHost), filepath.Join(root, id), !firecracker.BoolValue(drive.IsReadOnly), false); err != nil {
+					return fmt.Errorf("linking drive %s: %w", id, err)
+				}
+				m.Cfg.Drives[i].PathOnHost = firecracker.String(id)
+			}
+
+			if m.Cfg.Snapshot.MemFilePath != "" {
+				if err := p.linkToJail(m.Cfg.Snapshot.MemFilePath, filepath.Join(root, jailerMemFilePath), false, false); err != nil {
+					return fmt.Errorf("linking snapshot memory file: %w", err)
+				}
+
+				if err := p.linkToJail(m.Cfg.Snapshot.SnapshotPath, filepath.Join(root, jailerStatePath), false, false); err != nil {
+					return fmt.Errorf("linking snapshot file: %w", err)
+				}
+
+				m.Cfg.Snapshot.MemFilePath, m.Cfg.Snapshot.SnapshotPath = jailerMemFilePath, jailerStatePath
+			}
+
+			return nil
+		},
+	}
+}
+
+// linkToJail makes the file at src available at dst in a chroot: block
+// devices, e.g. devmapper thin devices, are recreated with mknod, and other
+// files are hard linked, or copied if allowed. Writable files are owned by
+// the jailer user.
+func (p *firecrackerProvider) linkToJail(src, dst string, writable, allowCopy bool) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeDevice != 0 {
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("%s: unsupported file", src)
+		}
+
+		if err := syscall.Mknod(dst, syscall.S_IFBLK|0600, int(stat.Rdev)); err != nil {
+			return fmt.Errorf("mknod: %w", err)
+		}
+
+		return os.Chown(dst, p.jailer.UID, p.jailer.GID)
+	}
+
+	err = os.Link(src, dst)
+	switch {
+	case errors.Is(err, syscall.EXDEV) && allowCopy:
+		output, err := exec.Command("cp", "--reflink=auto", src, dst).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cp: %w: %s", err, output)
+		}
+	case errors.Is(err, syscall.EXDEV):
+		return fmt.Errorf("%s must be on the same filesystem as the chroot base directory %s", src, p.jailer.GetChrootBaseDir())
+	case err != nil:
+		return err
+	}
+
+	if !writable {
+		return nil
+	}
+
+	return os.Chown(dst, p.jailer.UID, p.jailer.GID)
+}
+
+// removeJail removes the chroot and the cgroups of the machine, which the
+// jailer leaves behind.
+func (p *firecrackerProvider) removeJail(id string) error {
+	var errs []error
+	if err := os.RemoveAll(p.getJailerDir(id)); err != nil {
+		errs = append(errs, fmt.Errorf("removing chroot: %w", err))
+	}
+
+	// cgroup v1 has a hierarchy per controller, cgroup v2 a single one.
+	cgroups, _ := filepath.Glob(filepath.Join(cgroupRoot, "*", jailerCgroup, id))
+	cgroups = append(cgroups, filepath.Join(cgroupRoot, jailerCgroup, id))
+	for _, cgroup := range cgroups {
+		if err := os.Remove(cgroup); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("removing cgroup: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
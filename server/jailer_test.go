@@ -0,0 +1,54 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJailerArgs(t *testing.T) {
+	config := &JailerConfig{Enabled: true, UID: 1000, GID: 1001, Cgroups: map[string]string{"memory.max": "4G", "cpu.max": "200000 100000"}}
+
+	args := getJailerArgs("runner-1", "/usr/bin/firecracker", "/var/run/netns/runner-1", config)
+	assert.Equal(t, []string{
+		"--id", "runner-1",
+		"--uid", "1000",
+		"--gid", "1001",
+		"--exec-file", "/usr/bin/firecracker",
+		"--chroot-base-dir", defaultJailerChrootBaseDir,
+		"--netns", "/var/run/netns/runner-1",
+		"--cgroup-version", "2",
+		"--cgroup", "cpu.max=200000 100000",
+		"--cgroup", "memory.max=4G",
+		"--", "--api-sock", jailerSocketPath,
+	}, args)
+}
+
+func TestFirecrackerProvider_LinkToJail(t *testing.T) {
+	dir := t.TempDir()
+	p := &firecrackerProvider{jailer: &JailerConfig{ChrootBaseDir: filepath.Join(dir, "jailer"), UID: 1000, GID: 1000}, execFile: "/usr/bin/firecracker"}
+
+	root := p.getJailerRoot("runner-1")
+	assert.Equal(t, filepath.Join(dir, "jailer", "firecracker", "runner-1", "root"), root)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(dir, "toolcache.ext4")
+	if err := os.WriteFile(src, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, p.linkToJail(src, filepath.Join(root, "toolcache"), false, false))
+	data, err := os.ReadFile(filepath.Join(root, "toolcache"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	assert.Error(t, p.linkToJail(filepath.Join(dir, "missing.ext4"), filepath.Join(root, "missing"), false, false))
+
+	assert.NoError(t, p.removeJail("runner-1"))
+	assert.NoDirExists(t, p.getJailerDir("runner-1"))
+	assert.FileExists(t, src)
+}
@@ -72,4 +72,96 @@ var (
 		Subsystem: "pool",
 		Help:      "Status of a pool. 0 is paused, 1 is active.",
 	}, []string{"pool"})
+
+	metricGCReclaimed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "reclaimed_total",
+		Namespace: namespace,
+		Subsystem: "gc",
+		Help:      "Number of stale items removed by the garbage collector",
+	}, []string{"pool", "kind"})
+
+	metricGCReclaimedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "reclaimed_bytes_total",
+		Namespace: namespace,
+		Subsystem: "gc",
+		Help:      "Number of bytes of log files removed by the garbage collector",
+	}, []string{"pool"})
+
+	metricGCRotatedLogs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "rotated_logs_total",
+		Namespace: namespace,
+		Subsystem: "gc",
+		Help:      "Number of runner log files rotated by the garbage collector",
+	}, []string{"pool"})
+
+	metricRunnerBootDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "boot_duration_seconds",
+		Namespace: namespace,
+		Subsystem: "runner",
+		Help:      "Time from the creation of a runner machine until the runner agent received its configuration",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 10),
+	}, []string{"pool", "mode"})
+
+	metricWarmBootTemplateBuilds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "template_builds_total",
+		Namespace: namespace,
+		Subsystem: "warm_boot",
+		Help:      "Number of warm boot templates built",
+	}, []string{"pool", "result"})
+
+	metricCapacityAllocatableVcpus = promauto.NewGauge(prometheus.GaugeOpts{
+		Name:      "allocatable_vcpus",
+		Namespace: namespace,
+		Subsystem: "capacity",
+		Help:      "Number of vCPUs of the host allocatable to runners, after reservation and overcommit",
+	})
+
+	metricCapacityAllocatableMemoryMib = promauto.NewGauge(prometheus.GaugeOpts{
+		Name:      "allocatable_memory_mib",
+		Namespace: namespace,
+		Subsystem: "capacity",
+		Help:      "Memory of the host allocatable to runners in MiB, after reservation and overcommit",
+	})
+
+	metricCapacityAllocatedVcpus = promauto.NewGauge(prometheus.GaugeOpts{
+		Name:      "allocated_vcpus",
+		Namespace: namespace,
+		Subsystem: "capacity",
+		Help:      "Number of vCPUs allocated to runners",
+	})
+
+	metricCapacityAllocatedMemoryMib = promauto.NewGauge(prometheus.GaugeOpts{
+		Name:      "allocated_memory_mib",
+		Namespace: namespace,
+		Subsystem: "capacity",
+		Help:      "Memory allocated to runners in MiB",
+	})
+
+	metricCapacityRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "rejections_total",
+		Namespace: namespace,
+		Subsystem: "capacity",
+		Help:      "Number of runners not admitted because of insufficient host capacity",
+	}, []string{"pool"})
+
+	metricNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "sent_total",
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Help:      "Number of notifications sent to a sink",
+	}, []string{"sink", "result"})
+
+	metricConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "reloads_total",
+		Namespace: namespace,
+		Subsystem: "config",
+		Help:      "Number of configuration reloads",
+	}, []string{"trigger", "result"})
+
+	metricConfigLastReloadSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name:      "last_reload_success_timestamp_seconds",
+		Namespace: namespace,
+		Subsystem: "config",
+		Help:      "Time of the last successful configuration reload",
+	})
 )
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/firecracker-microvm/firecracker-go-sdk/cni/vmconf"
+)
+
+const (
+	// defaultNetNSDir and defaultCNICacheDir are where the Firecracker SDK
+	// creates the network namespace and caches the CNI results of a machine.
+	defaultNetNSDir    = "/var/run/netns"
+	defaultCNICacheDir = "/var/lib/cni"
+)
+
+// checkNetworks returns an error if the CNI network configuration list of an
+// interface can't be loaded.
+func checkNetworks(config *NetworkConfig) error {
+	for _, iface := range config.Interfaces {
+		if _, err := libcni.LoadConfList(config.ConfDir, iface.NetworkName); err != nil {
+			return fmt.Errorf("network %s: %w", iface.NetworkName, err)
+		}
+	}
+
+	return nil
+}
+
+// newNetworkInterface returns the primary network interface of the machine
+// with the given ID, which is set up by the Firecracker SDK.
+func (p *firecrackerProvider) newNetworkInterface(id string) (firecracker.NetworkInterface, error) {
+	iface := p.network.Interfaces[0]
+	ip, err := p.ips.Allocate(id, 0)
+	if err != nil {
+		return firecracker.NetworkInterface{}, err
+	}
+
+	var args [][2]string
+	if ip != nil {
+		args = getCNIArgs(ip, "")
+	}
+
+	networkInterface := firecracker.NetworkInterface{
+		AllowMMDS: true,
+		CNIConfiguration: &firecracker.CNIConfiguration{
+			NetworkName: iface.NetworkName,
+			IfName:      iface.IfName,
+			ConfDir:     p.network.ConfDir,
+			BinPath:     p.network.BinDirs,
+			Args:        args,
+		},
+		InRateLimiter:  newRateLimiter(iface.RxRateLimiter),
+		OutRateLimiter: newRateLimiter(iface.TxRateLimiter),
+	}
+
+	return networkInterface, nil
+}
+
+// newSecondaryNetworksHandler returns a Firecracker handler that attaches
+// the machine to the networks of the secondary interfaces, in the network
+// namespace of the primary one. The SDK supports a single CNI interface, so
+// these are invoked directly and added as static interfaces, whose addresses
+// are provided in the metadata for the runner agent to configure.
+func (p *firecrackerProvider) newSecondaryNetworksHandler(metadata map[string]interface{}) firecracker.Handler {
+	return firecracker.Handler{
+		Name: "fireactions.SetupSecondaryNetworks",
+		Fn: func(ctx context.Context, m *firecracker.Machine) error {
+			interfaces := make([]interface{}, 0, len(p.network.Interfaces)-1)
+			for i := 1; i < len(p.network.Interfaces); i++ {
+				result, err := p.addNetwork(ctx, m.Cfg.VMID, m.Cfg.NetNS, i)
+				if err != nil {
+					return fmt.Errorf("network %s: %w", p.network.Interfaces[i].NetworkName, err)
+				}
+
+				vmConf, err := vmconf.StaticNetworkConfFrom(result, m.Cfg.VMID)
+				if err != nil {
+					return fmt.Errorf("network %s: %w", p.network.Interfaces[i].NetworkName, err)
+				}
+
+				m.Cfg.NetworkInterfaces = append(m.Cfg.NetworkInterfaces, firecracker.NetworkInterface{
+					StaticConfiguration: &firecracker.StaticNetworkConfiguration{HostDevName: vmConf.TapName, MacAddress: vmConf.VMMacAddr},
+					InRateLimiter:       newRateLimiter(p.network.Interfaces[i].RxRateLimiter),
+					OutRateLimiter:      newRateLimiter(p.network.Interfaces[i].TxRateLimiter),
+				})
+
+				if vmConf.VMIPConfig == nil {
+					continue
+				}
+
+				interfaces = append(interfaces, map[string]interface{}{
+					"interface":   fmt.Sprintf("eth%d", i),
+					"address":     vmConf.VMIPConfig.Address.String(),
+					"mac_address": vmConf.VMMacAddr,
+				})
+			}
+
+			getFireactionsMetadata(metadata)["interfaces"] = interfaces
+			return nil
+		},
+	}
+}
+
+// addNetwork attaches the machine to the network of the i-th interface.
+func (p *firecrackerProvider) addNetwork(ctx context.Context, id, netNS string, i int) (types.Result, error) {
+	ip, err := p.ips.Allocate(id, i)
+	if err != nil {
+		return nil, err
+	}
+
+	iface := p.network.Interfaces[i]
+	networkConf, err := libcni.LoadConfList(p.network.ConfDir, iface.NetworkName)
+	if err != nil {
+		return nil, err
+	}
+
+	cni := libcni.NewCNIConfigWithCacheDir(p.network.BinDirs, filepath.Join(defaultCNICacheDir, id), nil)
+	runtimeConf := &libcni.RuntimeConf{ContainerID: id, NetNS: netNS, IfName: iface.IfName, Args: getCNIArgs(ip, getTapName(i))}
+
+	return cni.AddNetworkList(ctx, networkConf, runtimeConf)
+}
+
+// removeNetworks detaches the machine from the networks it's still attached
+// to, e.g. when it was adopted or its secondary interfaces, and releases its
+// addresses.
+func (p *firecrackerProvider) removeNetworks(ctx context.Context, id string) error {
+	defer p.ips.Release(id)
+
+	var errs []error
+	for i, iface := range p.network.Interfaces {
+		networkConf, err := libcni.LoadConfList(p.network.ConfDir, iface.NetworkName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("network %s: %w", iface.NetworkName, err))
+			continue
+		}
+
+		cni := libcni.NewCNIConfigWithCacheDir(p.network.BinDirs, filepath.Join(defaultCNICacheDir, id), nil)
+		runtimeConf := &libcni.RuntimeConf{ContainerID: id, NetNS: filepath.Join(defaultNetNSDir, id), IfName: iface.IfName}
+		if i > 0 {
+			runtimeConf.Args = getCNIArgs(nil, getTapName(i))
+		}
+
+		cached, err := cni.GetNetworkListCachedResult(networkConf, runtimeConf)
+		if err != nil || cached == nil {
+			continue
+		}
+
+		if err := cni.DelNetworkList(ctx, networkConf, runtimeConf); err != nil {
+			errs = append(errs, fmt.Errorf("network %s: %w", iface.NetworkName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// getCNIArgs returns the CNI arguments requesting the IP address from the
+// host-local IPAM plugin and naming the tap device created by tc-redirect-tap.
+func getCNIArgs(ip net.IP, tapName string) [][2]string {
+	args := [][2]string{{"IgnoreUnknown", "1"}}
+	if ip != nil {
+		args = append(args, [2]string{"IP", ip.String()})
+	}
+
+	if tapName != "" {
+		args = append(args, [2]string{"TC_REDIRECT_TAP_NAME", tapName})
+	}
+
+	return args
+}
+
+// getTapName returns the name of the tap device of the i-th interface in the
+// network namespace of the machine.
+func getTapName(i int) string {
+	return fmt.Sprintf("tap%d", i)
+}
+
+// getFireactionsMetadata returns the metadata read by the runner agent.
+func getFireactionsMetadata(metadata map[string]interface{}) map[string]interface{} {
+	return metadata["latest"].(map[string]interface{})["meta-data"].(map[string]interface{})["fireactions"].(map[string]interface{})
+}
+
+// ipRange is an inclusive range of IPv4 addresses.
+type ipRange struct {
+	first uint32
+	last  uint32
+}
+
+// parseIPRange parses an IPv4 CIDR, excluding its network and broadcast
+// addresses, or a first-last range of IPv4 addresses.
+func parseIPRange(s string) (*ipRange, error) {
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		firstIP, lastIP := net.ParseIP(strings.TrimSpace(first)).To4(), net.ParseIP(strings.TrimSpace(last)).To4()
+		if firstIP == nil || lastIP == nil {
+			return nil, fmt.Errorf("invalid IPv4 range %q", s)
+		}
+
+		r := &ipRange{first: binary.BigEndian.Uint32(firstIP), last: binary.BigEndian.Uint32(lastIP)}
+		if r.first > r.last {
+			return nil, fmt.Errorf("invalid IPv4 range %q: first address is after the last one", s)
+		}
+
+		return r, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil || ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 CIDR or range %q", s)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	r := &ipRange{first: binary.BigEndian.Uint32(ipNet.IP.To4())}
+	r.last = r.first | (1<<(bits-ones) - 1)
+	if bits-ones >= 2 {
+		r.first++
+		r.last--
+	}
+
+	return r, nil
+}
+
+// ipAllocator allocates the addresses of the interfaces with an IP range to
+// the machines of a pool.
+type ipAllocator struct {
+	ranges []*ipRange
+	used   map[string]string
+	l      *sync.Mutex
+}
+
+func newIPAllocator(config *NetworkConfig) *ipAllocator {
+	a := &ipAllocator{
+		ranges: make([]*ipRange, len(config.Interfaces)),
+		used:   make(map[string]string),
+		l:      &sync.Mutex{},
+	}
+
+	for i, iface := range config.Interfaces {
+		if iface.IPRange != "" {
+			a.ranges[i], _ = parseIPRange(iface.IPRange)
+		}
+	}
+
+	return a
+}
+
+// Allocate returns the first free address of the range of the i-th interface
+// for the machine with the given ID, or nil if the interface has no range.
+func (a *ipAllocator) Allocate(id string, i int) (net.IP, error) {
+	r := a.ranges[i]
+	if r == nil {
+		return nil, nil
+	}
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for n := r.first; n <= r.last && n >= r.first; n++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		key := fmt.Sprintf("%d/%s", i, ip)
+
+		owner, ok := a.used[key]
+		if ok && owner != id {
+			continue
+		}
+
+		a.used[key] = id
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("no free address left in range %s", formatIPRange(r))
+}
+
+// Reserve marks the address of the i-th interface as used by the machine
+// with the given ID, e.g. when adopted after a restart.
+func (a *ipAllocator) Reserve(id string, i int, ip net.IP) {
+	if i >= len(a.ranges) || a.ranges[i] == nil || ip.To4() == nil {
+		return
+	}
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	a.used[fmt.Sprintf("%d/%s", i, ip.To4())] = id
+}
+
+// Addresses returns the addresses of the machine with the given ID, indexed
+// by interface. The address of an interface without a range is empty.
+func (a *ipAllocator) Addresses(id string) []string {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	addresses := make([]string, len(a.ranges))
+	for key, owner := range a.used {
+		if owner != id {
+			continue
+		}
+
+		index, ip, _ := strings.Cut(key, "/")
+		i, err := strconv.Atoi(index)
+		if err != nil || i >= len(addresses) {
+			continue
+		}
+
+		addresses[i] = ip
+	}
+
+	return addresses
+}
+
+// Release frees the addresses of the machine with the given ID.
+func (a *ipAllocator) Release(id string) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for key, owner := range a.used {
+		if owner == id {
+			delete(a.used, key)
+		}
+	}
+}
+
+func formatIPRange(r *ipRange) string {
+	first, last := make(net.IP, 4), make(net.IP, 4)
+	binary.BigEndian.PutUint32(first, r.first)
+	binary.BigEndian.PutUint32(last, r.last)
+
+	return fmt.Sprintf("%s-%s", first, last)
+}
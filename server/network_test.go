@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIPRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{name: "CIDR", s: "10.10.0.0/24", want: "10.10.0.1-10.10.0.254"},
+		{name: "CIDRNotNetworkAddress", s: "10.10.0.64/26", want: "10.10.0.65-10.10.0.126"},
+		{name: "CIDRPointToPoint", s: "10.10.0.0/31", want: "10.10.0.0-10.10.0.1"},
+		{name: "Range", s: "10.10.0.10-10.10.0.20", want: "10.10.0.10-10.10.0.20"},
+		{name: "RangeSingleAddress", s: "10.10.0.10 - 10.10.0.10", want: "10.10.0.10-10.10.0.10"},
+		{name: "RangeReversed", s: "10.10.0.20-10.10.0.10", wantErr: true},
+		{name: "RangeInvalidAddress", s: "10.10.0.10-10.10.0", wantErr: true},
+		{name: "IPv6", s: "fd00::/64", wantErr: true},
+		{name: "Invalid", s: "10.10.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseIPRange(tt.s)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, formatIPRange(r))
+		})
+	}
+}
+
+func TestIPAllocator(t *testing.T) {
+	a := newIPAllocator(&NetworkConfig{Interfaces: []*NetworkInterfaceConfig{
+		{NetworkName: "fireactions"},
+		{NetworkName: "storage", IPRange: "10.10.0.1-10.10.0.3"},
+	}})
+
+	ip, err := a.Allocate("runner-1", 0)
+	assert.NoError(t, err)
+	assert.Nil(t, ip)
+
+	a.Reserve("runner-0", 1, net.ParseIP("10.10.0.1"))
+
+	ip, err = a.Allocate("runner-1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.2", ip.String())
+
+	ip, err = a.Allocate("runner-1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.2", ip.String(), "allocating again returns the same address")
+
+	ip, err = a.Allocate("runner-2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.3", ip.String())
+
+	_, err = a.Allocate("runner-3", 1)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"", "10.10.0.1"}, a.Addresses("runner-0"))
+	assert.Equal(t, []string{"", ""}, a.Addresses("runner-3"))
+
+	a.Reserve("runner-0", 2, net.ParseIP("10.10.0.4"))
+	assert.Equal(t, []string{"", "10.10.0.1"}, a.Addresses("runner-0"), "reserving an unknown interface is ignored")
+
+	a.Release("runner-1")
+
+	ip, err = a.Allocate("runner-3", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.2", ip.String())
+}
+
+func TestGetCNIArgs(t *testing.T) {
+	assert.Equal(t, [][2]string{{"IgnoreUnknown", "1"}, {"IP", "10.10.0.2"}}, getCNIArgs(net.ParseIP("10.10.0.2"), ""))
+	assert.Equal(t, [][2]string{{"IgnoreUnknown", "1"}, {"TC_REDIRECT_TAP_NAME", "tap1"}}, getCNIArgs(nil, getTapName(1)))
+}
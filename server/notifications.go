@@ -0,0 +1,303 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/rs/zerolog"
+)
+
+// defaultNotificationQueueSize is the number of notifications queued per
+// sink. Notifications are dropped when the queue of a sink is full.
+const defaultNotificationQueueSize = 100
+
+// notificationTemplateFuncs are the functions available in the body template
+// of webhook sinks.
+var notificationTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Notification is sent to the notification sinks when a pool needs
+// attention.
+type Notification struct {
+	Trigger string             `json:"trigger"`
+	Pool    string             `json:"pool"`
+	Message string             `json:"message"`
+	Time    time.Time          `json:"time"`
+	Event   *fireactions.Event `json:"event"`
+}
+
+// Notifier watches the events of the server and sends notifications to the
+// configured sinks when a pool needs attention.
+type Notifier struct {
+	config        *NotificationsConfig
+	sinks         []*notificationSink
+	client        *http.Client
+	logger        *zerolog.Logger
+	scaleFailures map[string]int
+	crashes       map[string][]time.Time
+}
+
+type notificationSink struct {
+	config   *NotificationSinkConfig
+	body     *template.Template
+	triggers map[string]struct{}
+	queue    chan *Notification
+}
+
+// NewNotifier creates a new Notifier.
+func NewNotifier(logger *zerolog.Logger, config *NotificationsConfig) (*Notifier, error) {
+	n := &Notifier{
+		config:        config,
+		client:        &http.Client{},
+		logger:        logger,
+		scaleFailures: make(map[string]int),
+		crashes:       make(map[string][]time.Time),
+	}
+
+	for _, config := range config.Sinks {
+		sink := &notificationSink{
+			config:   config,
+			triggers: make(map[string]struct{}),
+			queue:    make(chan *Notification, defaultNotificationQueueSize),
+		}
+
+		if config.Body != "" {
+			body, err := template.New(config.Name).Funcs(notificationTemplateFuncs).Parse(config.Body)
+			if err != nil {
+				return nil, fmt.Errorf("sink %s: body: %w", config.Name, err)
+			}
+
+			sink.body = body
+		}
+
+		for _, trigger := range config.GetTriggers() {
+			sink.triggers[trigger] = struct{}{}
+		}
+
+		n.sinks = append(n.sinks, sink)
+	}
+
+	return n, nil
+}
+
+// Run sends the notifications triggered by the events published on events
+// from now on, until ctx is done or the bus is closed.
+func (n *Notifier) Run(ctx context.Context, events *EventBus) {
+	for _, sink := range n.sinks {
+		go n.runSink(ctx, sink)
+	}
+
+	lastID := uint64(math.MaxUint64)
+	for {
+		subscription := events.Subscribe("", lastID)
+		for _, event := range subscription.History {
+			n.handle(event)
+			lastID = event.ID
+		}
+
+	loop:
+		for {
+			select {
+			case event, ok := <-subscription.C:
+				if !ok {
+					break loop
+				}
+
+				n.handle(event)
+				lastID = event.ID
+			case <-ctx.Done():
+				subscription.Close()
+				return
+			}
+		}
+
+		// The subscription is closed when the bus is closed, or when it falls
+		// behind, in which case the missed events are replayed.
+		subscription.Close()
+		if ctx.Err() != nil || events.isClosed() {
+			return
+		}
+	}
+}
+
+func (n *Notifier) handle(event *fireactions.Event) {
+	for _, notification := range n.evaluate(event) {
+		for _, sink := range n.sinks {
+			if _, ok := sink.triggers[notification.Trigger]; !ok {
+				continue
+			}
+
+			select {
+			case sink.queue <- notification:
+			default:
+				metricNotifications.WithLabelValues(sink.config.Name, "dropped").Inc()
+				n.logger.Warn().Msgf("Notification queue of sink %s is full, dropping %s notification", sink.config.Name, notification.Trigger)
+			}
+		}
+	}
+}
+
+// evaluate returns the notifications triggered by the event.
+func (n *Notifier) evaluate(event *fireactions.Event) []*Notification {
+	notification := &Notification{Pool: event.Pool, Time: event.Time, Event: event}
+
+	switch event.Type {
+	case fireactions.EventTypeVMStarted:
+		delete(n.scaleFailures, event.Pool)
+		return nil
+	case fireactions.EventTypeScaleFailed:
+		// Failing to scale down, e.g. when all runners are busy, is harmless.
+		if event.Message != eventMessageScaleUp {
+			return nil
+		}
+
+		n.scaleFailures[event.Pool]++
+		if n.scaleFailures[event.Pool] != n.config.GetScaleFailureThreshold() {
+			return nil
+		}
+
+		notification.Trigger = NotificationTriggerScaleFailures
+		notification.Message = fmt.Sprintf("Pool %s failed to scale %d times in a row: %s", event.Pool, n.scaleFailures[event.Pool], event.Error)
+	case fireactions.EventTypePoolPaused:
+		notification.Trigger = NotificationTriggerPoolPaused
+		notification.Message = fmt.Sprintf("Pool %s was paused", event.Pool)
+	case fireactions.EventTypeVMExited:
+		uptime := time.Duration(event.DurationSeconds * float64(time.Second))
+		crashed := event.Reason == fireactions.ExitReasonFailed ||
+			(event.Reason == fireactions.ExitReasonCompleted && uptime < n.config.GetCrashLoopMinUptime())
+		if !crashed {
+			return nil
+		}
+
+		crashes := []time.Time{}
+		for _, t := range n.crashes[event.Pool] {
+			if event.Time.Sub(t) < n.config.GetCrashLoopWindow() {
+				crashes = append(crashes, t)
+			}
+		}
+
+		n.crashes[event.Pool] = append(crashes, event.Time)
+		if len(n.crashes[event.Pool]) < n.config.GetCrashLoopThreshold() {
+			return nil
+		}
+
+		delete(n.crashes, event.Pool)
+		notification.Trigger = NotificationTriggerCrashLoop
+		notification.Message = fmt.Sprintf("%d runners of pool %s crashed within %s, last one %s after %s",
+			n.config.GetCrashLoopThreshold(), event.Pool, n.config.GetCrashLoopWindow(), event.Runner, uptime.Round(time.Second))
+		if event.Error != "" {
+			notification.Message += fmt.Sprintf(": %s", event.Error)
+		}
+	default:
+		return nil
+	}
+
+	return []*Notification{notification}
+}
+
+func (n *Notifier) runSink(ctx context.Context, sink *notificationSink) {
+	for {
+		select {
+		case notification := <-sink.queue:
+			if err := n.deliver(ctx, sink, notification); err != nil {
+				metricNotifications.WithLabelValues(sink.config.Name, "failure").Inc()
+				n.logger.Error().Err(err).Msgf("Failed to send %s notification to sink %s", notification.Trigger, sink.config.Name)
+				continue
+			}
+
+			metricNotifications.WithLabelValues(sink.config.Name, "success").Inc()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver sends the notification to the sink, retrying with an exponential
+// backoff on network errors, 429 and 5xx responses.
+func (n *Notifier) deliver(ctx context.Context, sink *notificationSink, notification *Notification) error {
+	body, err := sink.render(notification)
+	if err != nil {
+		return err
+	}
+
+	backoff := sink.config.GetBackoff()
+	for attempt := 1; ; attempt++ {
+		retry, err := n.send(ctx, sink, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= sink.config.GetMaxAttempts() {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+
+		n.logger.Debug().Err(err).Msgf("Failed to send notification to sink %s, retrying in %s", sink.config.Name, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff = min(backoff*2, defaultNotificationMaxBackoff)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, sink *notificationSink, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, sink.config.GetTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("fireactions/%s", fireactions.Version))
+	for key, value := range sink.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	rsp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer rsp.Body.Close()
+
+	switch {
+	case rsp.StatusCode >= 200 && rsp.StatusCode < 300:
+		return false, nil
+	case rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", rsp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", rsp.Status)
+	}
+}
+
+// render returns the body of the request sending the notification: a Slack
+// message, the rendered body template, or the notification as JSON.
+func (s *notificationSink) render(notification *Notification) ([]byte, error) {
+	switch {
+	case s.config.Type == NotificationSinkSlack:
+		return json.Marshal(map[string]string{"text": fmt.Sprintf(":warning: *Fireactions* (%s): %s", notification.Trigger, notification.Message)})
+	case s.body != nil:
+		var body bytes.Buffer
+		if err := s.body.Execute(&body, notification); err != nil {
+			return nil, fmt.Errorf("rendering body: %w", err)
+		}
+
+		return body.Bytes(), nil
+	default:
+		return json.Marshal(notification)
+	}
+}
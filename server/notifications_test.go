@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNotifier(t *testing.T, config *NotificationsConfig) *Notifier {
+	logger := zerolog.Nop()
+	notifier, err := NewNotifier(&logger, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return notifier
+}
+
+func TestNotifier_Evaluate_ScaleFailures(t *testing.T) {
+	notifier := newTestNotifier(t, &NotificationsConfig{ScaleFailureThreshold: 2})
+
+	failed := &fireactions.Event{Type: fireactions.EventTypeScaleFailed, Pool: "pool1", Message: eventMessageScaleUp, Error: "github: 401 Bad credentials"}
+	assert.Empty(t, notifier.evaluate(failed))
+	assert.Empty(t, notifier.evaluate(&fireactions.Event{Type: fireactions.EventTypeScaleFailed, Pool: "pool1", Message: eventMessageScaleDown}))
+
+	notifications := notifier.evaluate(failed)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, NotificationTriggerScaleFailures, notifications[0].Trigger)
+	assert.Equal(t, "pool1", notifications[0].Pool)
+	assert.Equal(t, "Pool pool1 failed to scale 2 times in a row: github: 401 Bad credentials", notifications[0].Message)
+
+	// Notified once until the pool scales again.
+	assert.Empty(t, notifier.evaluate(failed))
+	assert.Empty(t, notifier.evaluate(&fireactions.Event{Type: fireactions.EventTypeVMStarted, Pool: "pool1"}))
+	assert.Empty(t, notifier.evaluate(failed))
+	assert.Len(t, notifier.evaluate(failed), 1)
+}
+
+func TestNotifier_Evaluate_PoolPaused(t *testing.T) {
+	notifier := newTestNotifier(t, &NotificationsConfig{})
+
+	notifications := notifier.evaluate(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool1"})
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, NotificationTriggerPoolPaused, notifications[0].Trigger)
+}
+
+func TestNotifier_Evaluate_CrashLoop(t *testing.T) {
+	notifier := newTestNotifier(t, &NotificationsConfig{CrashLoopThreshold: 2, CrashLoopWindow: time.Minute, CrashLoopMinUptime: 10 * time.Second})
+
+	now := time.Now()
+	exited := func(reason string, uptime time.Duration, at time.Time) *fireactions.Event {
+		return &fireactions.Event{Type: fireactions.EventTypeVMExited, Pool: "pool1", Runner: "runner-1", Reason: reason, DurationSeconds: uptime.Seconds(), Time: at}
+	}
+
+	// Runners that ran long enough, or were deleted, didn't crash.
+	assert.Empty(t, notifier.evaluate(exited(fireactions.ExitReasonCompleted, time.Minute, now)))
+	assert.Empty(t, notifier.evaluate(exited(fireactions.ExitReasonDeleted, time.Second, now)))
+
+	// Crashes out of the window don't count.
+	assert.Empty(t, notifier.evaluate(exited(fireactions.ExitReasonFailed, time.Minute, now)))
+	assert.Empty(t, notifier.evaluate(exited(fireactions.ExitReasonCompleted, time.Second, now.Add(2*time.Minute))))
+
+	notifications := notifier.evaluate(exited(fireactions.ExitReasonCompleted, time.Second, now.Add(150*time.Second)))
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, NotificationTriggerCrashLoop, notifications[0].Trigger)
+	assert.Equal(t, "2 runners of pool pool1 crashed within 1m0s, last one runner-1 after 1s", notifications[0].Message)
+}
+
+func TestNotifier_Deliver(t *testing.T) {
+	var attempts atomic.Int32
+	var body, header atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body.Store(string(b))
+		header.Store(r.Header.Get("Authorization"))
+
+		switch attempts.Add(1) {
+		case 1, 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	notification := &Notification{Trigger: NotificationTriggerPoolPaused, Pool: "pool1", Message: `Pool "pool1" was paused`}
+
+	t.Run("Retry", func(t *testing.T) {
+		notifier := newTestNotifier(t, &NotificationsConfig{Sinks: []*NotificationSinkConfig{{
+			Name: "webhook", Type: NotificationSinkWebhook, URL: server.URL, Backoff: time.Millisecond,
+			Headers: map[string]string{"Authorization": "Bearer token"},
+			Body:    `{"summary": {{ json .Message }}, "pool": "{{ .Pool }}"}`,
+		}}})
+
+		assert.NoError(t, notifier.deliver(context.Background(), notifier.sinks[0], notification))
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.Equal(t, `{"summary": "Pool \"pool1\" was paused", "pool": "pool1"}`, body.Load())
+		assert.Equal(t, "Bearer token", header.Load())
+	})
+
+	t.Run("MaxAttempts", func(t *testing.T) {
+		attempts.Store(0)
+		notifier := newTestNotifier(t, &NotificationsConfig{Sinks: []*NotificationSinkConfig{{
+			Name: "webhook", Type: NotificationSinkWebhook, URL: server.URL, Backoff: time.Millisecond, MaxAttempts: 2,
+		}}})
+
+		assert.Error(t, notifier.deliver(context.Background(), notifier.sinks[0], notification))
+		assert.Equal(t, int32(2), attempts.Load())
+
+		var decoded Notification
+		assert.NoError(t, json.Unmarshal([]byte(body.Load().(string)), &decoded))
+		assert.Equal(t, notification.Message, decoded.Message)
+	})
+
+	t.Run("Slack", func(t *testing.T) {
+		attempts.Store(2)
+		notifier := newTestNotifier(t, &NotificationsConfig{Sinks: []*NotificationSinkConfig{{Name: "slack", Type: NotificationSinkSlack, URL: server.URL}}})
+
+		assert.NoError(t, notifier.deliver(context.Background(), notifier.sinks[0], notification))
+		assert.Equal(t, `{"text":":warning: *Fireactions* (pool_paused): Pool \"pool1\" was paused"}`, body.Load())
+	})
+}
+
+func TestNotifier_Deliver_ClientError(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, &NotificationsConfig{Sinks: []*NotificationSinkConfig{{Name: "webhook", Type: NotificationSinkWebhook, URL: server.URL, Backoff: time.Millisecond}}})
+
+	assert.Error(t, notifier.deliver(context.Background(), notifier.sinks[0], &Notification{}))
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestNotifier_Run(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received <- string(b)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, &NotificationsConfig{Sinks: []*NotificationSinkConfig{
+		{Name: "paused", Type: NotificationSinkWebhook, URL: server.URL, Body: "{{ .Trigger }} {{ .Pool }}", Triggers: []string{NotificationTriggerPoolPaused}},
+		{Name: "crashes", Type: NotificationSinkWebhook, URL: server.URL, Triggers: []string{NotificationTriggerCrashLoop}},
+	}})
+
+	bus := NewEventBus()
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "old"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		notifier.Run(ctx, bus)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		bus.l.Lock()
+		defer bus.l.Unlock()
+
+		return len(bus.subscribers) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	bus.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: "pool1"})
+
+	select {
+	case body := <-received:
+		assert.Equal(t, "pool_paused pool1", body)
+	case <-time.After(time.Second):
+		t.Fatal("notification not sent")
+	}
+
+	bus.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifier didn't stop")
+	}
+}
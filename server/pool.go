@@ -2,111 +2,115 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/containerd/containerd"
-	"github.com/containerd/containerd/leases"
-	"github.com/containerd/containerd/mount"
-	"github.com/containerd/errdefs"
-	"github.com/containerd/log"
-	"github.com/containerd/nerdctl/pkg/imgutil/dockerconfigresolver"
-	"github.com/distribution/reference"
-	"github.com/firecracker-microvm/firecracker-go-sdk"
-	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+	"github.com/hostinger/fireactions"
 	"github.com/hostinger/fireactions/helper/deepcopy"
 	"github.com/hostinger/fireactions/helper/github"
 	"github.com/hostinger/fireactions/helper/stringid"
-	"github.com/opencontainers/image-spec/identity"
 	"github.com/rs/zerolog"
-	"github.com/sirupsen/logrus"
 
 	githubv63 "github.com/google/go-github/v63/github"
 )
 
 const (
-	defaultSnapshotter = "devmapper"
+	defaultDrainTimeout          = 30 * time.Minute
+	defaultRunnerShutdownTimeout = 30 * time.Second
 )
 
 // Pool represents a pool of Firecracker VMs that are used to run GitHub Actions jobs.
 type Pool struct {
-	config       *PoolConfig
-	containerd   *containerd.Client
-	containerdMu *sync.Mutex
-	github       *github.Client
-	machinesMu   *sync.Mutex
-	machines     map[string]*firecracker.Machine
-	logger       *zerolog.Logger
-	l            *sync.Mutex
-	isActive     bool
-	t            *time.Ticker
-	stopCh       chan struct{}
+	config     atomic.Pointer[PoolConfig]
+	provider   MachineProvider
+	github     *github.Client
+	store      *Store
+	capacity   *Capacity
+	events     *EventBus
+	runnersMu  *sync.Mutex
+	runners    map[string]*Runner
+	logger     *zerolog.Logger
+	l          *sync.Mutex
+	isActive   atomic.Bool
+	isDraining atomic.Bool
+	generation int
+	t          *time.Ticker
+	stopCh     chan struct{}
+	stopOnce   *sync.Once
 }
 
 // PoolConfig represents the configuration of a Pool.
 type PoolConfig struct {
 	Name        string             `yaml:"name" validate:"required"`
 	MaxRunners  int                `yaml:"max_runners" validate:"min=1"`
-	MinRunners  int                `yaml:"min_runners" validate:"min=1"`
+	MinRunners  int                `yaml:"min_runners" validate:"min=0"`
 	Runner      *RunnerConfig      `yaml:"runner" validate:"required"`
 	Firecracker *FirecrackerConfig `yaml:"firecracker" validate:"required"`
+	Schedules   []*ScheduleConfig  `yaml:"schedules" validate:""`
+	Network     *NetworkConfig     `yaml:"network"`
+
+	// Priority orders the pools competing for the host capacity: the
+	// capacity the pools of higher priority need to reach their minimum
+	// number of runners is not admitted to the others.
+	Priority int `yaml:"priority"`
 }
 
-// NewPool creates a new Pool.
-func NewPool(logger *zerolog.Logger, config *PoolConfig, github *github.Client) (*Pool, error) {
+// NewPool creates a new Pool, running its runners on the machines of provider.
+// The runners of the pool are recorded in store and, if capacity is not nil,
+// admitted against the capacity of the host. Their lifecycle events are
+// published on events, if not nil.
+func NewPool(logger *zerolog.Logger, config *PoolConfig, github *github.Client, store *Store, provider MachineProvider, capacity *Capacity, events *EventBus) (*Pool, error) {
 	l := logger.With().Str("pool", config.Name).Logger()
-	containerd, err := containerd.New("/run/containerd/containerd.sock",
-		containerd.WithDefaultNamespace(config.Name),
-		containerd.WithTimeout(5*time.Second))
-	if err != nil {
-		return nil, fmt.Errorf("containerd: creating client: %w", err)
-	}
-
 	p := &Pool{
-		config:       config,
-		machinesMu:   &sync.Mutex{},
-		machines:     make(map[string]*firecracker.Machine),
-		isActive:     true,
-		containerd:   containerd,
-		containerdMu: &sync.Mutex{},
-		github:       github,
-		logger:       &l,
-		l:            &sync.Mutex{},
-		t:            time.NewTicker(1 * time.Second),
-		stopCh:       make(chan struct{}),
-	}
-
-	_, err = os.Stat(p.GetDir())
-	if os.IsNotExist(err) {
-		if err := os.MkdirAll(p.GetDir(), 0755); err != nil {
-			return nil, fmt.Errorf("creating pool directory: %w", err)
-		}
-
-		p.logger.Debug().Msgf("Pool directory created at %s", p.GetDir())
+		provider:  provider,
+		runnersMu: &sync.Mutex{},
+		runners:   make(map[string]*Runner),
+		github:    github,
+		store:     store,
+		capacity:  capacity,
+		events:    events,
+		logger:    &l,
+		l:         &sync.Mutex{},
+		t:         time.NewTicker(1 * time.Second),
+		stopCh:    make(chan struct{}),
+		stopOnce:  &sync.Once{},
 	}
+	p.config.Store(config)
+	p.isActive.Store(true)
 
 	metricPoolCurrentRunnersCount.
-		WithLabelValues(p.config.Name).Set(float64(p.GetCurrentSize()))
+		WithLabelValues(config.Name).Set(float64(p.GetCurrentSize()))
 	metricPoolMaxRunnersCount.
-		WithLabelValues(p.config.Name).Set(float64(p.config.MaxRunners))
+		WithLabelValues(config.Name).Set(float64(config.MaxRunners))
 	metricPoolMinRunnersCount.
-		WithLabelValues(p.config.Name).Set(float64(p.config.MinRunners))
+		WithLabelValues(config.Name).Set(float64(config.MinRunners))
 	metricPoolStatus.
-		WithLabelValues(p.config.Name).Set(1)
+		WithLabelValues(config.Name).Set(1)
 
 	metricPoolTotal.Inc()
 
+	if capacity != nil {
+		capacity.AddPool(p)
+	}
+
 	return p, nil
 }
 
 // Start starts the pool. Starting the pool will start the scaling process.
 func (p *Pool) Start() {
 	defer p.t.Stop()
+
+	refresherStopCh := make(chan struct{})
+	defer close(refresherStopCh)
+	go p.startImageRefresher(refresherStopCh)
+
 	for {
 		select {
 		case <-p.stopCh:
@@ -114,321 +118,807 @@ func (p *Pool) Start() {
 		case <-p.t.C:
 		}
 
-		metricPoolCurrentRunnersCount.WithLabelValues(p.config.Name).Set(float64(p.GetCurrentSize()))
+		metricPoolCurrentRunnersCount.WithLabelValues(p.getConfig().Name).Set(float64(p.GetCurrentSize()))
 
-		if !p.isActive {
-			p.logger.Debug().Msgf("Pool %s is paused, skipping scaling", p.config.Name)
+		if !p.isActive.Load() {
+			p.logger.Debug().Msgf("Pool %s is paused, skipping scaling", p.getConfig().Name)
 			continue
 		}
 
-		if err := p.Scale(context.Background(), p.config.MinRunners-p.GetCurrentSize()); err != nil {
-			p.logger.Error().Err(err).Msg("Failed to scale pool")
+		if p.isDraining.Load() {
+			p.logger.Debug().Msgf("Pool %s is draining, skipping scaling", p.getConfig().Name)
+			continue
+		}
+
+		p.replaceOutdatedRunners(context.Background())
+
+		profile := p.GetProfile()
+		metricPoolMaxRunnersCount.WithLabelValues(p.getConfig().Name).Set(float64(profile.MaxRunners))
+		metricPoolMinRunnersCount.WithLabelValues(p.getConfig().Name).Set(float64(profile.MinRunners))
+
+		curSize := p.GetCurrentSize()
+		switch {
+		case curSize < profile.MinRunners:
+			if err := p.Scale(context.Background(), profile.MinRunners-curSize).Err(); err != nil {
+				p.logger.Error().Err(err).Msg("Failed to scale pool")
+			}
+		case curSize > profile.MaxRunners:
+			// Busy runners can't be removed, they are retried on the next tick.
+			if err := p.Scale(context.Background(), profile.MaxRunners-curSize).Err(); err != nil {
+				p.logger.Debug().Err(err).Msgf("Failed to scale pool down to profile %s", profile.Name)
+			}
 		}
 	}
 }
 
 // Stop stops the pool. Stopping the pool will stop all the VMs in the pool.
+// The pool doesn't need to be started.
 func (p *Pool) Stop() {
-	p.stopCh <- struct{}{}
-	p.logger.Debug().Msgf("Stopping pool %s", p.config.Name)
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.logger.Debug().Msgf("Stopping pool %s", p.getConfig().Name)
 	p.t.Stop()
 	p.l.Lock()
 	defer p.l.Unlock()
 
-	for _, machine := range p.machines {
-		err := machine.StopVMM()
+	for _, runner := range p.ListRunners() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := p.DeleteRunner(ctx, runner.Name, true)
 		if err != nil {
-			p.logger.Error().Err(err).Msgf("Failed to stop Firecracker VM %s", machine.Cfg.VMID)
+			p.logger.Error().Err(err).Msgf("Failed to stop machine %s", runner.VMID)
 		}
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	if err := p.provider.Close(); err != nil {
+		p.logger.Error().Err(err).Msg("Failed to close machine provider")
+	}
+
+	p.logger.Debug().Msgf("Pool %s stopped", p.getConfig().Name)
+}
+
+// Drain stops the pool from scaling and gracefully removes all of its runners.
+// Idle runners are unregistered from GitHub and shut down right away, while
+// busy runners are given until the timeout to finish their jobs before being
+// forcefully stopped. Drain returns once all the runners have exited.
+func (p *Pool) Drain(ctx context.Context, timeout time.Duration) error {
+	p.isDraining.Store(true)
+	metricPoolStatus.WithLabelValues(p.getConfig().Name).Set(0)
+	p.logger.Info().Msgf("Draining pool %s (timeout: %s)", p.getConfig().Name, timeout)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	t := time.NewTicker(1 * time.Second)
+	defer t.Stop()
+
+	for {
+		runners := p.ListRunners()
+		if len(runners) == 0 {
+			p.logger.Info().Msgf("Pool %s drained", p.getConfig().Name)
+			return nil
+		}
+
+		for _, runner := range runners {
+			if runner.GetState() != fireactions.RunnerStateIdle {
+				continue
+			}
 
-		_ = machine.Wait(ctx)
+			if err := p.removeGitHubRunner(ctx, runner.githubID); err != nil {
+				p.logger.Debug().Err(err).Msgf("Failed to remove runner %s from GitHub, it may be running a job", runner.Name)
+				continue
+			}
 
-		p.machinesMu.Lock()
-		delete(p.machines, machine.Cfg.VMID)
-		p.machinesMu.Unlock()
+			if err := p.DeleteRunner(ctx, runner.Name, false); err != nil {
+				p.logger.Error().Err(err).Msgf("Failed to shut down machine %s", runner.VMID)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			p.logger.Warn().Msgf("Pool %s drain timed out after %s, stopping %d runner(s) forcefully", p.getConfig().Name, timeout, len(p.ListRunners()))
+			for _, runner := range p.ListRunners() {
+				if err := p.DeleteRunner(ctx, runner.Name, true); err != nil {
+					p.logger.Error().Err(err).Msgf("Failed to stop machine %s", runner.VMID)
+				}
+			}
+
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Reconcile brings the pool in line with the state store after a restart.
+// Runner machines that are still running are re-adopted, while the resources
+// and the logs of the ones that are gone are cleaned up, along with the
+// resources of machines left behind without a record.
+func (p *Pool) Reconcile(ctx context.Context) error {
+	if p.store == nil {
+		return nil
+	}
+
+	records, err := p.store.ListRunners(p.getConfig().Name)
+	if err != nil {
+		return fmt.Errorf("listing runners: %w", err)
+	}
+
+	for _, record := range records {
+		machine, err := p.provider.Adopt(ctx, record)
+		switch {
+		case err != nil:
+			p.logger.Debug().Err(err).Msgf("Failed to re-adopt machine %s", record.Name)
+		case record.State == fireactions.RunnerStateStarting:
+			// The server stopped while the machine was starting, before its
+			// runner was known to be registered, so it's not adopted.
+			p.stopStartingMachine(ctx, machine)
+		default:
+			p.adoptRunner(record, machine)
+			p.logger.Info().Msgf("Re-adopted machine %s (PID %d)", record.Name, record.PID)
+			continue
+		}
+
+		if record.GitHubID != 0 {
+			if err := p.removeGitHubRunner(ctx, record.GitHubID); err != nil {
+				p.logger.Debug().Err(err).Msgf("Failed to remove runner %s from GitHub", record.Name)
+			}
+		}
+
+		if err := p.provider.Remove(ctx, record.VMID); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to remove machine %s", record.VMID)
+		}
+
+		if err := os.Remove(record.LogPath); err != nil && !os.IsNotExist(err) {
+			p.logger.Error().Err(err).Msgf("Failed to remove %s", record.LogPath)
+		}
+
+		p.forgetRunner(record.Name)
+		p.logger.Info().Msgf("Cleaned up dead machine %s", record.Name)
+	}
+
+	machines, err := p.listStaleMachines(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, machine := range machines {
+		if err := p.provider.Remove(ctx, machine.ID); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to remove orphaned machine %s", machine.ID)
+			continue
+		}
 
-		p.logger.Debug().Msgf("Forcefully stopped Firecracker VM %s", machine.Cfg.VMID)
+		p.logger.Info().Msgf("Cleaned up orphaned machine %s", machine.ID)
 	}
 
-	p.logger.Debug().Msgf("Pool %s stopped", p.config.Name)
+	return nil
+}
+
+// stopStartingMachine kills a machine left starting by a previous run of the
+// server and waits for it to exit, so that its resources can be released.
+func (p *Pool) stopStartingMachine(ctx context.Context, machine Machine) {
+	if err := machine.Stop(); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to stop machine %s", machine.ID())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultRunnerShutdownTimeout)
+	defer cancel()
+
+	if err := machine.Wait(ctx); err != nil && ctx.Err() != nil {
+		p.logger.Error().Err(err).Msgf("Machine %s didn't exit in %s", machine.ID(), defaultRunnerShutdownTimeout)
+		return
+	}
+
+	p.logger.Info().Msgf("Stopped machine %s left starting", machine.ID())
+}
+
+// adoptRunner registers the runner of a machine that was started before the
+// server restarted and is still running.
+func (p *Pool) adoptRunner(record *RunnerRecord, machine Machine) {
+	runner := newRunner(record.Name, p.getConfig().Name, record.SocketPath, record.LogPath)
+	runner.VMID = record.VMID
+	runner.IPAddress = record.IPAddress
+	runner.ipAddresses = record.IPAddresses
+	runner.StartedAt = record.StartedAt
+	runner.state = record.State
+	runner.machine = machine
+	runner.githubID = record.GitHubID
+	runner.onStateChange = p.saveRunner
+
+	if p.capacity != nil {
+		p.capacity.Allocate(p, runner.VMID)
+	}
+
+	p.runnersMu.Lock()
+	p.runners[runner.Name] = runner
+	p.runnersMu.Unlock()
+
+	go p.waitRunner(runner)
 }
 
 // GetDir returns the directory where the pool sockets and logs are stored.
 func (p *Pool) GetDir() string {
-	return fmt.Sprintf("/var/lib/fireactions/pools/%s", p.config.Name)
+	return getPoolDir(p.getConfig().Name)
 }
 
-// Scale scales the pool to the desired size.
-func (p *Pool) Scale(ctx context.Context, replicas int) error {
+func getPoolDir(name string) string {
+	return fmt.Sprintf("/var/lib/fireactions/pools/%s", name)
+}
+
+// ScaleResult represents the outcome of scaling a pool.
+type ScaleResult struct {
+	// Replicas is the size of the pool after scaling.
+	Replicas int
+
+	// Errors contains an error for each VM that failed to be created or
+	// removed.
+	Errors []error
+}
+
+// Err returns all the errors of the scale operation joined, or nil.
+func (r *ScaleResult) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// Scale scales the pool by delta runners. See ScaleTo.
+func (p *Pool) Scale(ctx context.Context, delta int) *ScaleResult {
 	p.l.Lock()
 	defer p.l.Unlock()
 
-	if replicas < 0 {
-		return nil
-	}
+	return p.scaleTo(ctx, p.GetCurrentSize()+delta)
+}
+
+// ScaleTo scales the pool to the desired number of replicas, clamped between
+// the minimum and maximum number of runners of the pool. Scaling down removes
+// idle runners only, see scaleDown.
+func (p *Pool) ScaleTo(ctx context.Context, replicas int) *ScaleResult {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return p.scaleTo(ctx, replicas)
+}
 
+func (p *Pool) scaleTo(ctx context.Context, replicas int) *ScaleResult {
 	curSize := p.GetCurrentSize()
-	desSize := curSize + replicas
+	if p.isDraining.Load() {
+		return &ScaleResult{Replicas: curSize, Errors: []error{fmt.Errorf("pool %s is draining", p.getConfig().Name)}}
+	}
 
-	if desSize > p.config.MaxRunners || desSize < p.config.MinRunners || desSize == curSize {
-		return nil
+	profile := p.GetProfile()
+	desSize := max(min(replicas, profile.MaxRunners), profile.MinRunners)
+
+	result := &ScaleResult{}
+	switch {
+	case desSize > curSize:
+		for i := curSize; i < desSize; i++ {
+			if err := p.scaleUp(ctx); err != nil {
+				metricPoolScaleFailures.WithLabelValues(p.getConfig().Name).Inc()
+				p.events.Publish(&fireactions.Event{Type: fireactions.EventTypeScaleFailed, Pool: p.getConfig().Name, Message: eventMessageScaleUp, Error: err.Error()})
+				result.Errors = append(result.Errors, err)
+				if errors.Is(err, fireactions.ErrInsufficientCapacity) {
+					break
+				}
+
+				continue
+			}
+
+			metricPoolScaleSuccesses.WithLabelValues(p.getConfig().Name).Inc()
+			p.logger.Trace().Msgf("Pool scaled to %d", p.GetCurrentSize())
+		}
+	case desSize < curSize:
+		// Runners shutting down are already on their way out.
+		count := curSize - p.countRunners(fireactions.RunnerStateExiting) - desSize
+		if count <= 0 {
+			result.Replicas = curSize
+			return result
+		}
+
+		result.Errors = p.scaleDown(ctx, count)
+		for _, err := range result.Errors {
+			p.events.Publish(&fireactions.Event{Type: fireactions.EventTypeScaleFailed, Pool: p.getConfig().Name, Message: eventMessageScaleDown, Error: err.Error()})
+		}
+	default:
+		result.Replicas = curSize
+		return result
 	}
 
-	for i := curSize; i < desSize; i++ {
-		if err := p.scaleUp(ctx); err != nil {
-			metricPoolScaleFailures.WithLabelValues(p.config.Name).Inc()
-			return err
+	result.Replicas = p.GetCurrentSize()
+	p.logger.Debug().Msgf("Pool scaled %d -> %d (desired: %d, profile: %s, max: %d, min: %d)", curSize, result.Replicas, desSize, profile.Name, profile.MaxRunners, profile.MinRunners)
+	return result
+}
+
+// scaleDown removes up to count idle runners, most recently started first.
+// The runners are unregistered from GitHub before being shut down, which
+// GitHub refuses for runners that picked up a job the server isn't aware of,
+// e.g. without webhooks.
+func (p *Pool) scaleDown(ctx context.Context, count int) []error {
+	var errs []error
+
+	runners := p.ListRunners()
+	removed := 0
+	for i := len(runners) - 1; i >= 0 && removed < count; i-- {
+		if runners[i].GetState() != fireactions.RunnerStateIdle {
+			continue
+		}
+
+		if err := p.removeGitHubRunner(ctx, runners[i].githubID); err != nil {
+			p.logger.Debug().Err(err).Msgf("Failed to remove runner %s from GitHub, it may be running a job", runners[i].Name)
+			continue
+		}
+
+		if err := p.DeleteRunner(ctx, runners[i].Name, false); err != nil {
+			errs = append(errs, fmt.Errorf("removing runner %s: %w", runners[i].Name, err))
+			continue
 		}
 
-		metricPoolScaleSuccesses.WithLabelValues(p.config.Name).Inc()
-		p.logger.Trace().Msgf("Pool scaled to %d", i+1)
+		removed++
 	}
 
-	p.logger.Debug().Msgf("Pool scaled %d -> %d (max: %d, min: %d)", curSize, desSize, p.config.MaxRunners, p.config.MinRunners)
-	return nil
+	if removed < count {
+		errs = append(errs, fmt.Errorf("not enough idle runners: removed %d of %d", removed, count))
+	}
+
+	return errs
+}
+
+// Update applies a new configuration to the pool. If roll is true, the
+// runners created with the previous configuration are replaced: the idle ones
+// right away, the busy ones once their job completes.
+func (p *Pool) Update(config *PoolConfig, roll bool) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.config.Store(config)
+	if roll {
+		p.generation++
+	}
+
+	metricPoolMaxRunnersCount.WithLabelValues(config.Name).Set(float64(config.MaxRunners))
+	metricPoolMinRunnersCount.WithLabelValues(config.Name).Set(float64(config.MinRunners))
+}
+
+// replaceOutdatedRunners shuts down the idle runners created with a previous
+// configuration of the pool, which is then scaled back up with the current
+// one. Busy runners exit after their job, like all ephemeral runners.
+func (p *Pool) replaceOutdatedRunners(ctx context.Context) {
+	p.l.Lock()
+	generation := p.generation
+	p.l.Unlock()
+
+	for _, runner := range p.ListRunners() {
+		if runner.generation == generation || runner.GetState() != fireactions.RunnerStateIdle {
+			continue
+		}
+
+		if err := p.removeGitHubRunner(ctx, runner.githubID); err != nil {
+			p.logger.Debug().Err(err).Msgf("Failed to remove runner %s from GitHub, it may be running a job", runner.Name)
+			continue
+		}
+
+		if err := p.DeleteRunner(ctx, runner.Name, false); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to shut down machine %s", runner.VMID)
+			continue
+		}
+
+		p.logger.Info().Msgf("Replacing runner %s created with a previous configuration", runner.Name)
+	}
 }
 
 // Pause pauses the pool. Pausing the pool will prevent the pool from scaling.
 func (p *Pool) Pause() {
-	if !p.isActive {
+	if !p.isActive.CompareAndSwap(true, false) {
 		return
 	}
 
-	p.logger.Debug().Msgf("Pool %s state changed to paused", p.config.Name)
-	p.isActive = false
+	p.logger.Debug().Msgf("Pool %s state changed to paused", p.getConfig().Name)
+	p.events.Publish(&fireactions.Event{Type: fireactions.EventTypePoolPaused, Pool: p.getConfig().Name})
 }
 
-// Resume resumes the pool. Resuming the pool will allow the pool to scale.
+// Resume resumes the pool. Resuming the pool will allow the pool to scale,
+// including a pool that was drained.
 func (p *Pool) Resume() {
-	if p.isActive {
+	p.isDraining.Store(false)
+	if !p.isActive.CompareAndSwap(false, true) {
 		return
 	}
 
-	p.logger.Debug().Msgf("Pool %s state changed to active", p.config.Name)
-	p.isActive = true
+	p.logger.Debug().Msgf("Pool %s state changed to active", p.getConfig().Name)
+}
+
+// MatchesLabels returns true if every label requested by a job (e.g. the
+// `runs-on` labels of a workflow job) is provided by the pool runners.
+// Labels are compared case-insensitively, as GitHub does.
+func (p *Pool) MatchesLabels(labels []string) bool {
+	if len(labels) == 0 {
+		return false
+	}
+
+	for _, label := range labels {
+		found := false
+		for _, runnerLabel := range p.getConfig().Runner.Labels {
+			if strings.EqualFold(label, runnerLabel) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getConfig returns the current configuration of the pool, which Update
+// replaces while the pool is running.
+func (p *Pool) getConfig() *PoolConfig {
+	return p.config.Load()
+}
+
+// GetProfile returns the minimum and maximum number of runners currently in
+// effect, according to the pool schedules.
+func (p *Pool) GetProfile() Profile {
+	return p.getConfig().GetProfile(time.Now())
 }
 
 // GetCurrentSize returns the current size of the pool.
 func (p *Pool) GetCurrentSize() int {
-	return len(p.machines)
+	p.runnersMu.Lock()
+	defer p.runnersMu.Unlock()
+
+	return len(p.runners)
 }
 
-func (p *Pool) scaleUp(ctx context.Context) error {
-	imageExists := true
-	image, err := p.containerd.GetImage(ctx, p.config.Runner.Image)
-	if err != nil {
-		if !errdefs.IsNotFound(err) {
-			return fmt.Errorf("containerd: getting image: %w", err)
-		}
+// ListRunners returns all the runners of the pool, oldest first.
+func (p *Pool) ListRunners() []*Runner {
+	p.runnersMu.Lock()
+	defer p.runnersMu.Unlock()
 
-		imageExists = false
+	runners := make([]*Runner, 0, len(p.runners))
+	for _, runner := range p.runners {
+		runners = append(runners, runner)
 	}
 
-	if !imageExists {
-		p.logger.Debug().Msg("Pulling image")
+	sort.Slice(runners, func(i, j int) bool { return runners[i].StartedAt.Before(runners[j].StartedAt) })
+	return runners
+}
 
-		start := time.Now()
-		image, err = p.pullImage(ctx, p.config.Runner.Image)
-		if err != nil {
-			return fmt.Errorf("containerd: pulling image: %w", err)
+// countRunners returns the number of runners of the pool in the given state.
+func (p *Pool) countRunners(state fireactions.RunnerState) int {
+	count := 0
+	for _, runner := range p.ListRunners() {
+		if runner.GetState() == state {
+			count++
 		}
-
-		p.logger.Debug().Msgf("Image pulled in %s", time.Since(start))
 	}
 
-	runnerName := fmt.Sprintf("%s-%s", p.config.Runner.Name, stringid.New())
+	return count
+}
 
-	leaseCtx, leaseCtxCancel, err := p.containerd.WithLease(ctx,
-		leases.WithID(fmt.Sprintf("fireactions/pools/%s/%s", p.config.Name, runnerName)))
-	if err != nil {
-		return fmt.Errorf("containerd: creating lease: %w", err)
-	}
+// GetRunner returns the runner with the given name.
+func (p *Pool) GetRunner(name string) (*Runner, error) {
+	p.runnersMu.Lock()
+	defer p.runnersMu.Unlock()
 
-	snapshotMounts, err := p.createSnapshot(leaseCtx, image, runnerName)
-	if err != nil {
-		return fmt.Errorf("containerd: creating snapshot: %w", err)
+	runner, ok := p.runners[name]
+	if !ok {
+		return nil, fireactions.ErrRunnerNotFound
 	}
 
-	machineLogFile, err := os.Create(filepath.Join(p.GetDir(), fmt.Sprintf("%s.log", runnerName)))
-	if err != nil {
-		return fmt.Errorf("creating log file: %w", err)
-	}
-
-	machineCmd := firecracker.VMCommandBuilder{}.
-		WithSocketPath(filepath.Join(p.GetDir(), fmt.Sprintf("%s.sock", runnerName))).
-		WithStderr(machineLogFile).
-		WithStdout(machineLogFile).
-		WithBin(p.config.Firecracker.BinaryPath).
-		Build(context.Background())
-
-	logger := logrus.New()
-	logger.SetLevel(logrus.DebugLevel)
-	logger.SetOutput(io.Discard)
-
-	machine, err := firecracker.NewMachine(ctx, firecracker.Config{
-		VMID:            runnerName,
-		SocketPath:      filepath.Join(p.GetDir(), fmt.Sprintf("%s.sock", runnerName)),
-		KernelImagePath: p.config.Firecracker.KernelImagePath,
-		KernelArgs:      p.config.Firecracker.KernelArgs,
-		MachineCfg: models.MachineConfiguration{
-			VcpuCount:  &p.config.Firecracker.MachineConfig.VcpuCount,
-			MemSizeMib: &p.config.Firecracker.MachineConfig.MemSizeMib,
-		},
-		Drives: []models.Drive{{
-			DriveID:      firecracker.String("rootfs"),
-			PathOnHost:   &snapshotMounts[0].Source,
-			IsRootDevice: firecracker.Bool(true),
-			IsReadOnly:   firecracker.Bool(false),
-		}},
-		NetworkInterfaces: []firecracker.NetworkInterface{{
-			AllowMMDS:        true,
-			CNIConfiguration: &firecracker.CNIConfiguration{NetworkName: "fireactions", IfName: "eth0", ConfDir: "/etc/cni/net.d", BinPath: []string{"/opt/cni/bin"}},
-		}},
-		MmdsAddress:    net.IPv4(169, 254, 169, 254),
-		MmdsVersion:    firecracker.MMDSv2,
-		ForwardSignals: []os.Signal{},
-	}, firecracker.WithProcessRunner(machineCmd), firecracker.WithLogger(logrus.NewEntry(logger)))
-	if err != nil {
-		return fmt.Errorf("firecracker: creating machine: %w", err)
-	}
+	return runner, nil
+}
 
-	installation, _, err := p.github.Apps.FindOrganizationInstallation(ctx, p.config.Runner.Organization)
-	if err != nil {
-		return fmt.Errorf("github: %w", err)
+func (p *Pool) scaleUp(ctx context.Context) (err error) {
+	config := p.getConfig()
+	runnerName := fmt.Sprintf("%s-%s", config.Runner.Name, stringid.New())
+	runner := newRunner(runnerName, config.Name,
+		filepath.Join(p.GetDir(), fmt.Sprintf("%s.sock", runnerName)),
+		filepath.Join(p.GetDir(), fmt.Sprintf("%s.log", runnerName)))
+	runner.generation = p.generation
+
+	if p.capacity != nil {
+		if err := p.capacity.Admit(p, runner.VMID); err != nil {
+			return err
+		}
+
+		defer func() {
+			if err != nil {
+				p.capacity.Release(runner.VMID)
+			}
+		}()
 	}
 
-	client := p.github.Installation(installation.GetID())
-	jitConfig, _, err := client.Actions.GenerateOrgJITConfig(ctx, p.config.Runner.Organization, &githubv63.GenerateJITConfigRequest{
-		Name:          runnerName,
-		RunnerGroupID: p.config.Runner.GroupID,
-		Labels:        p.config.Runner.Labels,
-	})
+	jitConfig, err := p.generateJITConfig(ctx, runnerName)
 	if err != nil {
 		return fmt.Errorf("github: %w", err)
 	}
 
-	metadata := map[string]interface{}{"latest": map[string]interface{}{"meta-data": deepcopy.Map(p.config.Firecracker.Metadata)}}
+	p.events.Publish(&fireactions.Event{Type: fireactions.EventTypeJITConfigIssued, Pool: config.Name, Runner: runnerName,
+		Message: fmt.Sprintf("GitHub runner ID %d", jitConfig.GetRunner().GetID())})
+
+	metadata := map[string]interface{}{"latest": map[string]interface{}{"meta-data": deepcopy.Map(config.Firecracker.Metadata)}}
 	metadata["latest"].(map[string]interface{})["meta-data"].(map[string]interface{})["fireactions"] = map[string]interface{}{
 		"runner_id":         runnerName,
 		"runner_jit_config": jitConfig.GetEncodedJITConfig(),
 	}
 
-	machine.Handlers.FcInit = machine.Handlers.FcInit.Append(firecracker.NewSetMetadataHandler(metadata))
+	machine, err := p.provider.Create(ctx, &MachineSpec{
+		ID:         runner.VMID,
+		SocketPath: runner.SocketPath,
+		LogPath:    runner.LogPath,
+		Metadata:   metadata,
+		Config:     config,
+	})
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		_ = machine.Wait(context.Background())
-		p.logger.Debug().Msgf("Firecracker VM %s exited", runnerName)
+	p.events.Publish(&fireactions.Event{Type: fireactions.EventTypeVMCreated, Pool: config.Name, Runner: runnerName, Message: fmt.Sprintf("machine %s", runner.VMID)})
+	runner.machine = machine
+	runner.githubID = jitConfig.GetRunner().GetID()
+	runner.onStateChange = p.saveRunner
 
-		p.machinesMu.Lock()
-		delete(p.machines, runnerName)
-		p.machinesMu.Unlock()
+	p.runnersMu.Lock()
+	p.runners[runnerName] = runner
+	p.runnersMu.Unlock()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		err := leaseCtxCancel(ctx)
-		if err != nil && !errdefs.IsNotFound(err) {
-			p.logger.Error().Err(err).Msgf(`Failed to remove Containerd lease for Firecracker VM %s.
-Run 'ctr --namespace %s leases rm fireactions/pools/%s/%s' to remove the lease manually`, runnerName, p.config.Name, p.config.Name, runnerName)
+	// The machine is recorded before it starts, so that it's stopped and
+	// cleaned up on startup if the server stops in the meantime.
+	p.saveRunner(runner)
+
+	if err := machine.Start(context.Background()); err != nil {
+		p.runnersMu.Lock()
+		delete(p.runners, runnerName)
+		p.runnersMu.Unlock()
+		p.forgetRunner(runnerName)
+
+		if err := p.provider.Remove(context.Background(), runner.VMID); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to remove machine %s", runner.VMID)
 		}
 
-		_ = machineLogFile.Close()
-	}()
+		if err := p.removeGitHubRunner(ctx, runner.githubID); err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to remove runner %s from GitHub", runnerName)
+		}
 
-	if err := machine.Start(context.Background()); err != nil {
-		return fmt.Errorf("firecracker: starting machine: %w", err)
+		return fmt.Errorf("starting machine: %w", err)
 	}
 
-	p.logger.Debug().Msgf("Firecracker VM %s started", runnerName)
-	p.machinesMu.Lock()
-	p.machines[runnerName] = machine
-	p.machinesMu.Unlock()
+	ipAddress := machine.IPAddress()
+	runner.setIPAddresses(ipAddress, machine.IPAddresses())
+
+	go p.waitRunner(runner)
+
+	runner.SetState(fireactions.RunnerStateIdle)
+	p.events.Publish(&fireactions.Event{Type: fireactions.EventTypeVMStarted, Pool: config.Name, Runner: runnerName, Message: fmt.Sprintf("IP address %s", ipAddress)})
+	p.logger.Debug().Msgf("Machine %s started", runnerName)
 
 	return nil
 }
 
-func (p *Pool) createSnapshot(ctx context.Context, image containerd.Image, snapshotID string) ([]mount.Mount, error) {
-	snapshotService := p.containerd.SnapshotService(defaultSnapshotter)
-	snapshotExists := true
-	_, err := snapshotService.Stat(ctx, snapshotID)
+// DeleteRunner stops the runner with the given name. If force is true, the
+// machine is killed and DeleteRunner returns once the runner resources are
+// released. Otherwise, the guest is asked to shut down (with Ctrl+Alt+Del for
+// Firecracker) and is forcefully stopped if it doesn't exit in time.
+func (p *Pool) DeleteRunner(ctx context.Context, name string, force bool) error {
+	runner, err := p.GetRunner(name)
 	if err != nil {
-		if !errdefs.IsNotFound(err) {
-			return nil, err
+		return err
+	}
+
+	runner.SetState(fireactions.RunnerStateExiting)
+
+	if !force {
+		err := runner.machine.Shutdown(ctx)
+		if err == nil {
+			p.logger.Debug().Msgf("Shutdown requested for machine %s", runner.VMID)
+			go func() {
+				select {
+				case <-runner.doneCh:
+				case <-time.After(defaultRunnerShutdownTimeout):
+					p.logger.Warn().Msgf("machine %s didn't shut down in %s, stopping forcefully", runner.VMID, defaultRunnerShutdownTimeout)
+					_ = runner.machine.Stop()
+				}
+			}()
+
+			return nil
 		}
 
-		snapshotExists = false
+		p.logger.Warn().Err(err).Msgf("Failed to shut down machine %s, stopping forcefully", runner.VMID)
 	}
 
-	if !snapshotExists {
-		if err := p.unpackImage(ctx, image); err != nil {
-			return nil, fmt.Errorf("unpack: %w", err)
-		}
+	if err := runner.machine.Stop(); err != nil {
+		return fmt.Errorf("firecracker: stopping machine: %w", err)
+	}
 
-		imageContent, err := image.RootFS(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("image: rootfs: %w", err)
-		}
+	select {
+	case <-runner.doneCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-		_, err = snapshotService.Prepare(ctx, snapshotID, identity.ChainID(imageContent).String())
-		if err != nil {
-			return nil, fmt.Errorf("prepare: %w", err)
-		}
+	p.logger.Debug().Msgf("Forcefully stopped machine %s", runner.VMID)
+	return nil
+}
+
+// installationClient returns a GitHub client authenticated as the App
+// installation matching the runner scope.
+func (p *Pool) installationClient(ctx context.Context) (*githubv63.Client, error) {
+	config := p.getConfig().Runner
+	var installation *githubv63.Installation
+	var err error
+	switch config.GetScope() {
+	case RunnerScopeRepository:
+		owner, repo := config.GetRepository()
+		installation, _, err = p.github.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	case RunnerScopeEnterprise:
+		installation, err = p.github.FindEnterpriseInstallation(ctx, config.Enterprise)
+	default:
+		installation, _, err = p.github.Apps.FindOrganizationInstallation(ctx, config.Organization)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.github.Installation(installation.GetID()), nil
+}
 
-	mounts, err := snapshotService.Mounts(ctx, snapshotID)
+// generateJITConfig generates a just-in-time GitHub runner configuration,
+// using the installation and the endpoint matching the runner scope.
+func (p *Pool) generateJITConfig(ctx context.Context, runnerName string) (*githubv63.JITRunnerConfig, error) {
+	config := p.getConfig().Runner
+	client, err := p.installationClient(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("mounts: %w", err)
+		return nil, err
+	}
+
+	request := &githubv63.GenerateJITConfigRequest{
+		Name:          runnerName,
+		RunnerGroupID: config.GroupID,
+		Labels:        config.Labels,
+	}
+
+	var jitConfig *githubv63.JITRunnerConfig
+	switch config.GetScope() {
+	case RunnerScopeRepository:
+		owner, repo := config.GetRepository()
+		jitConfig, _, err = client.Actions.GenerateRepoJITConfig(ctx, owner, repo, request)
+	case RunnerScopeEnterprise:
+		jitConfig, _, err = client.Enterprise.GenerateEnterpriseJITConfig(ctx, config.Enterprise, request)
+	default:
+		jitConfig, _, err = client.Actions.GenerateOrgJITConfig(ctx, config.Organization, request)
 	}
 
-	return mounts, nil
+	return jitConfig, err
 }
 
-func (p *Pool) unpackImage(ctx context.Context, image containerd.Image) error {
-	isUnpacked, err := image.IsUnpacked(ctx, defaultSnapshotter)
+// removeGitHubRunner unregisters the runner with the given GitHub ID, using the
+// endpoint matching the runner scope. GitHub refuses to remove a runner that
+// is running a job.
+func (p *Pool) removeGitHubRunner(ctx context.Context, id int64) error {
+	config := p.getConfig().Runner
+	client, err := p.installationClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	if isUnpacked {
-		return nil
+	switch config.GetScope() {
+	case RunnerScopeRepository:
+		owner, repo := config.GetRepository()
+		_, err = client.Actions.RemoveRunner(ctx, owner, repo, id)
+	case RunnerScopeEnterprise:
+		_, err = client.Enterprise.RemoveRunner(ctx, config.Enterprise, id)
+	default:
+		_, err = client.Actions.RemoveOrganizationRunner(ctx, config.Organization, id)
 	}
 
-	return image.Unpack(ctx, defaultSnapshotter)
+	return err
 }
 
-func (p *Pool) pullImage(ctx context.Context, ref string) (containerd.Image, error) {
-	p.containerdMu.Lock()
-	defer p.containerdMu.Unlock()
+// waitRunner waits for the runner machine to exit and releases all of its
+// resources.
+func (p *Pool) waitRunner(runner *Runner) {
+	defer close(runner.doneCh)
 
-	image, err := p.containerd.GetImage(ctx, ref)
-	if err != nil && !errdefs.IsNotFound(err) {
-		return nil, err
-	} else if err == nil {
-		return image, nil
+	err := runner.machine.Wait(context.Background())
+
+	event := &fireactions.Event{Type: fireactions.EventTypeVMExited, Pool: p.getConfig().Name, Runner: runner.Name,
+		Reason: fireactions.ExitReasonCompleted, DurationSeconds: time.Since(runner.StartedAt).Seconds()}
+	switch {
+	case runner.GetState() == fireactions.RunnerStateExiting:
+		event.Reason = fireactions.ExitReasonDeleted
+	case err != nil:
+		event.Reason = fireactions.ExitReasonFailed
 	}
 
-	dockerRef, err := reference.ParseDockerRef(ref)
 	if err != nil {
-		return nil, fmt.Errorf("parsing image ref: %w", err)
+		event.Error = err.Error()
 	}
 
-	refDomain := reference.Domain(dockerRef)
-	resolver, err := dockerconfigresolver.New(ctx, refDomain)
-	if err != nil {
-		return nil, fmt.Errorf("creating docker config resolver: %w", err)
+	runner.SetState(fireactions.RunnerStateExiting)
+	p.logger.Debug().Msgf("Machine %s exited", runner.Name)
+	p.events.Publish(event)
+
+	p.runnersMu.Lock()
+	delete(p.runners, runner.Name)
+	p.runnersMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.provider.Remove(ctx, runner.VMID); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to remove machine %s", runner.VMID)
 	}
 
-	image, err = p.containerd.Pull(ctx, ref,
-		containerd.WithPullUnpack, containerd.WithResolver(resolver), containerd.WithPullSnapshotter(defaultSnapshotter))
-	if err != nil {
-		return nil, err
+	if p.capacity != nil {
+		p.capacity.Release(runner.VMID)
 	}
 
-	return image, nil
+	p.forgetRunner(runner.Name)
 }
 
-func init() {
-	_ = log.SetLevel("panic")
+// saveRunner records the runner in the state store.
+func (p *Pool) saveRunner(runner *Runner) {
+	if p.store == nil {
+		return
+	}
+
+	if err := p.store.PutRunner(runner.record()); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to record machine %s in the state store", runner.Name)
+	}
+}
+
+// forgetRunner removes the runner with the given name from the state store.
+func (p *Pool) forgetRunner(name string) {
+	if p.store == nil {
+		return
+	}
+
+	if err := p.store.DeleteRunner(p.getConfig().Name, name); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to remove machine %s from the state store", name)
+	}
+}
+
+// imageRefresher is implemented by the machine providers that can pre-pull
+// the runner image.
+type imageRefresher interface {
+	RefreshImage(ctx context.Context, config *RunnerConfig) error
+}
+
+// startImageRefresher periodically pre-pulls new digests of the runner image,
+// so that scaling up doesn't have to wait for the pull. It only runs for
+// pools with the Always image pull policy whose machine provider supports it,
+// and stops when stopCh is closed.
+func (p *Pool) startImageRefresher(stopCh <-chan struct{}) {
+	refresher, ok := p.provider.(imageRefresher)
+	if !ok {
+		return
+	}
+
+	t := time.NewTicker(p.getConfig().Runner.GetImageRefreshInterval())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+		}
+
+		// The interval may have changed on reload.
+		config := p.getConfig().Runner
+		t.Reset(config.GetImageRefreshInterval())
+
+		if config.GetImagePullPolicy() != ImagePullPolicyAlways {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), config.GetImageRefreshInterval())
+		err := refresher.RefreshImage(ctx, config)
+		cancel()
+		if err != nil {
+			p.logger.Error().Err(err).Msgf("Failed to refresh image %s", config.Image)
+		}
+	}
 }
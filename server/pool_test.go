@@ -1 +1,329 @@
 package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/hostinger/fireactions/helper/github"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGitHub starts a fake GitHub API serving the endpoints used by a pool
+// of an organization and returns a client for it, along with the number of
+// runners removed through it.
+func newTestGitHub(t *testing.T) (*github.Client, *atomic.Int32) {
+	return newTestGitHubWithBusyRunners(t, nil)
+}
+
+// newTestGitHubWithBusyRunners is like newTestGitHub, but refuses to remove
+// the runners with the given IDs, as GitHub does for runners running a job.
+func newTestGitHubWithBusyRunners(t *testing.T, busy map[string]bool) (*github.Client, *atomic.Int32) {
+	removed := &atomic.Int32{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/hostinger/installation", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 1})
+	})
+	mux.HandleFunc("POST /app/installations/1/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"token": "token", "expires_at": time.Now().Add(time.Hour)})
+	})
+	mux.HandleFunc("POST /orgs/hostinger/actions/runners/generate-jitconfig", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"runner": map[string]interface{}{"id": 42}, "encoded_jit_config": "config"})
+	})
+	mux.HandleFunc("DELETE /orgs/hostinger/actions/runners/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if busy[r.PathValue("id")] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+
+		removed.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	key, err := os.ReadFile("testdata/github.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := github.NewClient(12345, string(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client, removed
+}
+
+func newTestPool(t *testing.T, store *Store) (*Pool, *FakeProvider, *atomic.Int32) {
+	client, removed := newTestGitHub(t)
+	config := newTestConfig(t).Pools[0]
+	config.MaxRunners = 5
+
+	logger := zerolog.Nop()
+	provider := NewFakeProvider()
+	pool, err := NewPool(&logger, config, client, store, provider, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool, provider, removed
+}
+
+func TestPool_Scale(t *testing.T) {
+	pool, provider, _ := newTestPool(t, nil)
+
+	result := pool.ScaleTo(context.Background(), 3)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, 3, result.Replicas)
+	assert.Equal(t, 3, pool.GetCurrentSize())
+
+	for _, runner := range pool.ListRunners() {
+		assert.Equal(t, fireactions.RunnerStateIdle, runner.GetState())
+		assert.Equal(t, "127.0.0.1", runner.GetIPAddress())
+
+		machine, ok := provider.GetMachine(runner.VMID)
+		assert.True(t, ok)
+		assert.True(t, machine.IsRunning())
+		assert.Equal(t, runner.SocketPath, machine.Spec().SocketPath)
+
+		metadata := machine.Spec().Metadata["latest"].(map[string]interface{})["meta-data"].(map[string]interface{})
+		assert.Equal(t, "config", metadata["fireactions"].(map[string]interface{})["runner_jit_config"])
+	}
+
+	result = pool.Scale(context.Background(), -2)
+	assert.NoError(t, result.Err())
+	assert.Eventually(t, func() bool { return pool.GetCurrentSize() == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		machines, err := provider.List(context.Background())
+		return err == nil && len(machines) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestPool_Scale_ConcurrentReads reads a runner, like the API handlers, while
+// its machine starts. Run with -race.
+func TestPool_Scale_ConcurrentReads(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	pool, provider, _ := newTestPool(t, store)
+	provider.StartDelay = 100 * time.Millisecond
+
+	readCh := make(chan *fireactions.Runner, 1)
+	go func() {
+		for {
+			if runners := pool.ListRunners(); len(runners) > 0 {
+				_ = runners[0].record()
+				readCh <- convertRunner(runners[0])
+				return
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+	<-readCh
+
+	assert.Equal(t, "127.0.0.1", pool.ListRunners()[0].GetIPAddress())
+}
+
+func TestPool_Scale_Down(t *testing.T) {
+	t.Run("Graceful", func(t *testing.T) {
+		pool, provider, removed := newTestPool(t, nil)
+		provider.IgnoreShutdown = true
+		assert.NoError(t, pool.ScaleTo(context.Background(), 3).Err())
+
+		assert.NoError(t, pool.Scale(context.Background(), -2).Err())
+		assert.Equal(t, int32(2), removed.Load())
+		assert.Equal(t, 2, pool.countRunners(fireactions.RunnerStateExiting))
+
+		// The runners shutting down are not replaced by other ones.
+		assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+		assert.Equal(t, int32(2), removed.Load())
+		assert.Equal(t, 1, pool.countRunners(fireactions.RunnerStateIdle))
+	})
+
+	t.Run("Busy", func(t *testing.T) {
+		pool, _, _ := newTestPool(t, nil)
+		pool.github, _ = newTestGitHubWithBusyRunners(t, map[string]bool{"42": true})
+		assert.NoError(t, pool.ScaleTo(context.Background(), 2).Err())
+
+		result := pool.Scale(context.Background(), -1)
+		assert.ErrorContains(t, result.Err(), "not enough idle runners")
+		assert.Equal(t, 2, pool.GetCurrentSize())
+		assert.Equal(t, 0, pool.countRunners(fireactions.RunnerStateExiting))
+	})
+}
+
+func TestPool_Scale_StartError(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	pool, provider, removed := newTestPool(t, store)
+	provider.StartErr = assert.AnError
+
+	result := pool.ScaleTo(context.Background(), 1)
+	assert.ErrorIs(t, result.Err(), assert.AnError)
+	assert.Equal(t, 0, pool.GetCurrentSize())
+	assert.Equal(t, int32(1), removed.Load())
+
+	machines, err := provider.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, machines)
+
+	records, err := store.ListRunners("test")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestPool_MachineExit(t *testing.T) {
+	pool, provider, _ := newTestPool(t, nil)
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+
+	runner := pool.ListRunners()[0]
+	machine, _ := provider.GetMachine(runner.VMID)
+	machine.Exit()
+
+	assert.Eventually(t, func() bool { return pool.GetCurrentSize() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, ok := provider.GetMachine(runner.VMID)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPool_DeleteRunner(t *testing.T) {
+	t.Run("graceful", func(t *testing.T) {
+		pool, _, _ := newTestPool(t, nil)
+		assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+
+		runner := pool.ListRunners()[0]
+		assert.NoError(t, pool.DeleteRunner(context.Background(), runner.Name, false))
+		assert.Eventually(t, func() bool { return pool.GetCurrentSize() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("force", func(t *testing.T) {
+		pool, _, _ := newTestPool(t, nil)
+		assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+
+		runner := pool.ListRunners()[0]
+		assert.NoError(t, pool.DeleteRunner(context.Background(), runner.Name, true))
+		assert.Equal(t, 0, pool.GetCurrentSize())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		pool, _, _ := newTestPool(t, nil)
+		assert.ErrorIs(t, pool.DeleteRunner(context.Background(), "unknown", true), fireactions.ErrRunnerNotFound)
+	})
+}
+
+func TestPool_Drain(t *testing.T) {
+	pool, _, removed := newTestPool(t, nil)
+	assert.NoError(t, pool.ScaleTo(context.Background(), 2).Err())
+
+	assert.NoError(t, pool.Drain(context.Background(), time.Minute))
+	assert.Equal(t, 0, pool.GetCurrentSize())
+	assert.Equal(t, int32(2), removed.Load())
+
+	result := pool.Scale(context.Background(), 1)
+	assert.Error(t, result.Err())
+}
+
+func TestPool_Stop(t *testing.T) {
+	pool, _, _ := newTestPool(t, nil)
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+
+	// The pool was never started.
+	pool.Stop()
+	assert.Equal(t, 0, pool.GetCurrentSize())
+
+	pool.Stop()
+}
+
+func TestPool_PauseResume(t *testing.T) {
+	pool, _, _ := newTestPool(t, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); pool.Pause() }()
+		go func() { defer wg.Done(); _ = convertPool(pool) }()
+	}
+	wg.Wait()
+	assert.False(t, pool.isActive.Load())
+
+	go func() { _ = pool.Drain(context.Background(), time.Minute) }()
+	assert.Eventually(t, pool.isDraining.Load, time.Second, 10*time.Millisecond)
+
+	pool.Resume()
+	assert.True(t, pool.isActive.Load())
+	assert.False(t, pool.isDraining.Load())
+}
+
+func TestPool_Reconcile(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	pool, provider, removed := newTestPool(t, store)
+
+	alive, _ := provider.Create(context.Background(), &MachineSpec{ID: "test-alive"})
+	assert.NoError(t, alive.Start(context.Background()))
+	_, _ = provider.Create(context.Background(), &MachineSpec{ID: "test-dead"})
+	_, _ = provider.Create(context.Background(), &MachineSpec{ID: "test-orphan"})
+	starting, _ := provider.Create(context.Background(), &MachineSpec{ID: "test-starting"})
+	assert.NoError(t, starting.Start(context.Background()))
+
+	logPath := filepath.Join(t.TempDir(), "test-dead.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte("log"), 0644))
+
+	assert.NoError(t, store.PutRunner(&RunnerRecord{Name: "test-alive", Pool: "test", VMID: "test-alive", GitHubID: 1, State: fireactions.RunnerStateBusy}))
+	assert.NoError(t, store.PutRunner(&RunnerRecord{Name: "test-dead", Pool: "test", VMID: "test-dead", GitHubID: 2, LogPath: logPath}))
+	assert.NoError(t, store.PutRunner(&RunnerRecord{Name: "test-starting", Pool: "test", VMID: "test-starting", GitHubID: 3, State: fireactions.RunnerStateStarting}))
+
+	assert.NoError(t, pool.Reconcile(context.Background()))
+
+	// The machine left starting is stopped rather than adopted.
+	assert.False(t, starting.(*FakeMachine).IsRunning())
+	_, err = pool.GetRunner("test-starting")
+	assert.Error(t, err)
+
+	runner, err := pool.GetRunner("test-alive")
+	assert.NoError(t, err)
+	assert.Equal(t, fireactions.RunnerStateBusy, runner.GetState())
+	assert.Equal(t, 1, pool.GetCurrentSize())
+
+	machines, err := provider.List(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, machines, 1)
+	assert.Equal(t, int32(2), removed.Load())
+	assert.NoFileExists(t, logPath)
+
+	records, err := store.ListRunners("test")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "test-alive", records[0].Name)
+}
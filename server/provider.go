@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// MachineProvider creates and manages the virtual machines backing the runners
+// of a pool.
+type MachineProvider interface {
+	// Create prepares a new machine described by spec. The machine is not
+	// started.
+	Create(ctx context.Context, spec *MachineSpec) (Machine, error)
+
+	// Adopt returns the machine of a runner that was started before the server
+	// restarted. It returns an error if the machine is no longer running.
+	Adopt(ctx context.Context, record *RunnerRecord) (Machine, error)
+
+	// List returns the machines that have resources allocated by the
+	// provider, whether they are running or not.
+	List(ctx context.Context) ([]*MachineInfo, error)
+
+	// Remove releases the resources of the machine with the given ID. The
+	// machine must not be running.
+	Remove(ctx context.Context, id string) error
+
+	// Close releases the resources of the provider.
+	Close() error
+}
+
+// MachineProviderFactory creates the MachineProvider of a pool.
+type MachineProviderFactory func(logger *zerolog.Logger, config *PoolConfig) (MachineProvider, error)
+
+// Machine is a single virtual machine running a GitHub runner.
+type Machine interface {
+	// ID returns the ID of the machine.
+	ID() string
+
+	// PID returns the PID of the process running the machine, or 0 if it's
+	// not running.
+	PID() int
+
+	// IPAddress returns the IP address of the machine, if it has one.
+	IPAddress() string
+
+	// IPAddresses returns the addresses allocated to the interfaces of the
+	// machine from their IP ranges, indexed by interface. They are reserved
+	// again when the machine is adopted.
+	IPAddresses() []string
+
+	// Start boots the machine.
+	Start(ctx context.Context) error
+
+	// Shutdown asks the guest to shut down.
+	Shutdown(ctx context.Context) error
+
+	// Stop kills the machine immediately.
+	Stop() error
+
+	// Wait blocks until the machine exits or the context is canceled.
+	Wait(ctx context.Context) error
+}
+
+// MachineSpec describes a machine to create.
+type MachineSpec struct {
+	ID         string
+	SocketPath string
+	LogPath    string
+	Metadata   map[string]interface{}
+	Config     *PoolConfig
+}
+
+// MachineInfo describes a machine known to a MachineProvider.
+type MachineInfo struct {
+	ID        string
+	CreatedAt time.Time
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// FakeProvider is an in-process MachineProvider that doesn't run any virtual
+// machine. Its machines run until they are shut down or stopped, which makes it
+// suitable for testing the pool lifecycle.
+type FakeProvider struct {
+	// CreateErr, if set, is returned by Create.
+	CreateErr error
+
+	// StartErr, if set, is returned by Start of every machine.
+	StartErr error
+
+	// StartDelay, if set, makes Start of every machine take that long, like
+	// a booting guest.
+	StartDelay time.Duration
+
+	// IgnoreShutdown, if set, makes the machines keep running on Shutdown,
+	// like a guest that takes long to shut down.
+	IgnoreShutdown bool
+
+	machines map[string]*FakeMachine
+	l        *sync.Mutex
+}
+
+// NewFakeProvider creates a new FakeProvider.
+func NewFakeProvider() *FakeProvider {
+	p := &FakeProvider{
+		machines: make(map[string]*FakeMachine),
+		l:        &sync.Mutex{},
+	}
+
+	return p
+}
+
+// NewFakeProviderFactory returns a MachineProviderFactory creating a new
+// FakeProvider for every pool.
+func NewFakeProviderFactory() MachineProviderFactory {
+	return func(*zerolog.Logger, *PoolConfig) (MachineProvider, error) { return NewFakeProvider(), nil }
+}
+
+// Create creates a new FakeMachine.
+func (p *FakeProvider) Create(ctx context.Context, spec *MachineSpec) (Machine, error) {
+	if p.CreateErr != nil {
+		return nil, p.CreateErr
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	machine := &FakeMachine{
+		id:        spec.ID,
+		spec:      spec,
+		provider:  p,
+		createdAt: time.Now(),
+		exitCh:    make(chan struct{}),
+		l:         &sync.Mutex{},
+	}
+
+	p.machines[spec.ID] = machine
+	return machine, nil
+}
+
+// Adopt returns the machine with the ID of the record, if it's running.
+func (p *FakeProvider) Adopt(ctx context.Context, record *RunnerRecord) (Machine, error) {
+	machine, ok := p.GetMachine(record.VMID)
+	if !ok || !machine.IsRunning() {
+		return nil, fmt.Errorf("machine %s is not running", record.VMID)
+	}
+
+	return machine, nil
+}
+
+// List returns all the machines that haven't been removed.
+func (p *FakeProvider) List(ctx context.Context) ([]*MachineInfo, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	machines := make([]*MachineInfo, 0, len(p.machines))
+	for _, machine := range p.machines {
+		machines = append(machines, &MachineInfo{ID: machine.id, CreatedAt: machine.createdAt})
+	}
+
+	return machines, nil
+}
+
+// Remove removes the machine with the given ID.
+func (p *FakeProvider) Remove(ctx context.Context, id string) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	delete(p.machines, id)
+	return nil
+}
+
+// Close does nothing.
+func (p *FakeProvider) Close() error {
+	return nil
+}
+
+// GetMachine returns the machine with the given ID.
+func (p *FakeProvider) GetMachine(id string) (*FakeMachine, bool) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	machine, ok := p.machines[id]
+	return machine, ok
+}
+
+// FakeMachine is a Machine created by FakeProvider.
+type FakeMachine struct {
+	id        string
+	spec      *MachineSpec
+	provider  *FakeProvider
+	createdAt time.Time
+	isRunning bool
+	isExited  bool
+	exitCh    chan struct{}
+	l         *sync.Mutex
+}
+
+func (m *FakeMachine) ID() string {
+	return m.id
+}
+
+func (m *FakeMachine) PID() int {
+	if !m.IsRunning() {
+		return 0
+	}
+
+	return 1
+}
+
+func (m *FakeMachine) IPAddress() string {
+	return "127.0.0.1"
+}
+
+func (m *FakeMachine) IPAddresses() []string {
+	return nil
+}
+
+// Spec returns the spec the machine was created with.
+func (m *FakeMachine) Spec() *MachineSpec {
+	return m.spec
+}
+
+// IsRunning returns true if the machine was started and hasn't exited.
+func (m *FakeMachine) IsRunning() bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	return m.isRunning
+}
+
+func (m *FakeMachine) Start(ctx context.Context) error {
+	if m.provider.StartErr != nil {
+		return m.provider.StartErr
+	}
+
+	time.Sleep(m.provider.StartDelay)
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.isRunning = true
+	return nil
+}
+
+// Shutdown makes the machine exit, like a guest that shuts down.
+func (m *FakeMachine) Shutdown(ctx context.Context) error {
+	if !m.provider.IgnoreShutdown {
+		m.Exit()
+	}
+
+	return nil
+}
+
+// Stop makes the machine exit.
+func (m *FakeMachine) Stop() error {
+	m.Exit()
+	return nil
+}
+
+func (m *FakeMachine) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.exitCh:
+		return nil
+	}
+}
+
+// Exit makes the machine exit, like a runner that completed its job.
+func (m *FakeMachine) Exit() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.isExited {
+		return
+	}
+
+	m.isRunning = false
+	m.isExited = true
+	close(m.exitCh)
+}
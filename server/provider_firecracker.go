@@ -0,0 +1,723 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/nerdctl/pkg/imgutil/dockerconfigresolver"
+	"github.com/distribution/reference"
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+	"github.com/hostinger/fireactions/runner"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBootObserveTimeout = 10 * time.Minute
+)
+
+// firecrackerProvider runs machines as Firecracker VMs, booting from a root
+// drive prepared from a Containerd snapshot of the runner image and networked
+// with CNI.
+type firecrackerProvider struct {
+	pool         string
+	dir          string
+	snapshotter  string
+	rootDrives   rootDrives
+	network      *NetworkConfig
+	ips          *ipAllocator
+	jailer       *JailerConfig
+	execFile     string
+	containerd   *containerd.Client
+	containerdMu *sync.Mutex
+	templates    map[string]*warmBootTemplate
+	templatesMu  *sync.Mutex
+	logger       *zerolog.Logger
+}
+
+// NewFirecrackerProvider creates the default MachineProvider, using the pool
+// name as the Containerd namespace and the snapshotter of the pool. It fails
+// if a CNI network of the pool isn't configured on the host.
+func NewFirecrackerProvider(logger *zerolog.Logger, config *PoolConfig) (MachineProvider, error) {
+	network := config.GetNetwork()
+	if err := checkNetworks(network); err != nil {
+		return nil, fmt.Errorf("cni: %w", err)
+	}
+
+	var jailer *JailerConfig
+	var execFile string
+	if config.Firecracker.IsJailerEnabled() {
+		path, err := exec.LookPath(config.Firecracker.BinaryPath)
+		if err != nil {
+			return nil, fmt.Errorf("jailer: %w", err)
+		}
+
+		jailer = config.Firecracker.Jailer
+		if execFile, err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("jailer: %w", err)
+		}
+	}
+
+	containerd, err := containerd.New("/run/containerd/containerd.sock",
+		containerd.WithDefaultNamespace(config.Name),
+		containerd.WithTimeout(5*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("containerd: creating client: %w", err)
+	}
+
+	p := &firecrackerProvider{
+		pool:         config.Name,
+		dir:          getPoolDir(config.Name),
+		snapshotter:  config.Firecracker.GetSnapshotter(),
+		rootDrives:   newRootDrives(containerd, config.Name, getPoolDir(config.Name), config.Firecracker),
+		network:      network,
+		ips:          newIPAllocator(network),
+		jailer:       jailer,
+		execFile:     execFile,
+		containerd:   containerd,
+		containerdMu: &sync.Mutex{},
+		templates:    make(map[string]*warmBootTemplate),
+		templatesMu:  &sync.Mutex{},
+		logger:       logger,
+	}
+
+	_, err = os.Stat(p.dir)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(p.dir, 0755); err != nil {
+			return nil, fmt.Errorf("creating pool directory: %w", err)
+		}
+
+		p.logger.Debug().Msgf("Pool directory created at %s", p.dir)
+	}
+
+	return p, nil
+}
+
+// Create creates the root drive of the machine and configures the Firecracker
+// VM. With warm boot, the machine is restored from
+// the warm boot template of the pool instead of booting. The resources are
+// released if any step fails.
+func (p *firecrackerProvider) Create(ctx context.Context, spec *MachineSpec) (_ Machine, err error) {
+	createdAt := time.Now()
+
+	image, err := p.getImage(ctx, spec.Config.Runner)
+	if err != nil {
+		return nil, err
+	}
+
+	var template *warmBootTemplate
+	if spec.Config.Firecracker.IsWarmBootEnabled() {
+		template, err = p.getTemplate(ctx, spec.Config, image)
+		if err != nil {
+			return nil, fmt.Errorf("warm boot: %w", err)
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			_ = p.Remove(context.Background(), spec.ID)
+		}
+	}()
+
+	drivePath, err := p.rootDrives.Create(ctx, spec.ID, image, template)
+	if err != nil {
+		return nil, err
+	}
+
+	drives, drivesMetadata, err := p.createDrives(ctx, spec.ID, spec.Config.Firecracker)
+	if err != nil {
+		return nil, err
+	}
+	getFireactionsMetadata(spec.Metadata)["drives"] = drivesMetadata
+
+	// The log file is opened in append mode so that it can be rotated by
+	// truncating it while the Firecracker process is writing to it.
+	logFile, err := os.OpenFile(spec.LogPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("creating log file: %w", err)
+	}
+
+	var opts []firecracker.Opt
+	if template != nil {
+		opts = append(opts, firecracker.WithSnapshot(template.memFilePath, template.statePath))
+	}
+
+	networkInterface, err := p.newNetworkInterface(spec.ID)
+	if err != nil {
+		_ = logFile.Close()
+		return nil, fmt.Errorf("cni: %w", err)
+	}
+
+	machineConfig := newMachineConfig(spec.ID, spec.SocketPath, drivePath, spec.Config.Firecracker, networkInterface)
+	machineConfig.Drives = append(machineConfig.Drives, drives...)
+	machine, err := p.newMachine(ctx, machineConfig, spec.Config.Firecracker, logFile, opts...)
+	if err != nil {
+		_ = logFile.Close()
+		return nil, err
+	}
+
+	if len(p.network.Interfaces) > 1 {
+		machine.Handlers.FcInit = machine.Handlers.FcInit.AppendAfter(firecracker.SetupNetworkHandlerName, p.newSecondaryNetworksHandler(spec.Metadata))
+	}
+
+	bootMode := bootModeCold
+	if template != nil {
+		bootMode = bootModeWarm
+		machine.Handlers.FcInit = machine.Handlers.FcInit.Append(newRestoreHandler(spec.Metadata))
+	} else {
+		machine.Handlers.FcInit = machine.Handlers.FcInit.Append(firecracker.NewSetMetadataHandler(spec.Metadata))
+		if handler, ok := newBalloonHandler(spec.Config.Firecracker.Balloon); ok {
+			machine.Handlers.FcInit = machine.Handlers.FcInit.Append(handler)
+		}
+	}
+
+	m := &firecrackerMachine{
+		machine:   machine,
+		logFile:   logFile,
+		pool:      p.pool,
+		bootMode:  bootMode,
+		createdAt: createdAt,
+		ips:       p.ips,
+		once:      &sync.Once{},
+	}
+
+	return m, nil
+}
+
+// newMachine creates the Firecracker VM, launched directly or through the
+// jailer, with its output written to logFile. With the jailer, the API socket
+// in the chroot is linked at the socket path of the configuration.
+func (p *firecrackerProvider) newMachine(ctx context.Context, machineConfig firecracker.Config, config *FirecrackerConfig, logFile *os.File, opts ...firecracker.Opt) (*firecracker.Machine, error) {
+	socketPath := machineConfig.SocketPath
+
+	var machineCmd *exec.Cmd
+	if p.jailer != nil {
+		machineCmd = p.jail(&machineConfig, logFile)
+	} else {
+		machineCmd = firecracker.VMCommandBuilder{}.
+			WithSocketPath(socketPath).
+			WithStderr(logFile).
+			WithStdout(logFile).
+			WithBin(config.BinaryPath).
+			Build(context.Background())
+	}
+
+	opts = append([]firecracker.Opt{firecracker.WithProcessRunner(machineCmd), firecracker.WithLogger(newDiscardLogger())}, opts...)
+	machine, err := firecracker.NewMachine(ctx, machineConfig, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("firecracker: creating machine: %w", err)
+	}
+
+	if p.jailer == nil {
+		return machine, nil
+	}
+
+	machine.Handlers.FcInit = machine.Handlers.FcInit.AppendAfter(firecracker.CreateLogFilesHandlerName, p.newJailerLinkHandler())
+
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("removing socket: %w", err)
+	}
+
+	if err := os.Symlink(machine.Cfg.SocketPath, socketPath); err != nil {
+		return nil, fmt.Errorf("linking socket: %w", err)
+	}
+
+	return machine, nil
+}
+
+// newMachineConfig returns the configuration of a Firecracker VM booting from
+// the root drive at drivePath, with networkInterface as its primary interface.
+func newMachineConfig(id, socketPath, drivePath string, config *FirecrackerConfig, networkInterface firecracker.NetworkInterface) firecracker.Config {
+	return firecracker.Config{
+		VMID:            id,
+		SocketPath:      socketPath,
+		KernelImagePath: config.KernelImagePath,
+		KernelArgs:      config.KernelArgs,
+		MachineCfg: models.MachineConfiguration{
+			VcpuCount:       &config.MachineConfig.VcpuCount,
+			MemSizeMib:      &config.MachineConfig.MemSizeMib,
+			Smt:             firecracker.Bool(config.MachineConfig.Smt),
+			CPUTemplate:     models.CPUTemplate(config.MachineConfig.CPUTemplate),
+			TrackDirtyPages: config.MachineConfig.TrackDirtyPages,
+		},
+		Drives: []models.Drive{{
+			DriveID:      firecracker.String("rootfs"),
+			PathOnHost:   &drivePath,
+			IsRootDevice: firecracker.Bool(true),
+			IsReadOnly:   firecracker.Bool(false),
+			RateLimiter:  newRateLimiter(config.RootfsRateLimiter),
+		}},
+		NetworkInterfaces: []firecracker.NetworkInterface{networkInterface},
+		MmdsAddress:       net.IPv4(169, 254, 169, 254),
+		MmdsVersion:       firecracker.MMDSv2,
+		ForwardSignals:    []os.Signal{},
+	}
+}
+
+// Adopt connects to the API socket of a Firecracker VM that is still running.
+// As the process isn't a child of the server, it's signaled and polled by PID.
+// A process that can't be adopted is killed.
+func (p *firecrackerProvider) Adopt(ctx context.Context, record *RunnerRecord) (Machine, error) {
+	// Machines recorded while starting may have no PID yet.
+	pid := record.PID
+	if pid == 0 {
+		pid = findFirecrackerProcess(record.VMID, record.SocketPath)
+	}
+
+	if !isFirecrackerProcess(pid, record.VMID, record.SocketPath) {
+		return nil, fmt.Errorf("firecracker: process %d is not running", pid)
+	}
+
+	machine, err := firecracker.NewMachine(ctx, firecracker.Config{
+		VMID:       record.VMID,
+		SocketPath: record.SocketPath,
+	}, firecracker.WithLogger(newDiscardLogger()))
+	if err != nil {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+		return nil, fmt.Errorf("firecracker: creating machine: %w", err)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, fmt.Errorf("finding process: %w", err)
+	}
+
+	// Records saved by older versions only have the primary address.
+	p.ips.Reserve(record.VMID, 0, net.ParseIP(record.IPAddress))
+	for i, ip := range record.IPAddresses {
+		p.ips.Reserve(record.VMID, i, net.ParseIP(ip))
+	}
+
+	m := &firecrackerMachine{machine: machine, process: process, ipAddress: record.IPAddress, ips: p.ips, once: &sync.Once{}}
+	return m, nil
+}
+
+// List returns the machines having a root drive, scratch drives or a leftover
+// API socket.
+func (p *firecrackerProvider) List(ctx context.Context) ([]*MachineInfo, error) {
+	machines := make(map[string]*MachineInfo)
+	add := func(id string, createdAt time.Time) {
+		machine, ok := machines[id]
+		if !ok {
+			machines[id] = &MachineInfo{ID: id, CreatedAt: createdAt}
+			return
+		}
+
+		if createdAt.Before(machine.CreatedAt) {
+			machine.CreatedAt = createdAt
+		}
+	}
+
+	if err := p.rootDrives.List(ctx, add); err != nil {
+		return nil, err
+	}
+
+	dirs, err := os.ReadDir(filepath.Join(p.dir, "drives"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("listing drives: %w", err)
+	}
+
+	for _, dir := range dirs {
+		info, err := dir.Info()
+		if err != nil || strings.HasPrefix(dir.Name(), templatePrefix) {
+			continue
+		}
+
+		add(dir.Name(), info.ModTime())
+	}
+
+	sockets, err := filepath.Glob(filepath.Join(p.dir, "*.sock"))
+	if err != nil {
+		return nil, fmt.Errorf("listing sockets: %w", err)
+	}
+
+	for _, socket := range sockets {
+		info, err := os.Stat(socket)
+		if err != nil {
+			continue
+		}
+
+		add(strings.TrimSuffix(filepath.Base(socket), ".sock"), info.ModTime())
+	}
+
+	result := make([]*MachineInfo, 0, len(machines))
+	for _, machine := range machines {
+		result = append(result, machine)
+	}
+
+	return result, nil
+}
+
+// Remove detaches the machine from its networks and removes its root drive,
+// scratch drives, chroot and API socket. The log file is left to the garbage
+// collector.
+func (p *firecrackerProvider) Remove(ctx context.Context, id string) error {
+	var errs []error
+
+	if err := p.removeNetworks(ctx, id); err != nil {
+		errs = append(errs, fmt.Errorf("cni: %w", err))
+	}
+
+	if err := p.rootDrives.Remove(ctx, id); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := os.RemoveAll(p.getDrivesDir(id)); err != nil {
+		errs = append(errs, fmt.Errorf("removing drives: %w", err))
+	}
+
+	if p.jailer != nil {
+		if err := p.removeJail(id); err != nil {
+			errs = append(errs, fmt.Errorf("jailer: %w", err))
+		}
+	}
+
+	err := os.Remove(filepath.Join(p.dir, fmt.Sprintf("%s.sock", id)))
+	if err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("removing socket: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the Containerd client.
+func (p *firecrackerProvider) Close() error {
+	return p.containerd.Close()
+}
+
+// getImage returns the runner image, pulling it according to the image pull
+// policy of the pool.
+func (p *firecrackerProvider) getImage(ctx context.Context, config *RunnerConfig) (containerd.Image, error) {
+	ref := config.Image
+
+	switch config.GetImagePullPolicy() {
+	case ImagePullPolicyNever:
+		image, err := p.containerd.GetImage(ctx, ref)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				return nil, fmt.Errorf("containerd: image %s is not present and image pull policy is %s", ref, ImagePullPolicyNever)
+			}
+
+			return nil, fmt.Errorf("containerd: getting image: %w", err)
+		}
+
+		return image, nil
+	case ImagePullPolicyAlways:
+		image, err := p.refreshImage(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("containerd: refreshing image: %w", err)
+		}
+
+		return image, nil
+	default:
+		image, err := p.containerd.GetImage(ctx, ref)
+		if err == nil {
+			return image, nil
+		}
+
+		if !errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("containerd: getting image: %w", err)
+		}
+
+		p.logger.Debug().Msg("Pulling image")
+
+		start := time.Now()
+		image, err = p.pullImage(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("containerd: pulling image: %w", err)
+		}
+
+		p.logger.Debug().Msgf("Image pulled in %s", time.Since(start))
+		return image, nil
+	}
+}
+
+// RefreshImage pre-pulls a new digest of the runner image, so that scaling up
+// doesn't have to wait for the pull.
+func (p *firecrackerProvider) RefreshImage(ctx context.Context, config *RunnerConfig) error {
+	_, err := p.refreshImage(ctx, config.Image)
+	return err
+}
+
+// refreshImage resolves the image reference against the registry and pulls
+// the image if the local image is missing or its digest differs from the
+// remote one.
+func (p *firecrackerProvider) refreshImage(ctx context.Context, ref string) (containerd.Image, error) {
+	p.containerdMu.Lock()
+	defer p.containerdMu.Unlock()
+
+	resolver, err := newImageResolver(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving image ref: %w", err)
+	}
+
+	image, err := p.containerd.GetImage(ctx, ref)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return nil, err
+	} else if err == nil && image.Target().Digest == desc.Digest {
+		return image, nil
+	}
+
+	p.logger.Debug().Msgf("Pulling image %s (%s)", ref, desc.Digest)
+
+	start := time.Now()
+	image, err = p.containerd.Pull(ctx, ref,
+		containerd.WithPullUnpack, containerd.WithResolver(resolver), containerd.WithPullSnapshotter(p.snapshotter))
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug().Msgf("Image pulled in %s", time.Since(start))
+	return image, nil
+}
+
+func (p *firecrackerProvider) pullImage(ctx context.Context, ref string) (containerd.Image, error) {
+	p.containerdMu.Lock()
+	defer p.containerdMu.Unlock()
+
+	image, err := p.containerd.GetImage(ctx, ref)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return nil, err
+	} else if err == nil {
+		return image, nil
+	}
+
+	resolver, err := newImageResolver(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err = p.containerd.Pull(ctx, ref,
+		containerd.WithPullUnpack, containerd.WithResolver(resolver), containerd.WithPullSnapshotter(p.snapshotter))
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func newImageResolver(ctx context.Context, ref string) (remotes.Resolver, error) {
+	dockerRef, err := reference.ParseDockerRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image ref: %w", err)
+	}
+
+	refDomain := reference.Domain(dockerRef)
+	resolver, err := dockerconfigresolver.New(ctx, refDomain)
+	if err != nil {
+		return nil, fmt.Errorf("creating docker config resolver: %w", err)
+	}
+
+	return resolver, nil
+}
+
+func newDiscardLogger() *logrus.Entry {
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	logger.SetOutput(io.Discard)
+
+	return logrus.NewEntry(logger)
+}
+
+// firecrackerMachine is a Machine backed by a Firecracker VM.
+type firecrackerMachine struct {
+	machine   *firecracker.Machine
+	process   *os.Process
+	ipAddress string
+	logFile   *os.File
+	pool      string
+	bootMode  string
+	createdAt time.Time
+	ips       *ipAllocator
+	once      *sync.Once
+}
+
+func (m *firecrackerMachine) ID() string {
+	return m.machine.Cfg.VMID
+}
+
+func (m *firecrackerMachine) PID() int {
+	if m.process != nil {
+		return m.process.Pid
+	}
+
+	pid, _ := m.machine.PID()
+	return pid
+}
+
+func (m *firecrackerMachine) IPAddress() string {
+	if m.ipAddress != "" {
+		return m.ipAddress
+	}
+
+	for _, iface := range m.machine.Cfg.NetworkInterfaces {
+		if iface.StaticConfiguration == nil || iface.StaticConfiguration.IPConfiguration == nil {
+			continue
+		}
+
+		return iface.StaticConfiguration.IPConfiguration.IPAddr.IP.String()
+	}
+
+	return ""
+}
+
+func (m *firecrackerMachine) IPAddresses() []string {
+	return m.ips.Addresses(m.ID())
+}
+
+// Start boots the machine. If it fails to boot, its log file is closed.
+func (m *firecrackerMachine) Start(ctx context.Context) error {
+	if err := m.machine.Start(ctx); err != nil {
+		m.once.Do(func() {
+			if m.logFile != nil {
+				_ = m.logFile.Close()
+			}
+		})
+
+		return err
+	}
+
+	go m.observeBootDuration()
+	return nil
+}
+
+// observeBootDuration records the time from the creation of the machine
+// until the runner agent announces it received its configuration.
+func (m *firecrackerMachine) observeBootDuration() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultBootObserveTimeout)
+	defer cancel()
+
+	go func() {
+		_ = m.machine.Wait(ctx)
+		cancel()
+	}()
+
+	if err := waitForLogLine(ctx, m.logFile.Name(), runner.ConfiguredMarker); err != nil {
+		return
+	}
+
+	metricRunnerBootDuration.WithLabelValues(m.pool, m.bootMode).Observe(time.Since(m.createdAt).Seconds())
+}
+
+// Shutdown sends Ctrl+Alt+Del to the guest.
+func (m *firecrackerMachine) Shutdown(ctx context.Context) error {
+	return m.machine.Shutdown(ctx)
+}
+
+// Stop kills the Firecracker process.
+func (m *firecrackerMachine) Stop() error {
+	if m.process == nil {
+		return m.machine.StopVMM()
+	}
+
+	err := m.process.Signal(syscall.SIGTERM)
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+
+	return nil
+}
+
+// Wait blocks until the Firecracker process exits and closes its log file.
+// Adopted processes are not children of the server, so they are polled.
+func (m *firecrackerMachine) Wait(ctx context.Context) error {
+	var err error
+	if m.process == nil {
+		err = m.machine.Wait(ctx)
+	} else {
+		t := time.NewTicker(1 * time.Second)
+		defer t.Stop()
+
+		for isProcessAlive(m.process.Pid) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+
+	if ctx.Err() == nil {
+		m.once.Do(func() {
+			if m.logFile != nil {
+				_ = m.logFile.Close()
+			}
+		})
+	}
+
+	return err
+}
+
+// isProcessAlive returns true if a process with the given PID exists.
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// isFirecrackerProcess returns true if the process with the given PID is a
+// live Firecracker process serving the API on socketPath, or jailed with the
+// given ID, guarding against PIDs reused by unrelated processes.
+func isFirecrackerProcess(pid int, id, socketPath string) bool {
+	if !isProcessAlive(pid) {
+		return false
+	}
+
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return false
+	}
+
+	// Jailed processes are passed the ID and the socket path in the chroot.
+	return strings.Contains(string(cmdline), socketPath) || strings.Contains(string(cmdline), fmt.Sprintf("--id\x00%s\x00", id))
+}
+
+// findFirecrackerProcess returns the PID of the Firecracker process serving
+// the API on socketPath, or jailed with the given ID, or 0 if there's none.
+func findFirecrackerProcess(id, socketPath string) int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if isFirecrackerProcess(pid, id, socketPath) {
+			return pid
+		}
+	}
+
+	return 0
+}
+
+func init() {
+	_ = log.SetLevel("panic")
+}
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hostinger/fireactions"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ReloadTriggerAPI, ReloadTriggerSignal and ReloadTriggerWatch tell what
+	// triggered a reload of the configuration: the API, a SIGHUP or a change
+	// of the configuration file.
+	ReloadTriggerAPI    = "api"
+	ReloadTriggerSignal = "signal"
+	ReloadTriggerWatch  = "watch"
+
+	// defaultReloadDebounce is how long TriggerReload waits for further
+	// requests before reloading, as editors write files in several steps.
+	defaultReloadDebounce = 1 * time.Second
+)
+
+var (
+	// livePoolConfigKeys are the settings of a pool applied to its running
+	// runners as they are. Changing any other setting replaces the runners.
+	livePoolConfigKeys = []string{
+		"min_runners",
+		"max_runners",
+		"schedules",
+		"priority",
+		"runner.image_pull_policy",
+		"runner.image_refresh_interval",
+	}
+
+	// liveServerConfigKeys are the settings of the server itself applied on
+	// reload.
+	liveServerConfigKeys = []string{
+		"github.webhook_secret",
+		"github.webhook_secret_file",
+	}
+
+	// restartPoolConfigKeys are the settings of a pool read once by its
+	// machine provider, which only take effect after a restart.
+	restartPoolConfigKeys = []string{
+		"network",
+		"firecracker.snapshotter",
+		"firecracker.rootfs_size_mib",
+		"firecracker.jailer",
+	}
+)
+
+// diffConfigs compares the configuration of the server with a new one and
+// returns the changes to the pools. The settings of the server itself, except
+// the webhook secret, are not reloaded and reported as requiring a restart.
+func diffConfigs(old, new *Config) (*fireactions.ReloadResult, error) {
+	result := &fireactions.ReloadResult{
+		Added:           []string{},
+		Removed:         []string{},
+		Changed:         fireactions.PoolChanges{},
+		Unchanged:       []string{},
+		RestartRequired: []string{},
+	}
+
+	oldPools := make(map[string]*PoolConfig, len(old.Pools))
+	for _, pool := range old.Pools {
+		oldPools[pool.Name] = pool
+	}
+
+	newPools := make(map[string]*PoolConfig, len(new.Pools))
+	for _, pool := range new.Pools {
+		newPools[pool.Name] = pool
+
+		oldPool, ok := oldPools[pool.Name]
+		if !ok {
+			result.Added = append(result.Added, pool.Name)
+			continue
+		}
+
+		change, err := diffPoolConfigs(oldPool, pool)
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %w", pool.Name, err)
+		}
+
+		if change == nil {
+			result.Unchanged = append(result.Unchanged, pool.Name)
+			continue
+		}
+
+		result.Changed = append(result.Changed, change)
+	}
+
+	for _, pool := range old.Pools {
+		if _, ok := newPools[pool.Name]; !ok {
+			result.Removed = append(result.Removed, pool.Name)
+		}
+	}
+
+	// The pools of the included files and the templates are compared as
+	// part of the pools.
+	oldServer, newServer := *old, *new
+	oldServer.Pools, newServer.Pools = nil, nil
+	oldServer.Includes, newServer.Includes = nil, nil
+	oldServer.Templates, newServer.Templates = nil, nil
+
+	fields, err := diffKeys(&oldServer, &newServer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		if !matchConfigKey(field, liveServerConfigKeys) {
+			result.RestartRequired = append(result.RestartRequired, field)
+		}
+	}
+
+	return result, nil
+}
+
+// diffPoolConfigs returns the changed settings of a pool, or nil if none
+// changed.
+func diffPoolConfigs(old, new *PoolConfig) (*fireactions.PoolChange, error) {
+	fields, err := diffKeys(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	change := &fireactions.PoolChange{Pool: new.Name, Fields: fields}
+	for _, field := range fields {
+		// The jailer launches the Firecracker binary resolved when the pool
+		// was created.
+		restart := matchConfigKey(field, restartPoolConfigKeys) ||
+			(field == "firecracker.binary_path" && old.Firecracker.IsJailerEnabled())
+
+		switch {
+		case restart:
+			change.RestartRequired = append(change.RestartRequired, field)
+		case !matchConfigKey(field, livePoolConfigKeys):
+			change.Rolled = true
+		}
+	}
+
+	return change, nil
+}
+
+// diffKeys returns the sorted keys, in dotted notation, of the settings which
+// differ between two configurations. Lists are compared as a whole.
+func diffKeys(old, new interface{}) ([]string, error) {
+	oldValues, err := flattenConfig(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newValues, err := flattenConfig(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key, value := range oldValues {
+		if !reflect.DeepEqual(value, newValues[key]) {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// flattenConfig returns the settings of a configuration by their key in
+// dotted notation. Unset and zero settings, which mean the same, are omitted.
+func flattenConfig(config interface{}) (map[string]interface{}, error) {
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling config: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
+	}
+
+	flat := make(map[string]interface{})
+	flattenValues(flat, "", values)
+	return flat, nil
+}
+
+func flattenValues(flat map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flattenValues(flat, key, value)
+		case []interface{}:
+			if len(value) > 0 {
+				flat[key] = value
+			}
+		default:
+			if value != nil && !reflect.ValueOf(value).IsZero() {
+				flat[key] = value
+			}
+		}
+	}
+}
+
+// matchConfigKey returns true if the key is one of keys, or a setting nested
+// in one of them.
+func matchConfigKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchConfigFile triggers a reload when the configuration file or an included
+// file changes, until the context is canceled. The directories of the files
+// are watched, as editors and Kubernetes ConfigMaps replace the files rather
+// than write to them. The watches of the included files follow the include
+// patterns of the configuration after each reload.
+func (s *Server) watchConfigFile(ctx context.Context) error {
+	if s.config.path == "" {
+		return fmt.Errorf("configuration file path is unknown")
+	}
+
+	path, err := filepath.Abs(s.config.path)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	patterns, dirs := s.watchIncludes(watcher)
+	go func() {
+		defer watcher.Close()
+
+		// A ConfigMap links the file to a directory swapped on update.
+		target, _ := filepath.EvalSymlinks(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.reloadedCh:
+				watched := len(watcher.WatchList())
+				patterns, dirs = s.watchIncludes(watcher)
+
+				// The files written to a new directory before it was
+				// watched are loaded by another reload.
+				if len(watcher.WatchList()) > watched {
+					s.TriggerReload(ReloadTriggerWatch)
+				}
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// A created include directory is watched once the reload
+				// loading its files succeeds.
+				current, _ := filepath.EvalSymlinks(path)
+				if (event.Name != path && current == target && !matchPatterns(event.Name, patterns) && !matchPatterns(event.Name, dirs)) || event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				target = current
+				s.logger.Debug().Msgf("Configuration file changed: %s", event)
+				s.TriggerReload(ReloadTriggerWatch)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				s.logger.Error().Err(err).Msg("Failed to watch configuration file")
+			}
+		}
+	}()
+
+	s.logger.Info().Msgf("Watching configuration file %s", path)
+	return nil
+}
+
+// watchIncludes watches the directories of the include patterns of the
+// configuration, and returns the absolute patterns and directories. The
+// directories that don't exist yet are skipped.
+func (s *Server) watchIncludes(watcher *fsnotify.Watcher) ([]string, []string) {
+	s.l.Lock()
+	patterns := s.config.getIncludePatterns()
+	s.l.Unlock()
+
+	dirs := make([]string, 0, len(patterns))
+	for i, pattern := range patterns {
+		if abs, err := filepath.Abs(pattern); err == nil {
+			patterns[i] = abs
+		}
+
+		dir := filepath.Dir(patterns[i])
+		dirs = append(dirs, dir)
+		if err := watcher.Add(dir); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				s.logger.Debug().Msgf("Include directory %s doesn't exist, not watching it", dir)
+				continue
+			}
+
+			s.logger.Error().Err(err).Msgf("Failed to watch include directory %s", dir)
+		}
+	}
+
+	return patterns, dirs
+}
+
+// matchPatterns returns true if the path matches one of the glob patterns.
+func matchPatterns(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+
+	return false
+}
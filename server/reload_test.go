@@ -0,0 +1,410 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	old := newTestConfig(t)
+	old.Pools = append(old.Pools, &PoolConfig{Name: "removed", Runner: &RunnerConfig{}, Firecracker: &FirecrackerConfig{}})
+
+	new := newTestConfig(t)
+	new.BindAddress = "127.0.0.1:9090"
+	new.Pools = append(new.Pools, &PoolConfig{Name: "added", Runner: &RunnerConfig{}, Firecracker: &FirecrackerConfig{}})
+
+	result, err := diffConfigs(old, new)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"added"}, result.Added)
+	assert.Equal(t, []string{"removed"}, result.Removed)
+	assert.Equal(t, []string{"test"}, result.Unchanged)
+	assert.Empty(t, result.Changed)
+	assert.Equal(t, []string{"bind_address"}, result.RestartRequired)
+}
+
+func TestDiffPoolConfigs(t *testing.T) {
+	tests := []struct {
+		name            string
+		update          func(config *PoolConfig)
+		fields          []string
+		rolled          bool
+		restartRequired []string
+	}{
+		{
+			name:   "Unchanged",
+			update: func(config *PoolConfig) {},
+		},
+		{
+			name:   "Live",
+			update: func(config *PoolConfig) { config.MaxRunners = 10; config.Priority = 5 },
+			fields: []string{"max_runners", "priority"},
+		},
+		{
+			name:   "Image",
+			update: func(config *PoolConfig) { config.Runner.Image = "ghcr.io/hostinger/fireactions/runner:latest" },
+			fields: []string{"runner.image"},
+			rolled: true,
+		},
+		{
+			name:   "Kernel",
+			update: func(config *PoolConfig) { config.Firecracker.KernelImagePath = "/var/lib/fireactions/vmlinux" },
+			fields: []string{"firecracker.kernel_image_path"},
+			rolled: true,
+		},
+		{
+			name:   "Metadata",
+			update: func(config *PoolConfig) { config.Firecracker.Metadata = map[string]interface{}{"key": "value"} },
+			fields: []string{"firecracker.metadata.key"},
+			rolled: true,
+		},
+		{
+			name: "Network",
+			update: func(config *PoolConfig) {
+				config.Network = &NetworkConfig{Interfaces: []*NetworkInterfaceConfig{{NetworkName: "fireactions1"}}}
+			},
+			fields:          []string{"network.interfaces"},
+			restartRequired: []string{"network.interfaces"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := newTestConfig(t).Pools[0], newTestConfig(t).Pools[0]
+			tt.update(new)
+
+			change, err := diffPoolConfigs(old, new)
+			assert.NoError(t, err)
+			if tt.fields == nil {
+				assert.Nil(t, change)
+				return
+			}
+
+			assert.Equal(t, tt.fields, change.Fields)
+			assert.Equal(t, tt.rolled, change.Rolled)
+			assert.Equal(t, tt.restartRequired, change.RestartRequired)
+		})
+	}
+}
+
+func TestPool_Update(t *testing.T) {
+	pool, _, removed := newTestPool(t, nil)
+	assert.NoError(t, pool.ScaleTo(context.Background(), 2).Err())
+
+	config := *pool.getConfig()
+	runner := *config.Runner
+	runner.Image = "ghcr.io/hostinger/fireactions/runner:latest"
+	config.Runner = &runner
+	config.MaxRunners = 10
+
+	pool.Update(&config, true)
+	assert.Equal(t, 10, pool.getConfig().MaxRunners)
+
+	pool.replaceOutdatedRunners(context.Background())
+	assert.Eventually(t, func() bool { return pool.GetCurrentSize() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), removed.Load())
+
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+	pool.replaceOutdatedRunners(context.Background())
+	assert.Equal(t, 1, pool.GetCurrentSize())
+	assert.Equal(t, int32(2), removed.Load())
+}
+
+// newTestReloadServer creates a server with the pools of config, loaded from
+// a configuration file rewritten by the returned function. The pools are not
+// started.
+func newTestReloadServer(t *testing.T, config *Config, opts ...Opt) (*Server, func(config *Config)) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(config *Config) {
+		b, err := yaml.Marshal(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(config)
+
+	config, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(config, append(opts, WithMachineProviderFactory(NewFakeProviderFactory()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.github, _ = newTestGitHub(t)
+	for _, poolConfig := range config.Pools {
+		pool, err := s.newPool(context.Background(), poolConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.pools[poolConfig.Name] = pool
+	}
+
+	return s, writeConfig
+}
+
+func TestServer_Reload(t *testing.T) {
+	config := newTestConfig(t)
+	config.Pools = append(config.Pools, &PoolConfig{
+		Name:        "removed",
+		MaxRunners:  1,
+		MinRunners:  1,
+		Runner:      config.Pools[0].Runner,
+		Firecracker: &FirecrackerConfig{},
+	})
+
+	s, writeConfig := newTestReloadServer(t, config)
+	go s.pools["removed"].Start()
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := newTestConfig(t)
+		invalid.Pools = nil
+		writeConfig(invalid)
+
+		_, err := s.Reload(context.Background())
+		assert.Error(t, err)
+		assert.Len(t, s.pools, 2)
+		assert.Len(t, s.config.Pools, 2)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		updated := newTestConfig(t)
+		updated.Pools[0].MaxRunners = 5
+		updated.Pools[0].Runner.Image = "ghcr.io/hostinger/fireactions/runner:latest"
+		updated.Pools = append(updated.Pools, &PoolConfig{
+			Name:        "added",
+			MaxRunners:  1,
+			MinRunners:  1,
+			Runner:      updated.Pools[0].Runner,
+			Firecracker: &FirecrackerConfig{},
+		})
+		writeConfig(updated)
+
+		result, err := s.Reload(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"added"}, result.Added)
+		assert.Equal(t, []string{"removed"}, result.Removed)
+		assert.Equal(t, fireactions.PoolChanges{{Pool: "test", Fields: []string{"max_runners", "runner.image"}, Rolled: true}}, result.Changed)
+		assert.Empty(t, result.RestartRequired)
+
+		pools, err := s.ListPools(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, pools, 2)
+
+		pool, err := s.GetPool(context.Background(), "test")
+		assert.NoError(t, err)
+		assert.Equal(t, 5, pool.getConfig().MaxRunners)
+		assert.Equal(t, 1, pool.generation)
+
+		assert.Eventually(t, func() bool {
+			for _, pool := range s.capacity.Get().Pools {
+				if pool.Pool == "removed" {
+					return false
+				}
+			}
+
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestServer_Reload_ReaddedWhileDraining(t *testing.T) {
+	config := newTestConfig(t)
+	config.Pools = append(config.Pools, &PoolConfig{
+		Name:        "removed",
+		MaxRunners:  1,
+		MinRunners:  1,
+		Runner:      config.Pools[0].Runner,
+		Firecracker: &FirecrackerConfig{},
+	})
+
+	s, writeConfig := newTestReloadServer(t, config)
+	pool := s.pools["removed"]
+	pool.github, _ = newTestGitHubWithBusyRunners(t, map[string]bool{"42": true})
+	assert.NoError(t, pool.ScaleTo(context.Background(), 1).Err())
+
+	removed := newTestConfig(t)
+	writeConfig(removed)
+
+	result, err := s.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed"}, result.Removed)
+
+	// The busy runner keeps the removed pool draining.
+	writeConfig(config)
+	_, err = s.Reload(context.Background())
+	assert.ErrorContains(t, err, "pool removed: still draining")
+	assert.Len(t, s.pools, 1)
+	assert.Len(t, s.config.Pools, 1)
+
+	machine, _ := pool.provider.(*FakeProvider).GetMachine(pool.ListRunners()[0].VMID)
+	machine.Exit()
+
+	assert.Eventually(t, func() bool {
+		result, err := s.Reload(context.Background())
+		return err == nil && assert.ObjectsAreEqual([]string{"removed"}, result.Added)
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.NotSame(t, pool, s.pools["removed"])
+}
+
+func TestServer_TriggerReload(t *testing.T) {
+	s, writeConfig := newTestReloadServer(t, newTestConfig(t))
+	s.reloadDebounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.runReloader(ctx)
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := newTestConfig(t)
+		invalid.Pools[0].MaxRunners = 3
+		invalid.BindAddress = ""
+		writeConfig(invalid)
+
+		failures := testutil.ToFloat64(metricConfigReloads.WithLabelValues(ReloadTriggerSignal, "failure"))
+		s.TriggerReload(ReloadTriggerSignal)
+
+		assert.Eventually(t, func() bool {
+			return testutil.ToFloat64(metricConfigReloads.WithLabelValues(ReloadTriggerSignal, "failure")) > failures
+		}, time.Second, 10*time.Millisecond)
+
+		pool, err := s.GetPool(context.Background(), "test")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, pool.getConfig().MaxRunners)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		updated := newTestConfig(t)
+		updated.Pools[0].MaxRunners = 3
+		writeConfig(updated)
+
+		for i := 0; i < 3; i++ {
+			s.TriggerReload(ReloadTriggerSignal)
+		}
+
+		assert.Eventually(t, func() bool {
+			pool, err := s.GetPool(context.Background(), "test")
+			return err == nil && pool.getConfig().MaxRunners == 3
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestServer_WatchConfigFile(t *testing.T) {
+	s, writeConfig := newTestReloadServer(t, newTestConfig(t), WithConfigWatch())
+	s.reloadDebounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.runReloader(ctx)
+	assert.NoError(t, s.watchConfigFile(ctx))
+
+	updated := newTestConfig(t)
+	updated.Pools[0].MaxRunners = 3
+	writeConfig(updated)
+
+	assert.Eventually(t, func() bool {
+		pool, err := s.GetPool(context.Background(), "test")
+		return err == nil && pool.getConfig().MaxRunners == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_WatchConfigFile_Includes(t *testing.T) {
+	config := newTestConfig(t)
+	config.Includes = []string{"conf.d/*.yaml"}
+	s, _ := newTestReloadServer(t, config, WithConfigWatch())
+	s.reloadDebounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.runReloader(ctx)
+	assert.NoError(t, s.watchConfigFile(ctx))
+
+	dir := filepath.Join(filepath.Dir(s.config.path), "conf.d")
+	writeInclude := func(name string) {
+		pool := *newTestConfig(t).Pools[0]
+		pool.Name = name
+		b, err := yaml.Marshal(map[string]interface{}{"pools": []*PoolConfig{&pool}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The include directory is created after the watch started.
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeInclude("a")
+
+	assert.Eventually(t, func() bool {
+		_, err := s.GetPool(context.Background(), "a")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeInclude("b")
+
+	assert.Eventually(t, func() bool {
+		_, err := s.GetPool(context.Background(), "b")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_Reload_WebhookSecret(t *testing.T) {
+	s, writeConfig := newTestReloadServer(t, newTestConfig(t))
+
+	payload, err := os.ReadFile("testdata/workflow_job_queued.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliver := func(secret string) int {
+		req := httptest.NewRequest("POST", "/webhooks/github", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "workflow_job")
+		req.Header.Set("X-Hub-Signature-256", signWebhookPayload(payload, secret))
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, deliver("secret"))
+
+	updated := newTestConfig(t)
+	updated.GitHub.WebhookSecret = "rotated"
+	writeConfig(updated)
+
+	result, err := s.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, result.RestartRequired)
+
+	assert.Equal(t, http.StatusUnauthorized, deliver("secret"))
+	assert.Equal(t, http.StatusOK, deliver("rotated"))
+
+	updated.GitHub.WebhookSecret = ""
+	writeConfig(updated)
+
+	_, err = s.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, deliver("rotated"))
+}
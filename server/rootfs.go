@@ -0,0 +1,499 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+)
+
+// rootDrives prepares the root drives of the machines of a pool from the
+// runner image, unpacked with a Containerd snapshotter.
+type rootDrives interface {
+	// Create creates the root drive of the machine with the given ID, from
+	// the root drive of the warm boot template if it's not nil or from the
+	// image otherwise, and returns its path on the host.
+	Create(ctx context.Context, id string, image containerd.Image, template *warmBootTemplate) (string, error)
+
+	// CreateTemplate creates the root drive of the warm boot template from
+	// the image and returns its path on the host.
+	CreateTemplate(ctx context.Context, template *warmBootTemplate, image containerd.Image) (string, error)
+
+	// CommitTemplate makes the root drive of the template, once its VM has
+	// stopped, the base of the machines restored from it.
+	CommitTemplate(ctx context.Context, template *warmBootTemplate) error
+
+	// TemplateExists returns true if the root drive of the template exists.
+	TemplateExists(ctx context.Context, template *warmBootTemplate) bool
+
+	// RemoveTemplate releases the root drive of the template with the given
+	// key.
+	RemoveTemplate(ctx context.Context, key string) error
+
+	// List calls add for the root drive of every machine.
+	List(ctx context.Context, add func(id string, createdAt time.Time)) error
+
+	// Remove removes the root drive of the machine with the given ID.
+	Remove(ctx context.Context, id string) error
+}
+
+// newRootDrives returns the root drives matching the snapshotter: block
+// devices of a devmapper thin pool, or ext4 image files flattened from the
+// snapshots of any other snapshotter.
+func newRootDrives(client *containerd.Client, pool, dir string, config *FirecrackerConfig) rootDrives {
+	snapshotter := config.GetSnapshotter()
+	if snapshotter == SnapshotterDevmapper {
+		return &blockRootDrives{pool: pool, dir: dir, snapshotter: snapshotter, containerd: client}
+	}
+
+	return &imageFileRootDrives{dir: dir, snapshotter: snapshotter, sizeMib: config.GetRootfsSizeMib(), containerd: client, l: &sync.Mutex{}}
+}
+
+// containerdClient is the part of the Containerd client used by the block
+// root drives.
+type containerdClient interface {
+	SnapshotService(snapshotterName string) snapshots.Snapshotter
+	LeasesService() leases.Manager
+	WithLease(ctx context.Context, opts ...leases.Opt) (context.Context, func(context.Context) error, error)
+}
+
+// blockRootDrives uses the active snapshots of a block device snapshotter
+// (devmapper) as root drives, each held by a Containerd lease.
+//
+// Committing the root drive of a warm boot template deactivates its device,
+// while the snapshot of the template VM still refers to it, and Firecracker
+// opens it when loading the snapshot, before the drive is swapped for the
+// machine's own. The template VM is therefore given a symbolic link to its
+// device, which is pointed at an active snapshot of the committed drive once
+// the template is built.
+type blockRootDrives struct {
+	pool        string
+	dir         string
+	snapshotter string
+	containerd  containerdClient
+}
+
+func (d *blockRootDrives) Create(ctx context.Context, id string, image containerd.Image, template *warmBootTemplate) (string, error) {
+	leaseCtx, _, err := d.containerd.WithLease(ctx, leases.WithID(d.getLeaseID(id)))
+	if err != nil {
+		return "", fmt.Errorf("containerd: creating lease: %w", err)
+	}
+
+	var parent string
+	if template != nil {
+		parent = d.getTemplateSnapshot(template.key)
+	} else {
+		parent, err = getImageChainID(ctx, image, d.snapshotter)
+		if err != nil {
+			return "", fmt.Errorf("containerd: %w", err)
+		}
+	}
+
+	mounts, err := d.createSnapshot(leaseCtx, id, parent)
+	if err != nil {
+		return "", fmt.Errorf("containerd: creating snapshot: %w", err)
+	}
+
+	return mounts[0].Source, nil
+}
+
+func (d *blockRootDrives) CreateTemplate(ctx context.Context, template *warmBootTemplate, image containerd.Image) (string, error) {
+	leaseCtx, _, err := d.containerd.WithLease(ctx, leases.WithID(d.getTemplateLeaseID(template.key)))
+	if err != nil {
+		return "", fmt.Errorf("containerd: creating lease: %w", err)
+	}
+
+	chainID, err := getImageChainID(ctx, image, d.snapshotter)
+	if err != nil {
+		return "", fmt.Errorf("containerd: %w", err)
+	}
+
+	mounts, err := d.createSnapshot(leaseCtx, d.getTemplateSnapshot(template.key)+"-active", chainID)
+	if err != nil {
+		return "", fmt.Errorf("containerd: creating snapshot: %w", err)
+	}
+
+	path := d.getTemplatePath(template.key)
+	if err := linkTemplateDrive(mounts[0].Source, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// CommitTemplate commits the root drive of the template, which deactivates
+// its device, and points the link given to the template VM at an active
+// snapshot of it, so that the machines restored from the template find a
+// device there.
+func (d *blockRootDrives) CommitTemplate(ctx context.Context, template *warmBootTemplate) error {
+	name := d.getTemplateSnapshot(template.key)
+	leaseCtx := leases.WithLease(ctx, d.getTemplateLeaseID(template.key))
+	if err := d.containerd.SnapshotService(d.snapshotter).Commit(leaseCtx, name, name+"-active"); err != nil {
+		return fmt.Errorf("containerd: committing snapshot: %w", err)
+	}
+
+	mounts, err := d.createSnapshot(leaseCtx, name+"-restore", name)
+	if err != nil {
+		return fmt.Errorf("containerd: creating snapshot: %w", err)
+	}
+
+	return linkTemplateDrive(mounts[0].Source, d.getTemplatePath(template.key))
+}
+
+func (d *blockRootDrives) TemplateExists(ctx context.Context, template *warmBootTemplate) bool {
+	snapshotService := d.containerd.SnapshotService(d.snapshotter)
+	info, err := snapshotService.Stat(ctx, d.getTemplateSnapshot(template.key))
+	if err != nil || info.Kind != snapshots.KindCommitted {
+		return false
+	}
+
+	// Templates built before the restore snapshot was introduced are rebuilt.
+	if _, err := snapshotService.Stat(ctx, d.getTemplateSnapshot(template.key)+"-restore"); err != nil {
+		return false
+	}
+
+	_, err = os.Stat(d.getTemplatePath(template.key))
+	return err == nil
+}
+
+// RemoveTemplate releases the lease of the template snapshot, which is
+// removed by Containerd once no machine is based on it anymore.
+func (d *blockRootDrives) RemoveTemplate(ctx context.Context, key string) error {
+	err := d.containerd.LeasesService().Delete(ctx, leases.Lease{ID: d.getTemplateLeaseID(key)})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("containerd: removing lease: %w", err)
+	}
+
+	return nil
+}
+
+// List calls add for the machines holding a Containerd lease or an active
+// snapshot.
+func (d *blockRootDrives) List(ctx context.Context, add func(id string, createdAt time.Time)) error {
+	leaseList, err := d.containerd.LeasesService().List(ctx)
+	if err != nil {
+		return fmt.Errorf("containerd: listing leases: %w", err)
+	}
+
+	for _, lease := range leaseList {
+		if id, ok := strings.CutPrefix(lease.ID, d.getLeaseID("")); ok {
+			add(id, lease.CreatedAt)
+		}
+	}
+
+	err = d.containerd.SnapshotService(d.snapshotter).Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+		// Committed snapshots are image layers and are left to Containerd,
+		// while active "extract-" snapshots belong to images being unpacked
+		// and "template-" ones to warm boot templates being built.
+		if info.Kind == snapshots.KindActive && !strings.HasPrefix(info.Name, "extract-") && !strings.HasPrefix(info.Name, templatePrefix) {
+			add(info.Name, info.Created)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("containerd: listing snapshots: %w", err)
+	}
+
+	return nil
+}
+
+// Remove removes the Containerd snapshot and lease of the machine.
+func (d *blockRootDrives) Remove(ctx context.Context, id string) error {
+	var errs []error
+
+	err := d.containerd.SnapshotService(d.snapshotter).Remove(ctx, id)
+	if err != nil && !errdefs.IsNotFound(err) {
+		errs = append(errs, fmt.Errorf("containerd: removing snapshot: %w", err))
+	}
+
+	err = d.containerd.LeasesService().Delete(ctx, leases.Lease{ID: d.getLeaseID(id)})
+	if err != nil && !errdefs.IsNotFound(err) {
+		errs = append(errs, fmt.Errorf(`containerd: removing lease: %w.
+Run 'ctr --namespace %s leases rm %s' to remove the lease manually`, err, d.pool, d.getLeaseID(id)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// getLeaseID returns the ID of the Containerd lease holding the resources of
+// the machine with the given ID.
+func (d *blockRootDrives) getLeaseID(id string) string {
+	return fmt.Sprintf("fireactions/pools/%s/%s", d.pool, id)
+}
+
+// getTemplateLeaseID returns the ID of the Containerd lease holding the root
+// drive snapshot of the warm boot template. It's outside of the machine
+// leases, so that the garbage collector leaves it alone.
+func (d *blockRootDrives) getTemplateLeaseID(key string) string {
+	return fmt.Sprintf("fireactions/templates/%s/%s", d.pool, key)
+}
+
+func (d *blockRootDrives) getTemplateSnapshot(key string) string {
+	return templatePrefix + key
+}
+
+// getTemplatePath returns the path of the link to the root drive of the
+// template, which is the path its snapshot refers to.
+func (d *blockRootDrives) getTemplatePath(key string) string {
+	return filepath.Join(d.dir, "templates", key, "rootfs")
+}
+
+// linkTemplateDrive atomically points the link at path to the device at src.
+func linkTemplateDrive(src, path string) error {
+	tmpPath := path + ".tmp"
+	_ = os.Remove(tmpPath)
+	if err := os.Symlink(src, tmpPath); err != nil {
+		return fmt.Errorf("linking root drive: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("linking root drive: %w", err)
+	}
+
+	return nil
+}
+
+// createSnapshot prepares the active snapshot with the given ID on top of
+// parent, unless it exists already, and returns its mounts.
+func (d *blockRootDrives) createSnapshot(ctx context.Context, snapshotID, parent string) ([]mount.Mount, error) {
+	snapshotService := d.containerd.SnapshotService(d.snapshotter)
+	_, err := snapshotService.Stat(ctx, snapshotID)
+	if err != nil {
+		if !errdefs.IsNotFound(err) {
+			return nil, err
+		}
+
+		_, err = snapshotService.Prepare(ctx, snapshotID, parent)
+		if err != nil {
+			return nil, fmt.Errorf("prepare: %w", err)
+		}
+	}
+
+	mounts, err := snapshotService.Mounts(ctx, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("mounts: %w", err)
+	}
+
+	return mounts, nil
+}
+
+// imageFileRootDrives uses copies of an ext4 image file as root drives. The
+// image file is flattened once per image from a view of its snapshot, which
+// works with any snapshotter, and copied sparsely (or with reflinks, if the
+// filesystem supports them) for every machine.
+type imageFileRootDrives struct {
+	dir         string
+	snapshotter string
+	sizeMib     int64
+	containerd  *containerd.Client
+	l           *sync.Mutex
+}
+
+func (d *imageFileRootDrives) Create(ctx context.Context, id string, image containerd.Image, template *warmBootTemplate) (string, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	var src string
+	if template != nil {
+		src = d.getTemplatePath(template.key)
+	} else {
+		var err error
+		src, err = d.getBaseImage(ctx, image)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	dst := filepath.Join(d.dir, fmt.Sprintf("%s.ext4", id))
+	if err := copyImageFile(ctx, src, dst); err != nil {
+		return "", fmt.Errorf("copying root drive: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (d *imageFileRootDrives) CreateTemplate(ctx context.Context, template *warmBootTemplate, image containerd.Image) (string, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	src, err := d.getBaseImage(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	dst := d.getTemplatePath(template.key)
+	if err := copyImageFile(ctx, src, dst); err != nil {
+		return "", fmt.Errorf("copying root drive: %w", err)
+	}
+
+	return dst, nil
+}
+
+// CommitTemplate does nothing, the root drive of the template is copied as is.
+func (d *imageFileRootDrives) CommitTemplate(ctx context.Context, template *warmBootTemplate) error {
+	return nil
+}
+
+func (d *imageFileRootDrives) TemplateExists(ctx context.Context, template *warmBootTemplate) bool {
+	_, err := os.Stat(d.getTemplatePath(template.key))
+	return err == nil
+}
+
+// RemoveTemplate does nothing, the root drive is removed with the template
+// directory.
+func (d *imageFileRootDrives) RemoveTemplate(ctx context.Context, key string) error {
+	return nil
+}
+
+// List calls add for the image files of the machines.
+func (d *imageFileRootDrives) List(ctx context.Context, add func(id string, createdAt time.Time)) error {
+	paths, err := filepath.Glob(filepath.Join(d.dir, "*.ext4"))
+	if err != nil {
+		return fmt.Errorf("listing root drives: %w", err)
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		add(strings.TrimSuffix(filepath.Base(path), ".ext4"), info.ModTime())
+	}
+
+	return nil
+}
+
+// Remove removes the image file of the machine.
+func (d *imageFileRootDrives) Remove(ctx context.Context, id string) error {
+	err := os.Remove(filepath.Join(d.dir, fmt.Sprintf("%s.ext4", id)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing root drive: %w", err)
+	}
+
+	return nil
+}
+
+func (d *imageFileRootDrives) getTemplatePath(key string) string {
+	return filepath.Join(d.dir, "templates", key, "rootfs.ext4")
+}
+
+// getBaseImage returns the path of the ext4 image file flattened from the
+// image, creating it if it doesn't exist yet. Image files of previous images
+// are removed once a new one is created.
+func (d *imageFileRootDrives) getBaseImage(ctx context.Context, image containerd.Image) (string, error) {
+	chainID, err := getImageChainID(ctx, image, d.snapshotter)
+	if err != nil {
+		return "", fmt.Errorf("containerd: %w", err)
+	}
+
+	dir := filepath.Join(d.dir, "images")
+	path := filepath.Join(dir, fmt.Sprintf("%s.ext4", digest.Digest(chainID).Encoded()[:12]))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating images directory: %w", err)
+	}
+
+	if err := d.flatten(ctx, chainID, path); err != nil {
+		return "", fmt.Errorf("flattening image: %w", err)
+	}
+
+	stale, _ := filepath.Glob(filepath.Join(dir, "*.ext4"))
+	for _, stalePath := range stale {
+		if stalePath != path {
+			_ = os.Remove(stalePath)
+		}
+	}
+
+	return path, nil
+}
+
+// flatten creates an ext4 image file of d.sizeMib at path, populated with the
+// content of a read-only view of the snapshot with the given chain ID.
+func (d *imageFileRootDrives) flatten(ctx context.Context, chainID, path string) error {
+	leaseCtx, done, err := d.containerd.WithLease(ctx, leases.WithRandomID(), leases.WithExpiration(1*time.Hour))
+	if err != nil {
+		return fmt.Errorf("containerd: creating lease: %w", err)
+	}
+	defer done(context.Background())
+
+	snapshotService := d.containerd.SnapshotService(d.snapshotter)
+	key := fmt.Sprintf("flatten-%s", filepath.Base(path))
+	mounts, err := snapshotService.View(leaseCtx, key, chainID)
+	if err != nil {
+		return fmt.Errorf("containerd: creating view: %w", err)
+	}
+	defer snapshotService.Remove(context.Background(), key)
+
+	target, err := os.MkdirTemp("", "fireactions-rootfs-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(target)
+
+	if err := mount.All(mounts, target); err != nil {
+		return fmt.Errorf("mounting view: %w", err)
+	}
+	defer mount.UnmountAll(target, 0)
+
+	tmpPath := path + ".tmp"
+	output, err := exec.CommandContext(ctx, "mkfs.ext4", "-q", "-F", "-d", target, tmpPath, fmt.Sprintf("%dM", d.sizeMib)).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("mkfs.ext4: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// copyImageFile copies the image file at src to dst, keeping it sparse and
+// sharing its blocks if the filesystem supports reflinks.
+func copyImageFile(ctx context.Context, src, dst string) error {
+	output, err := exec.CommandContext(ctx, "cp", "--sparse=always", "--reflink=auto", src, dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cp: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// getImageChainID unpacks the image with the snapshotter and returns the
+// chain ID of its root filesystem, which is the parent of the runner
+// snapshots.
+func getImageChainID(ctx context.Context, image containerd.Image, snapshotter string) (string, error) {
+	isUnpacked, err := image.IsUnpacked(ctx, snapshotter)
+	if err != nil {
+		return "", fmt.Errorf("unpack: %w", err)
+	}
+
+	if !isUnpacked {
+		if err := image.Unpack(ctx, snapshotter); err != nil {
+			return "", fmt.Errorf("unpack: %w", err)
+		}
+	}
+
+	imageContent, err := image.RootFS(ctx)
+	if err != nil {
+		return "", fmt.Errorf("image: rootfs: %w", err)
+	}
+
+	return identity.ChainID(imageContent).String(), nil
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageFileRootDrives(t *testing.T) {
+	dir := t.TempDir()
+	drives := newRootDrives(nil, "test", dir, &FirecrackerConfig{Snapshotter: SnapshotterOverlayfs})
+
+	src := filepath.Join(dir, "templates", "abc", "rootfs.ext4")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(src), 0755))
+	assert.NoError(t, os.WriteFile(src, []byte("rootfs"), 0644))
+
+	template := &warmBootTemplate{key: "abc"}
+	assert.True(t, drives.TemplateExists(context.Background(), template))
+	assert.False(t, drives.TemplateExists(context.Background(), &warmBootTemplate{key: "def"}))
+
+	for _, id := range []string{"test-1", "test-2"} {
+		path, err := drives.Create(context.Background(), id, nil, template)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, id+".ext4"), path)
+		assert.FileExists(t, path)
+	}
+
+	ids := []string{}
+	assert.NoError(t, drives.List(context.Background(), func(id string, createdAt time.Time) { ids = append(ids, id) }))
+	sort.Strings(ids)
+	assert.Equal(t, []string{"test-1", "test-2"}, ids)
+
+	assert.NoError(t, drives.Remove(context.Background(), "test-1"))
+	assert.NoError(t, drives.Remove(context.Background(), "test-1"))
+	assert.NoFileExists(t, filepath.Join(dir, "test-1.ext4"))
+}
+
+func TestNewRootDrives(t *testing.T) {
+	assert.IsType(t, &blockRootDrives{}, newRootDrives(nil, "test", t.TempDir(), &FirecrackerConfig{}))
+	assert.IsType(t, &imageFileRootDrives{}, newRootDrives(nil, "test", t.TempDir(), &FirecrackerConfig{Snapshotter: SnapshotterErofs}))
+}
+
+// fakeDevmapper mimics the devmapper snapshotter: active snapshots are backed
+// by a device file, which is removed when the snapshot is committed.
+type fakeDevmapper struct {
+	snapshots.Snapshotter
+
+	dir       string
+	snapshots map[string]snapshots.Info
+	devices   map[string]string
+}
+
+func newFakeDevmapper(dir string, committed ...string) *fakeDevmapper {
+	s := &fakeDevmapper{dir: dir, snapshots: make(map[string]snapshots.Info), devices: make(map[string]string)}
+	for _, name := range committed {
+		s.snapshots[name] = snapshots.Info{Kind: snapshots.KindCommitted, Name: name}
+	}
+
+	return s
+}
+
+func (s *fakeDevmapper) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	info, ok := s.snapshots[key]
+	if !ok {
+		return snapshots.Info{}, fmt.Errorf("snapshot %s: %w", key, errdefs.ErrNotFound)
+	}
+
+	return info, nil
+}
+
+func (s *fakeDevmapper) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	if info, ok := s.snapshots[parent]; !ok || info.Kind != snapshots.KindCommitted {
+		return nil, fmt.Errorf("parent %s: %w", parent, errdefs.ErrNotFound)
+	}
+
+	device := filepath.Join(s.dir, fmt.Sprintf("test-snap-%d", len(s.devices)+1))
+	if err := os.WriteFile(device, nil, 0600); err != nil {
+		return nil, err
+	}
+
+	s.snapshots[key] = snapshots.Info{Kind: snapshots.KindActive, Name: key, Parent: parent}
+	s.devices[key] = device
+	return s.Mounts(ctx, key)
+}
+
+func (s *fakeDevmapper) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	device, ok := s.devices[key]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s: %w", key, errdefs.ErrNotFound)
+	}
+
+	return []mount.Mount{{Type: "ext4", Source: device}}, nil
+}
+
+func (s *fakeDevmapper) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
+	info, ok := s.snapshots[key]
+	if !ok || info.Kind != snapshots.KindActive {
+		return fmt.Errorf("snapshot %s: %w", key, errdefs.ErrNotFound)
+	}
+
+	// The device of the committed snapshot is deactivated.
+	if err := os.Remove(s.devices[key]); err != nil {
+		return err
+	}
+
+	delete(s.snapshots, key)
+	delete(s.devices, key)
+	s.snapshots[name] = snapshots.Info{Kind: snapshots.KindCommitted, Name: name, Parent: info.Parent}
+	return nil
+}
+
+func (s *fakeDevmapper) Walk(ctx context.Context, fn snapshots.WalkFunc, filters ...string) error {
+	for _, info := range s.snapshots {
+		if err := fn(ctx, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fakeLeases is a lease manager without leases.
+type fakeLeases struct {
+	leases.Manager
+}
+
+func (fakeLeases) List(context.Context, ...string) ([]leases.Lease, error) {
+	return nil, nil
+}
+
+type fakeContainerdClient struct {
+	snapshotter snapshots.Snapshotter
+}
+
+func (c *fakeContainerdClient) SnapshotService(string) snapshots.Snapshotter {
+	return c.snapshotter
+}
+
+func (c *fakeContainerdClient) LeasesService() leases.Manager {
+	return fakeLeases{}
+}
+
+func (c *fakeContainerdClient) WithLease(ctx context.Context, opts ...leases.Opt) (context.Context, func(context.Context) error, error) {
+	var lease leases.Lease
+	for _, opt := range opts {
+		if err := opt(&lease); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return leases.WithLease(ctx, lease.ID), func(context.Context) error { return nil }, nil
+}
+
+// fakeImage is an unpacked image with a single layer.
+type fakeImage struct {
+	containerd.Image
+
+	layer digest.Digest
+}
+
+func (i *fakeImage) IsUnpacked(context.Context, string) (bool, error) {
+	return true, nil
+}
+
+func (i *fakeImage) RootFS(context.Context) ([]digest.Digest, error) {
+	return []digest.Digest{i.layer}, nil
+}
+
+func TestBlockRootDrives_WarmBootTemplate(t *testing.T) {
+	dir := t.TempDir()
+	image := &fakeImage{layer: digest.FromString("rootfs")}
+	snapshotter := newFakeDevmapper(t.TempDir(), image.layer.String())
+	drives := &blockRootDrives{pool: "test", dir: dir, snapshotter: SnapshotterDevmapper, containerd: &fakeContainerdClient{snapshotter: snapshotter}}
+
+	template := &warmBootTemplate{key: "abc"}
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "templates", "abc"), 0755))
+
+	// The template VM is given the path that its snapshot refers to.
+	templatePath, err := drives.CreateTemplate(context.Background(), template, image)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "templates", "abc", "rootfs"), templatePath)
+	_, err = os.Stat(templatePath)
+	assert.NoError(t, err)
+	assert.False(t, drives.TemplateExists(context.Background(), template))
+
+	assert.NoError(t, drives.CommitTemplate(context.Background(), template))
+	assert.True(t, drives.TemplateExists(context.Background(), template))
+
+	// Restoring a machine opens the drive at the path of the snapshot before
+	// swapping it for the machine's own.
+	for _, id := range []string{"test-1", "test-2"} {
+		path, err := drives.Create(context.Background(), id, nil, template)
+		assert.NoError(t, err)
+		assert.FileExists(t, path)
+		assert.Equal(t, "template-abc", snapshotter.snapshots[id].Parent)
+
+		_, err = os.Stat(templatePath)
+		assert.NoError(t, err)
+	}
+
+	// The snapshot of the committed drive isn't a machine.
+	ids := []string{}
+	assert.NoError(t, drives.List(context.Background(), func(id string, createdAt time.Time) { ids = append(ids, id) }))
+	sort.Strings(ids)
+	assert.Equal(t, []string{"test-1", "test-2"}, ids)
+}
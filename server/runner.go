@@ -0,0 +1,120 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hostinger/fireactions"
+)
+
+// Runner represents a single virtual machine that runs a GitHub runner.
+type Runner struct {
+	Name       string
+	Pool       string
+	VMID       string
+	SocketPath string
+	LogPath    string
+	IPAddress  string
+	StartedAt  time.Time
+
+	machine     Machine
+	githubID    int64
+	ipAddresses []string
+
+	// generation is the generation of the pool configuration the runner
+	// was created with.
+	generation int
+	state      fireactions.RunnerState
+	doneCh     chan struct{}
+	l          *sync.Mutex
+
+	// onStateChange is called after the state of the Runner changes.
+	onStateChange func(r *Runner)
+}
+
+func newRunner(name, pool, socketPath, logPath string) *Runner {
+	r := &Runner{
+		Name:       name,
+		Pool:       pool,
+		VMID:       name,
+		SocketPath: socketPath,
+		LogPath:    logPath,
+		StartedAt:  time.Now(),
+		state:      fireactions.RunnerStateStarting,
+		doneCh:     make(chan struct{}),
+		l:          &sync.Mutex{},
+	}
+
+	return r
+}
+
+// GetState returns the current state of the Runner.
+func (r *Runner) GetState() fireactions.RunnerState {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return r.state
+}
+
+// SetState sets the current state of the Runner. Exiting is a terminal state
+// and can't be changed.
+func (r *Runner) SetState(state fireactions.RunnerState) {
+	r.l.Lock()
+
+	if r.state == fireactions.RunnerStateExiting || r.state == state {
+		r.l.Unlock()
+		return
+	}
+
+	r.state = state
+	r.l.Unlock()
+
+	if r.onStateChange != nil {
+		r.onStateChange(r)
+	}
+}
+
+// GetIPAddress returns the primary IP address of the Runner, which is empty
+// until its machine has started.
+func (r *Runner) GetIPAddress() string {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return r.IPAddress
+}
+
+// setIPAddresses sets the primary IP address and the addresses of all the
+// interfaces of the Runner, once its machine has started.
+func (r *Runner) setIPAddresses(ipAddress string, ipAddresses []string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.IPAddress = ipAddress
+	r.ipAddresses = ipAddresses
+}
+
+// record returns the persisted state of the Runner.
+func (r *Runner) record() *RunnerRecord {
+	r.l.Lock()
+	ipAddress, ipAddresses := r.IPAddress, r.ipAddresses
+	r.l.Unlock()
+
+	record := &RunnerRecord{
+		Name:        r.Name,
+		Pool:        r.Pool,
+		VMID:        r.VMID,
+		SocketPath:  r.SocketPath,
+		LogPath:     r.LogPath,
+		IPAddress:   ipAddress,
+		IPAddresses: ipAddresses,
+		GitHubID:    r.githubID,
+		State:       r.GetState(),
+		StartedAt:   r.StartedAt,
+	}
+
+	if r.machine != nil {
+		record.PID = r.machine.PID()
+	}
+
+	return record
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultProfile is the name of the profile in effect when none of the pool
+// schedules match.
+const defaultProfile = "default"
+
+// ScheduleConfig overrides the minimum and maximum number of runners of a pool
+// while the current time matches a cron expression. The expression is
+// evaluated every minute, so "* 8-19 * * 1-5" is in effect on weekdays from
+// 08:00 to 20:00.
+type ScheduleConfig struct {
+	Name       string `yaml:"name" validate:"required"`
+	Cron       string `yaml:"cron" validate:"required"`
+	Timezone   string `yaml:"timezone" validate:""`
+	MinRunners *int   `yaml:"min_runners" validate:"omitempty,min=0"`
+	MaxRunners *int   `yaml:"max_runners" validate:"omitempty,min=1"`
+
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// Validate parses the cron expression and the time zone of the schedule.
+func (c *ScheduleConfig) Validate() error {
+	schedule, err := cron.ParseStandard(c.Cron)
+	if err != nil {
+		return fmt.Errorf("parsing cron %q: %w", c.Cron, err)
+	}
+
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("loading timezone %q: %w", c.Timezone, err)
+	}
+
+	c.schedule = schedule
+	c.location = location
+	return nil
+}
+
+// Matches returns true if the minute of t matches the cron expression, in the
+// time zone of the schedule (UTC if not set). A schedule that wasn't validated
+// never matches.
+func (c *ScheduleConfig) Matches(t time.Time) bool {
+	if c.schedule == nil {
+		return false
+	}
+
+	minute := t.In(c.location).Truncate(time.Minute)
+	return c.schedule.Next(minute.Add(-time.Second)).Equal(minute)
+}
+
+// Profile is the minimum and maximum number of runners of a pool in effect at
+// a given time.
+type Profile struct {
+	Name       string
+	MinRunners int
+	MaxRunners int
+}
+
+// GetProfile returns the profile in effect at t: the first matching schedule
+// overrides the pool minimum and maximum number of runners, otherwise the pool
+// defaults apply.
+func (c *PoolConfig) GetProfile(t time.Time) Profile {
+	profile := Profile{Name: defaultProfile, MinRunners: c.MinRunners, MaxRunners: c.MaxRunners}
+	for _, schedule := range c.Schedules {
+		if !schedule.Matches(t) {
+			continue
+		}
+
+		profile.Name = schedule.Name
+		if schedule.MinRunners != nil {
+			profile.MinRunners = *schedule.MinRunners
+		}
+
+		if schedule.MaxRunners != nil {
+			profile.MaxRunners = *schedule.MaxRunners
+		}
+
+		break
+	}
+
+	return profile
+}
+
+// validateSchedules validates the pool schedules and makes sure that each
+// profile has a minimum that doesn't exceed its maximum.
+func (c *PoolConfig) validateSchedules() error {
+	names := make(map[string]struct{}, len(c.Schedules))
+	for _, schedule := range c.Schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf("schedule name is required")
+		}
+
+		if schedule.Name == defaultProfile {
+			return fmt.Errorf("schedule name %q is reserved", defaultProfile)
+		}
+
+		if _, ok := names[schedule.Name]; ok {
+			return fmt.Errorf("duplicate schedule %q", schedule.Name)
+		}
+		names[schedule.Name] = struct{}{}
+
+		if err := schedule.Validate(); err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+
+		minRunners, maxRunners := c.MinRunners, c.MaxRunners
+		if schedule.MinRunners != nil {
+			minRunners = *schedule.MinRunners
+		}
+
+		if schedule.MaxRunners != nil {
+			maxRunners = *schedule.MaxRunners
+		}
+
+		if minRunners < 0 || maxRunners < 1 {
+			return fmt.Errorf("schedule %s: min_runners must not be negative and max_runners must be at least 1", schedule.Name)
+		}
+
+		if minRunners > maxRunners {
+			return fmt.Errorf("schedule %s: min_runners (%d) must not exceed max_runners (%d)", schedule.Name, minRunners, maxRunners)
+		}
+	}
+
+	return nil
+}
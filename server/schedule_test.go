@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleConfig_Matches(t *testing.T) {
+	schedule := &ScheduleConfig{Name: "business-hours", Cron: "* 8-19 * * 1-5", Timezone: "Europe/Vilnius"}
+	assert.NoError(t, schedule.Validate())
+
+	// Monday, 2024-06-03. Vilnius is UTC+3 in summer.
+	assert.False(t, schedule.Matches(time.Date(2024, 6, 3, 4, 59, 59, 0, time.UTC)))
+	assert.True(t, schedule.Matches(time.Date(2024, 6, 3, 5, 0, 0, 0, time.UTC)))
+	assert.True(t, schedule.Matches(time.Date(2024, 6, 3, 16, 59, 30, 0, time.UTC)))
+	assert.False(t, schedule.Matches(time.Date(2024, 6, 3, 17, 0, 0, 0, time.UTC)))
+
+	// Saturday, 2024-06-08.
+	assert.False(t, schedule.Matches(time.Date(2024, 6, 8, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestScheduleConfig_Matches_NotValidated(t *testing.T) {
+	schedule := &ScheduleConfig{Name: "always", Cron: "* * * * *"}
+	assert.False(t, schedule.Matches(time.Now()))
+}
+
+func TestPoolConfig_GetProfile(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := config.Pools[1]
+
+	profile := pool.GetProfile(time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, Profile{Name: "business-hours", MinRunners: 20, MaxRunners: 20}, profile)
+
+	profile = pool.GetProfile(time.Date(2024, 6, 8, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, Profile{Name: defaultProfile, MinRunners: 10, MaxRunners: 20}, profile)
+
+	profile = config.Pools[0].GetProfile(time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, Profile{Name: defaultProfile, MinRunners: 10, MaxRunners: 20}, profile)
+}
+
+func TestConfig_Validate_Schedules(t *testing.T) {
+	config, err := NewConfig("testdata/config1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minRunners := 30
+	tests := []struct {
+		name     string
+		schedule *ScheduleConfig
+	}{
+		{name: "InvalidCron", schedule: &ScheduleConfig{Name: "test", Cron: "every day"}},
+		{name: "InvalidTimezone", schedule: &ScheduleConfig{Name: "test", Cron: "* * * * *", Timezone: "Mars/Olympus"}},
+		{name: "ReservedName", schedule: &ScheduleConfig{Name: defaultProfile, Cron: "* * * * *"}},
+		{name: "MinExceedsMax", schedule: &ScheduleConfig{Name: "test", Cron: "* * * * *", MinRunners: &minRunners}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Pools[0].Schedules = []*ScheduleConfig{tt.schedule}
+			assert.Error(t, config.Validate())
+		})
+	}
+
+	config.Pools[0].Schedules = []*ScheduleConfig{
+		{Name: "test", Cron: "* * * * *"},
+		{Name: "test", Cron: "* * * * *"},
+	}
+	assert.Error(t, config.Validate())
+}
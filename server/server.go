@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,11 +23,24 @@ import (
 type Server struct {
 	config        *Config
 	pools         map[string]*Pool
+	removing      map[string]struct{}
 	server        *http.Server
 	metricsServer *http.Server
 	github        *github.Client
+	store         *Store
+	capacity      *Capacity
+	events        *EventBus
+	notifier      *Notifier
 	l             *sync.Mutex
+	reloadMu      *sync.Mutex
 	logger        *zerolog.Logger
+	drainTimeout  time.Duration
+	newProvider   MachineProviderFactory
+
+	reloadCh       chan string
+	reloadedCh     chan struct{}
+	reloadDebounce time.Duration
+	watchConfig    bool
 }
 
 // Opt is a functional option for Server.
@@ -41,6 +55,37 @@ func WithLogger(logger *zerolog.Logger) Opt {
 	return f
 }
 
+// WithDrainTimeout sets how long the pools are given to drain their runners
+// on shutdown before the remaining ones are stopped forcefully. Zero, the
+// default, stops all runners immediately.
+func WithDrainTimeout(timeout time.Duration) Opt {
+	f := func(s *Server) {
+		s.drainTimeout = timeout
+	}
+
+	return f
+}
+
+// WithConfigWatch reloads the configuration when the configuration file or
+// an included file changes.
+func WithConfigWatch() Opt {
+	f := func(s *Server) {
+		s.watchConfig = true
+	}
+
+	return f
+}
+
+// WithMachineProviderFactory sets the factory creating the machine provider of
+// each pool. Defaults to NewFirecrackerProvider.
+func WithMachineProviderFactory(factory MachineProviderFactory) Opt {
+	f := func(s *Server) {
+		s.newProvider = factory
+	}
+
+	return f
+}
+
 // New creates a new Server.
 func New(config *Config, opts ...Opt) (*Server, error) {
 	err := config.Validate()
@@ -53,6 +98,11 @@ func New(config *Config, opts ...Opt) (*Server, error) {
 		return nil, fmt.Errorf("creating github client: %w", err)
 	}
 
+	capacity, err := NewCapacity(config.Capacity)
+	if err != nil {
+		return nil, fmt.Errorf("capacity: %w", err)
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	handler := gin.New()
 	handler.Use(requestid.New(requestid.WithCustomHeaderStrKey("X-Request-ID")))
@@ -68,18 +118,34 @@ func New(config *Config, opts ...Opt) (*Server, error) {
 
 	logger := zerolog.Nop()
 	s := &Server{
-		config: config,
-		server: server,
-		pools:  make(map[string]*Pool),
-		github: github,
-		l:      &sync.Mutex{},
-		logger: &logger,
+		config:      config,
+		server:      server,
+		pools:       make(map[string]*Pool),
+		removing:    make(map[string]struct{}),
+		github:      github,
+		capacity:    capacity,
+		events:      NewEventBus(),
+		l:           &sync.Mutex{},
+		reloadMu:    &sync.Mutex{},
+		logger:      &logger,
+		newProvider: NewFirecrackerProvider,
+
+		reloadCh:       make(chan string, 1),
+		reloadedCh:     make(chan struct{}, 1),
+		reloadDebounce: defaultReloadDebounce,
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	if config.Notifications != nil && len(config.Notifications.Sinks) > 0 {
+		s.notifier, err = NewNotifier(s.logger, config.Notifications)
+		if err != nil {
+			return nil, fmt.Errorf("notifications: %w", err)
+		}
+	}
+
 	if config.Metrics.Enabled {
 		metricsHandler := http.NewServeMux()
 		metricsHandler.Handle("/metrics", promhttp.Handler())
@@ -101,6 +167,8 @@ func New(config *Config, opts ...Opt) (*Server, error) {
 		pprof.Register(handler)
 	}
 
+	handler.POST("/webhooks/github", githubWebhookHandler(s, s.getWebhookSecret))
+
 	api := handler.Group("/api")
 	if config.BasicAuthEnabled {
 		api.Use(gin.BasicAuth(gin.Accounts(config.BasicAuthUsers)))
@@ -113,7 +181,13 @@ func New(config *Config, opts ...Opt) (*Server, error) {
 		v1.GET("/pools/:id", getPoolHandler(s))
 		v1.POST("/pools/:id/resume", resumePoolHandler(s))
 		v1.POST("/pools/:id/pause", pausePoolHandler(s))
+		v1.POST("/pools/:id/drain", drainPoolHandler(s))
+		v1.GET("/pools/:id/runners", listRunnersHandler(s))
+		v1.GET("/runners/:name", getRunnerHandler(s))
+		v1.DELETE("/runners/:name", deleteRunnerHandler(s))
 		v1.POST("/reload", reloadHandler(s))
+		v1.GET("/capacity", getCapacityHandler(s))
+		v1.GET("/events", getEventsHandler(s))
 	}
 
 	return s, nil
@@ -132,10 +206,16 @@ func (s *Server) Run(ctx context.Context) error {
 	}
 	defer listener.Close()
 
+	s.store, err = NewStore(s.config.StatePath)
+	if err != nil {
+		return err
+	}
+	defer s.store.Close()
+
 	for _, poolConfig := range s.config.Pools {
-		pool, err := NewPool(s.logger, poolConfig, s.github)
+		pool, err := s.newPool(ctx, poolConfig)
 		if err != nil {
-			return fmt.Errorf("creating pool: %w", err)
+			return err
 		}
 
 		s.pools[poolConfig.Name] = pool
@@ -164,6 +244,12 @@ func (s *Server) Run(ctx context.Context) error {
 		for _, pool := range s.pools {
 			wg.Add(1)
 			go func(pool *Pool) {
+				if s.drainTimeout > 0 {
+					if err := pool.Drain(context.Background(), s.drainTimeout); err != nil {
+						s.logger.Error().Err(err).Msgf("Failed to drain pool %s", pool.getConfig().Name)
+					}
+				}
+
 				pool.Stop()
 				wg.Done()
 			}(pool)
@@ -178,11 +264,27 @@ func (s *Server) Run(ctx context.Context) error {
 			_ = s.metricsServer.Shutdown(cancelCtx)
 		}
 
+		s.events.Close()
 		if err := s.server.Shutdown(cancelCtx); err != nil {
 			s.logger.Error().Err(err).Msg("Failed to shutdown server")
 		}
 	}()
 
+	if s.config.GC != nil && s.config.GC.Enabled {
+		go s.runGC(ctx)
+	}
+
+	if s.notifier != nil {
+		go s.notifier.Run(ctx, s.events)
+	}
+
+	go s.runReloader(ctx)
+	if s.watchConfig {
+		if err := s.watchConfigFile(ctx); err != nil {
+			return fmt.Errorf("watching config: %w", err)
+		}
+	}
+
 	metricUp.Set(1)
 
 	err = errGroup.Wait()
@@ -194,6 +296,53 @@ func (s *Server) Run(ctx context.Context) error {
 	return nil
 }
 
+// newPool creates a new pool with its machine provider and reconciles it with
+// the state store.
+func (s *Server) newPool(ctx context.Context, config *PoolConfig) (*Pool, error) {
+	provider, err := s.newProvider(s.logger, config)
+	if err != nil {
+		return nil, fmt.Errorf("creating machine provider: %w", err)
+	}
+
+	pool, err := NewPool(s.logger, config, s.github, s.store, provider, s.capacity, s.events)
+	if err != nil {
+		return nil, fmt.Errorf("creating pool: %w", err)
+	}
+
+	if err := pool.Reconcile(ctx); err != nil {
+		s.logger.Error().Err(err).Msgf("Failed to reconcile pool %s", config.Name)
+	}
+
+	return pool, nil
+}
+
+// runGC periodically collects the garbage of all pools until the context is
+// canceled.
+func (s *Server) runGC(ctx context.Context) {
+	t := time.NewTicker(s.config.GC.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		pools, _ := s.ListPools(ctx)
+		for _, pool := range pools {
+			result, err := pool.CollectGarbage(ctx, s.config.GC)
+			if err != nil {
+				s.logger.Error().Err(err).Msgf("Failed to collect garbage of pool %s", pool.getConfig().Name)
+				continue
+			}
+
+			s.logger.Debug().Msgf("Collected garbage of pool %s: %d machine(s), %d log(s) (%d bytes), %d log(s) rotated",
+				pool.getConfig().Name, result.Machines, result.Logs, result.Bytes, result.RotatedLogs)
+		}
+	}
+}
+
 // GetPool returns the pool with the given ID.
 func (s *Server) GetPool(ctx context.Context, id string) (*Pool, error) {
 	s.l.Lock()
@@ -220,16 +369,29 @@ func (s *Server) ListPools(ctx context.Context) ([]*Pool, error) {
 	return pools, nil
 }
 
-// ScalePool scales the pool with the given ID to the desired size.
-func (s *Server) ScalePool(ctx context.Context, id string, replicas int) error {
+// ScalePool scales the pool with the given ID by delta runners.
+func (s *Server) ScalePool(ctx context.Context, id string, delta int) (*ScaleResult, error) {
 	metricPoolScaleRequests.WithLabelValues(id).Inc()
 
 	pool, err := s.GetPool(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return pool.Scale(ctx, replicas)
+	return pool.Scale(ctx, delta), nil
+}
+
+// ScalePoolTo scales the pool with the given ID to the desired number of
+// replicas.
+func (s *Server) ScalePoolTo(ctx context.Context, id string, replicas int) (*ScaleResult, error) {
+	metricPoolScaleRequests.WithLabelValues(id).Inc()
+
+	pool, err := s.GetPool(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.ScaleTo(ctx, replicas), nil
 }
 
 // PausePool pauses the pool with the given ID.
@@ -256,33 +418,299 @@ func (s *Server) ResumePool(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *Server) Reload(ctx context.Context) error {
-	s.l.Lock()
-	defer s.l.Unlock()
+// DrainPool starts draining the pool with the given ID in the background. See
+// Pool.Drain.
+func (s *Server) DrainPool(ctx context.Context, id string, timeout time.Duration) error {
+	pool, err := s.GetPool(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := pool.Drain(context.Background(), timeout); err != nil {
+			s.logger.Error().Err(err).Msgf("Failed to drain pool %s", id)
+		}
+	}()
+
+	return nil
+}
+
+// ListRunners returns a list of all runners of the pool with the given ID.
+func (s *Server) ListRunners(ctx context.Context, poolID string) ([]*Runner, error) {
+	pool, err := s.GetPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.ListRunners(), nil
+}
+
+// GetRunner returns the runner with the given name.
+func (s *Server) GetRunner(ctx context.Context, name string) (*Runner, error) {
+	pools, err := s.ListPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pool := range pools {
+		runner, err := pool.GetRunner(name)
+		if err == nil {
+			return runner, nil
+		}
+	}
+
+	return nil, fireactions.ErrRunnerNotFound
+}
+
+// DeleteRunner stops the runner with the given name. See Pool.DeleteRunner.
+func (s *Server) DeleteRunner(ctx context.Context, name string, force bool) error {
+	runner, err := s.GetRunner(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	pool, err := s.GetPool(ctx, runner.Pool)
+	if err != nil {
+		return err
+	}
+
+	return pool.DeleteRunner(ctx, name, force)
+}
+
+// GetCapacity returns the capacity of the host and the capacity allocated to
+// each pool.
+func (s *Server) GetCapacity(ctx context.Context) (*fireactions.Capacity, error) {
+	return s.capacity.Get(), nil
+}
+
+// SubscribeEvents subscribes to the events of the pool with the given ID, or
+// all events if empty. See EventBus.Subscribe.
+func (s *Server) SubscribeEvents(ctx context.Context, poolID string, lastID uint64) (*EventSubscription, error) {
+	if poolID != "" {
+		if _, err := s.GetPool(ctx, poolID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.events.Subscribe(poolID, lastID), nil
+}
+
+// Reload reloads the pools from the configuration file. The new configuration
+// is validated and the added pools are created before any change is applied,
+// so a failed reload leaves the pools as they were. Removed pools are drained
+// and stopped in the background, and the runners of changed pools replaced if
+// needed. The settings of the server itself require a restart.
+func (s *Server) Reload(ctx context.Context) (*fireactions.ReloadResult, error) {
+	return s.reload(ctx, ReloadTriggerAPI)
+}
+
+// TriggerReload requests a reload of the configuration in the background, e.g.
+// on SIGHUP. Requests are debounced: the reload runs once none came in for a
+// second. Errors are logged and the current configuration is kept.
+func (s *Server) TriggerReload(trigger string) {
+	select {
+	case s.reloadCh <- trigger:
+	default:
+	}
+}
+
+// runReloader runs the reloads requested with TriggerReload until the context
+// is canceled.
+func (s *Server) runReloader(ctx context.Context) {
+	var trigger string
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case trigger = <-s.reloadCh:
+			debounce = time.After(s.reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			result, err := s.reload(ctx, trigger)
+			if err != nil {
+				s.logger.Error().Err(err).Msgf("Failed to reload configuration on %s, keeping the current one", trigger)
+				continue
+			}
+
+			s.logger.Info().Msgf("Configuration reloaded on %s: %d pool(s) added, %d removed, %d changed",
+				trigger, len(result.Added), len(result.Removed), len(result.Changed))
+		}
+	}
+}
+
+// reload applies the configuration file. Reloads are serialized by reloadMu,
+// which also guards the writes to the configuration of the server; s.l is
+// only held to swap the pools and the configuration, so that the pools are
+// updated without blocking the API.
+func (s *Server) reload(ctx context.Context, trigger string) (result *fireactions.ReloadResult, err error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	defer func() {
+		if err != nil {
+			metricConfigReloads.WithLabelValues(trigger, "failure").Inc()
+			return
+		}
+
+		metricConfigReloads.WithLabelValues(trigger, "success").Inc()
+		metricConfigLastReloadSuccess.SetToCurrentTime()
+	}()
 
 	s.logger.Info().Msgf("Reloading server configuration")
-	err := s.config.Load()
+	config, err := NewConfig(s.config.path)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
-	for _, poolConfig := range s.config.Pools {
-		pool, ok := s.pools[poolConfig.Name]
-		if ok {
-			pool.config = poolConfig
-			s.logger.Info().Msgf("Pool %s reloaded", poolConfig.Name)
-			continue
+	result, err = diffConfigs(s.config, config)
+	if err != nil {
+		return nil, fmt.Errorf("comparing config: %w", err)
+	}
+
+	// A pool added back while its previous instance is still draining would
+	// adopt the machines and clean up the resources the latter still owns.
+	s.l.Lock()
+	for _, name := range result.Added {
+		if _, ok := s.removing[name]; ok {
+			s.l.Unlock()
+			return nil, fmt.Errorf("pool %s: still draining since its removal, retry once it's removed", name)
 		}
+	}
+	s.l.Unlock()
+
+	poolConfigs := make(map[string]*PoolConfig, len(config.Pools))
+	for _, poolConfig := range config.Pools {
+		poolConfigs[poolConfig.Name] = poolConfig
+	}
 
-		pool, err = NewPool(s.logger, poolConfig, s.github)
+	added := make([]*Pool, 0, len(result.Added))
+	for _, name := range result.Added {
+		pool, err := s.newPool(ctx, poolConfigs[name])
 		if err != nil {
-			return fmt.Errorf("creating pool: %w", err)
+			for _, pool := range added {
+				if err := pool.provider.Close(); err != nil {
+					s.logger.Error().Err(err).Msgf("Failed to close machine provider of pool %s", pool.getConfig().Name)
+				}
+
+				s.unregisterPool(pool)
+			}
+
+			return nil, fmt.Errorf("pool %s: %w", name, err)
 		}
 
-		s.pools[poolConfig.Name] = pool
+		added = append(added, pool)
+	}
+
+	s.l.Lock()
+	pools := make(map[string]*Pool, len(s.pools))
+	for name, pool := range s.pools {
+		pools[name] = pool
+	}
+
+	for _, name := range result.Removed {
+		delete(s.pools, name)
+		s.removing[name] = struct{}{}
+	}
+
+	for _, pool := range added {
+		s.pools[pool.getConfig().Name] = pool
+	}
+
+	// The webhook secret is read from the configuration on each delivery.
+	githubConfig := *s.config.GitHub
+	githubConfig.WebhookSecret = config.GitHub.WebhookSecret
+	s.config.GitHub = &githubConfig
+
+	s.config.Includes = config.Includes
+	s.config.Pools = config.Pools
+	s.l.Unlock()
+
+	// The configuration watch follows the new include patterns.
+	select {
+	case s.reloadedCh <- struct{}{}:
+	default:
+	}
+
+	for _, change := range result.Changed {
+		pool, ok := pools[change.Pool]
+		if !ok {
+			continue
+		}
+
+		pool.Update(poolConfigs[change.Pool], change.Rolled)
+		s.logger.Info().Msgf("Pool %s reloaded, changed: %s", change.Pool, strings.Join(change.Fields, ", "))
+		if len(change.RestartRequired) > 0 {
+			s.logger.Warn().Msgf("Pool %s changes require a restart: %s", change.Pool, strings.Join(change.RestartRequired, ", "))
+		}
+	}
+
+	for _, name := range result.Unchanged {
+		if pool, ok := pools[name]; ok {
+			pool.Update(poolConfigs[name], false)
+		}
+	}
+
+	for _, name := range result.Removed {
+		if pool, ok := pools[name]; ok {
+			go s.removePool(pool)
+		}
+	}
+
+	for _, pool := range added {
 		go pool.Start()
-		s.logger.Info().Msgf("Pool %s started", poolConfig.Name)
+		s.logger.Info().Msgf("Pool %s started", pool.getConfig().Name)
 	}
 
-	return nil
+	if len(result.RestartRequired) > 0 {
+		s.logger.Warn().Msgf("Server configuration changes require a restart: %s", strings.Join(result.RestartRequired, ", "))
+	}
+
+	s.events.Publish(&fireactions.Event{Type: fireactions.EventTypeConfigReloaded,
+		Message: fmt.Sprintf("%d pool(s) added, %d removed, %d changed", len(result.Added), len(result.Removed), len(result.Changed))})
+
+	return result, nil
+}
+
+// removePool drains and stops a pool removed from the configuration.
+func (s *Server) removePool(pool *Pool) {
+	name := pool.getConfig().Name
+	s.logger.Info().Msgf("Removing pool %s", name)
+
+	if err := pool.Drain(context.Background(), defaultDrainTimeout); err != nil {
+		s.logger.Error().Err(err).Msgf("Failed to drain pool %s", name)
+	}
+
+	pool.Stop()
+	s.logger.Info().Msgf("Pool %s removed", name)
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	delete(s.removing, name)
+	s.unregisterPool(pool)
+}
+
+// unregisterPool removes the capacity accounting and the metrics of a pool
+// which is stopped, or was never started.
+func (s *Server) unregisterPool(pool *Pool) {
+	name := pool.getConfig().Name
+	if s.capacity != nil {
+		s.capacity.RemovePool(name)
+	}
+
+	metricPoolMaxRunnersCount.DeleteLabelValues(name)
+	metricPoolMinRunnersCount.DeleteLabelValues(name)
+	metricPoolCurrentRunnersCount.DeleteLabelValues(name)
+	metricPoolStatus.DeleteLabelValues(name)
+	metricPoolTotal.Dec()
+}
+
+// getWebhookSecret returns the secret of the GitHub webhook deliveries, which
+// is updated on reload. Webhooks are disabled if it's empty.
+func (s *Server) getWebhookSecret() string {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.config.GitHub.WebhookSecret
 }
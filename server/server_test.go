@@ -1 +1,87 @@
 package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConfig(t *testing.T) *Config {
+	key, err := os.ReadFile("testdata/github.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Metrics.Enabled = false
+	config.GitHub.AppID = 12345
+	config.GitHub.AppPrivateKey = string(key)
+	config.GitHub.WebhookSecret = "secret"
+	config.Pools = []*PoolConfig{{
+		Name:       "test",
+		MaxRunners: 1,
+		MinRunners: 1,
+		Runner: &RunnerConfig{
+			Name:            "test",
+			Image:           "ghcr.io/hostinger/fireactions/runner:ubuntu-20.04-x64-2.310.2",
+			ImagePullPolicy: "ifnotpresent",
+			Organization:    "hostinger",
+			GroupID:         1,
+			Labels:          []string{"self-hosted", "fireactions-2vcpu-2gb"},
+		},
+		Firecracker: &FirecrackerConfig{},
+	}}
+
+	return config
+}
+
+func TestServer_GitHubWebhook(t *testing.T) {
+	config := newTestConfig(t)
+	s, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pool is already at its maximum size, so a queued job must not
+	// trigger a scale up.
+	s.pools["test"] = &Pool{
+		runners:   map[string]*Runner{"test-1": newRunner("test-1", "test", "", "")},
+		runnersMu: &sync.Mutex{},
+	}
+	s.pools["test"].config.Store(config.Pools[0])
+	s.pools["test"].isActive.Store(true)
+
+	payload, err := os.ReadFile("testdata/workflow_job_queued.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ValidSignature", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhooks/github", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "workflow_job")
+		req.Header.Set("X-Hub-Signature-256", signWebhookPayload(payload, "secret"))
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"message":"No matching pool with free capacity found"}`, rec.Body.String())
+	})
+
+	t.Run("MissingSignature", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhooks/github", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "workflow_job")
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
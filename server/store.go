@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"go.etcd.io/bbolt"
+)
+
+var runnersBucket = []byte("runners")
+
+// Store persists the runner VMs of all pools on disk, so that they can be
+// re-adopted or cleaned up after the server restarts.
+type Store struct {
+	db *bbolt.DB
+}
+
+// RunnerRecord is the persisted state of a runner VM.
+type RunnerRecord struct {
+	Name        string                  `json:"name"`
+	Pool        string                  `json:"pool"`
+	VMID        string                  `json:"vmid"`
+	PID         int                     `json:"pid"`
+	SocketPath  string                  `json:"socket_path"`
+	LogPath     string                  `json:"log_path"`
+	IPAddress   string                  `json:"ip_address"`
+	IPAddresses []string                `json:"ip_addresses,omitempty"`
+	GitHubID    int64                   `json:"github_id"`
+	State       fireactions.RunnerState `json:"state"`
+	StartedAt   time.Time               `json:"started_at"`
+}
+
+// NewStore opens the state store at path, creating it if it doesn't exist.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating state directory: %w", err)
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening state store: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(runnersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating state store buckets: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the state store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// PutRunner creates or updates the record of a runner.
+func (s *Store) PutRunner(record *RunnerRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(runnersBucket).Put(runnerKey(record.Pool, record.Name), data)
+	})
+}
+
+// DeleteRunner deletes the record of a runner. Deleting a missing record is
+// not an error.
+func (s *Store) DeleteRunner(pool, name string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(runnersBucket).Delete(runnerKey(pool, name))
+	})
+}
+
+// ListRunners returns the records of all runners of a pool.
+func (s *Store) ListRunners(pool string) ([]*RunnerRecord, error) {
+	records := make([]*RunnerRecord, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := runnerKey(pool, "")
+		c := tx.Bucket(runnersBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			record := &RunnerRecord{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return fmt.Errorf("decoding runner %s: %w", k, err)
+			}
+
+			records = append(records, record)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func runnerKey(pool, name string) []byte {
+	return []byte(pool + "/" + name)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hostinger/fireactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.db")
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := &RunnerRecord{
+		Name:       "test-1",
+		Pool:       "test",
+		VMID:       "test-1",
+		PID:        1234,
+		SocketPath: "/var/lib/fireactions/pools/test/test-1.sock",
+		LogPath:    "/var/lib/fireactions/pools/test/test-1.log",
+		IPAddress:  "10.0.0.2",
+		GitHubID:   42,
+		State:      fireactions.RunnerStateIdle,
+		StartedAt:  time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC),
+	}
+
+	assert.NoError(t, store.PutRunner(record))
+	assert.NoError(t, store.PutRunner(&RunnerRecord{Name: "test-2", Pool: "test"}))
+	assert.NoError(t, store.PutRunner(&RunnerRecord{Name: "test-1", Pool: "test-other"}))
+
+	records, err := store.ListRunners("test")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, record, records[0])
+
+	assert.NoError(t, store.Close())
+
+	store, err = NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	assert.NoError(t, store.DeleteRunner("test", "test-2"))
+	assert.NoError(t, store.DeleteRunner("test", "missing"))
+
+	records, err = store.ListRunners("test")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	records, err = store.ListRunners("missing")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
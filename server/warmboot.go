@@ -0,0 +1,387 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/hostinger/fireactions/helper/deepcopy"
+	"github.com/hostinger/fireactions/runner"
+)
+
+const (
+	bootModeCold = "cold"
+	bootModeWarm = "warm"
+
+	templatePrefix  = "template-"
+	logPollInterval = 100 * time.Millisecond
+)
+
+// warmBootTemplate is a Firecracker snapshot of a VM booted from the runner
+// image, taken when its runner agent waits for the runner configuration,
+// along with its root drive at that moment.
+type warmBootTemplate struct {
+	key         string
+	memFilePath string
+	statePath   string
+}
+
+// getTemplate returns the warm boot template matching the runner image and
+// the machine configuration of the pool, building it if it doesn't exist yet.
+// Templates of previous images or configurations are removed once a new one
+// is built.
+func (p *firecrackerProvider) getTemplate(ctx context.Context, config *PoolConfig, image containerd.Image) (*warmBootTemplate, error) {
+	p.templatesMu.Lock()
+	defer p.templatesMu.Unlock()
+
+	key := getTemplateKey(image.Target().Digest.String(), config)
+	if template, ok := p.templates[key]; ok {
+		return template, nil
+	}
+
+	dir := p.getTemplateDir(key)
+	template := &warmBootTemplate{
+		key:         key,
+		memFilePath: filepath.Join(dir, "memory"),
+		statePath:   filepath.Join(dir, "vmstate"),
+	}
+
+	if !p.templateExists(ctx, template) {
+		p.logger.Info().Msgf("Building warm boot template %s", key)
+
+		start := time.Now()
+		if err := p.buildTemplate(ctx, config, image, template); err != nil {
+			metricWarmBootTemplateBuilds.WithLabelValues(p.pool, "failure").Inc()
+			return nil, err
+		}
+
+		metricWarmBootTemplateBuilds.WithLabelValues(p.pool, "success").Inc()
+		p.logger.Info().Msgf("Warm boot template %s built in %s", key, time.Since(start))
+	}
+
+	p.removeStaleTemplates(ctx, key)
+	p.templates = map[string]*warmBootTemplate{key: template}
+
+	return template, nil
+}
+
+// getTemplateKey returns the key of a warm boot template, which changes
+// whenever the image or a setting that's part of the snapshot changes.
+func getTemplateKey(digest string, config *PoolConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", digest, config.Firecracker.BinaryPath, config.Firecracker.KernelImagePath, config.Firecracker.KernelArgs)
+
+	// The machine configuration and the devices, including their rate
+	// limiters, are part of the snapshot.
+	devices, _ := json.Marshal([]interface{}{config.Firecracker.MachineConfig, config.Firecracker.RootfsRateLimiter,
+		config.Firecracker.Balloon, config.Firecracker.Drives, config.GetNetwork().Interfaces})
+	h.Write(devices)
+
+	// Jailed snapshots refer to the files by their path in the chroot.
+	fmt.Fprintf(h, "%t\n", config.Firecracker.IsJailerEnabled())
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// getTemplateDir returns the directory of the warm boot template files.
+func (p *firecrackerProvider) getTemplateDir(key string) string {
+	return filepath.Join(p.dir, "templates", key)
+}
+
+// templateExists returns true if the files and the root drive of the
+// template exist, e.g. when built before a restart.
+func (p *firecrackerProvider) templateExists(ctx context.Context, template *warmBootTemplate) bool {
+	for _, path := range []string{template.memFilePath, template.statePath} {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+
+	return p.rootDrives.TemplateExists(ctx, template)
+}
+
+// buildTemplate boots a template VM from the image, waits for its runner
+// agent to be ready, snapshots it and commits its root drive, so that the
+// runners can be restored from the same memory and disk state.
+func (p *firecrackerProvider) buildTemplate(ctx context.Context, config *PoolConfig, image containerd.Image, template *warmBootTemplate) (err error) {
+	dir := p.getTemplateDir(template.key)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing template directory: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating template directory: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			p.removeTemplate(context.Background(), template.key)
+		}
+	}()
+
+	drivePath, err := p.rootDrives.CreateTemplate(ctx, template, image)
+	if err != nil {
+		return err
+	}
+
+	logPath := filepath.Join(dir, "firecracker.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("creating log file: %w", err)
+	}
+	defer logFile.Close()
+
+	socketPath := filepath.Join(dir, "firecracker.sock")
+	id := templatePrefix + template.key
+	defer func() {
+		_ = p.removeNetworks(context.Background(), id)
+		if p.jailer != nil {
+			_ = p.removeJail(id)
+		}
+	}()
+
+	networkInterface, err := p.newNetworkInterface(id)
+	if err != nil {
+		return fmt.Errorf("cni: %w", err)
+	}
+
+	drives, _, err := p.createDrives(ctx, id, config.Firecracker)
+	if err != nil {
+		return err
+	}
+
+	machineConfig := newMachineConfig(id, socketPath, drivePath, config.Firecracker, networkInterface)
+	machineConfig.Drives = append(machineConfig.Drives, drives...)
+	machine, err := p.newMachine(ctx, machineConfig, config.Firecracker, logFile)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{"latest": map[string]interface{}{"meta-data": deepcopy.Map(config.Firecracker.Metadata)}}
+	metadata["latest"].(map[string]interface{})["meta-data"].(map[string]interface{})["fireactions"] = map[string]interface{}{"warm_boot_template": true}
+	if len(p.network.Interfaces) > 1 {
+		machine.Handlers.FcInit = machine.Handlers.FcInit.AppendAfter(firecracker.SetupNetworkHandlerName, p.newSecondaryNetworksHandler(metadata))
+	}
+	machine.Handlers.FcInit = machine.Handlers.FcInit.Append(firecracker.NewSetMetadataHandler(metadata))
+	if handler, ok := newBalloonHandler(config.Firecracker.Balloon); ok {
+		machine.Handlers.FcInit = machine.Handlers.FcInit.Append(handler)
+	}
+
+	if err := machine.Start(context.Background()); err != nil {
+		return fmt.Errorf("firecracker: starting machine: %w", err)
+	}
+
+	stopped := false
+	stop := func() error {
+		if stopped {
+			return nil
+		}
+
+		stopped = true
+		if err := machine.StopVMM(); err != nil {
+			return err
+		}
+
+		_ = machine.Wait(context.Background())
+		return nil
+	}
+	defer func() { _ = stop() }()
+
+	readyCtx, cancel := context.WithTimeout(ctx, config.Firecracker.WarmBoot.GetReadyTimeout())
+	defer cancel()
+
+	if err := waitForLogLine(readyCtx, logPath, runner.ReadyMarker); err != nil {
+		return fmt.Errorf("waiting for the runner agent: %w", err)
+	}
+
+	if err := machine.PauseVM(ctx); err != nil {
+		return fmt.Errorf("firecracker: pausing machine: %w", err)
+	}
+
+	// A jailed VM can only write the snapshot in its chroot, from which it's
+	// moved once the VM is stopped.
+	memFilePath, statePath := template.memFilePath, template.statePath
+	if p.jailer != nil {
+		memFilePath, statePath = jailerMemFilePath, jailerStatePath
+	}
+
+	if err := machine.CreateSnapshot(ctx, memFilePath, statePath); err != nil {
+		return fmt.Errorf("firecracker: creating snapshot: %w", err)
+	}
+
+	if err := stop(); err != nil {
+		return fmt.Errorf("firecracker: stopping machine: %w", err)
+	}
+
+	if p.jailer != nil {
+		root := p.getJailerRoot(id)
+		if err := os.Rename(filepath.Join(root, jailerMemFilePath), template.memFilePath); err != nil {
+			return fmt.Errorf("moving snapshot memory file: %w", err)
+		}
+
+		if err := os.Rename(filepath.Join(root, jailerStatePath), template.statePath); err != nil {
+			return fmt.Errorf("moving snapshot file: %w", err)
+		}
+	}
+
+	return p.rootDrives.CommitTemplate(ctx, template)
+}
+
+// removeStaleTemplates removes the warm boot templates other than the one
+// with the given key.
+func (p *firecrackerProvider) removeStaleTemplates(ctx context.Context, key string) {
+	dirs, err := os.ReadDir(filepath.Join(p.dir, "templates"))
+	if err != nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		if dir.Name() == key {
+			continue
+		}
+
+		p.removeTemplate(ctx, dir.Name())
+		p.logger.Info().Msgf("Removed stale warm boot template %s", dir.Name())
+	}
+}
+
+// removeTemplate removes the files and releases the root drive of the warm
+// boot template with the given key. Running machines restored from the
+// template are not affected, as they have the memory file mapped already.
+func (p *firecrackerProvider) removeTemplate(ctx context.Context, key string) {
+	if err := os.RemoveAll(p.getTemplateDir(key)); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to remove warm boot template %s", key)
+	}
+
+	if err := os.RemoveAll(p.getDrivesDir(templatePrefix + key)); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to remove the drives of warm boot template %s", key)
+	}
+
+	if err := p.rootDrives.RemoveTemplate(ctx, key); err != nil {
+		p.logger.Error().Err(err).Msgf("Failed to remove the root drive of warm boot template %s", key)
+	}
+}
+
+// newRestoreHandler returns a Firecracker handler that prepares a machine
+// restored from a warm boot template: it swaps the drives of the template for
+// the machine's own, provides the metadata, including the network
+// configuration to apply in place of the template's, and resumes the VM.
+func newRestoreHandler(metadata map[string]interface{}) firecracker.Handler {
+	return firecracker.Handler{
+		Name: "fireactions.RestoreWarmBootTemplate",
+		Fn: func(ctx context.Context, m *firecracker.Machine) error {
+			for _, drive := range m.Cfg.Drives {
+				if err := m.UpdateGuestDrive(ctx, firecracker.StringValue(drive.DriveID), firecracker.StringValue(drive.PathOnHost)); err != nil {
+					return fmt.Errorf("updating drive %s: %w", firecracker.StringValue(drive.DriveID), err)
+				}
+			}
+
+			if network := getNetworkMetadata(m.Cfg.NetworkInterfaces); network != nil {
+				getFireactionsMetadata(metadata)["network"] = network
+			}
+
+			if err := m.SetMetadata(ctx, metadata); err != nil {
+				return fmt.Errorf("setting metadata: %w", err)
+			}
+
+			return m.ResumeVM(ctx)
+		},
+	}
+}
+
+// getNetworkMetadata returns the network configuration assigned to the
+// machine by CNI, in the format read by the runner agent.
+func getNetworkMetadata(ifaces firecracker.NetworkInterfaces) map[string]interface{} {
+	for _, iface := range ifaces {
+		if iface.StaticConfiguration == nil || iface.StaticConfiguration.IPConfiguration == nil {
+			continue
+		}
+
+		ipConfig := iface.StaticConfiguration.IPConfiguration
+		network := map[string]interface{}{
+			"interface":   "eth0",
+			"address":     ipConfig.IPAddr.String(),
+			"mac_address": iface.StaticConfiguration.MacAddress,
+		}
+
+		if ipConfig.Gateway != nil {
+			network["gateway"] = ipConfig.Gateway.String()
+		}
+
+		return network
+	}
+
+	return nil
+}
+
+// waitForLogLine blocks until the file at path contains the given line, or
+// the context is canceled. The file is read incrementally as it grows.
+func waitForLogLine(ctx context.Context, path, line string) error {
+	var offset int64
+	var tail []byte
+	for {
+		data, truncated, err := readFrom(path, offset)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		// The file was truncated, e.g. rotated by the garbage collector.
+		if truncated {
+			offset, tail = 0, nil
+			continue
+		}
+
+		if bytes.Contains(append(tail, data...), []byte(line)) {
+			return nil
+		}
+
+		offset += int64(len(data))
+		tail = lastBytes(append(tail, data...), len(line))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// readFrom reads the file at path from offset. It reports whether the file is
+// smaller than offset instead.
+func readFrom(path string, offset int64) ([]byte, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if info.Size() < offset {
+		return nil, true, nil
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(file, offset, info.Size()-offset))
+	return data, false, err
+}
+
+func lastBytes(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+
+	return b[len(b)-n:]
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForLogLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	go func() {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		for _, chunk := range []string{"[    0.1] booting\nfireactions: wait", "ing for runner ", "configuration\n"} {
+			time.Sleep(2 * logPollInterval)
+			_, _ = file.WriteString(chunk)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, waitForLogLine(ctx, path, "fireactions: waiting for runner configuration"))
+}
+
+func TestWaitForLogLine_Truncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	assert.NoError(t, os.WriteFile(path, []byte("some very long line that is going to be truncated\n"), 0644))
+
+	go func() {
+		time.Sleep(2 * logPollInterval)
+		_ = os.WriteFile(path, []byte("ready\n"), 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, waitForLogLine(ctx, path, "ready"))
+}
+
+func TestWaitForLogLine_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*logPollInterval)
+	defer cancel()
+
+	err := waitForLogLine(ctx, filepath.Join(t.TempDir(), "missing.log"), "ready")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGetTemplateKey(t *testing.T) {
+	config := &PoolConfig{Firecracker: &FirecrackerConfig{KernelImagePath: "/var/lib/fireactions/vmlinux", MachineConfig: FirecrackerMachineConfig{VcpuCount: 2, MemSizeMib: 2048}}}
+
+	key := getTemplateKey("sha256:abc", config)
+	assert.Len(t, key, 12)
+	assert.Equal(t, key, getTemplateKey("sha256:abc", config))
+	assert.NotEqual(t, key, getTemplateKey("sha256:def", config))
+
+	config.Firecracker.Metadata = map[string]interface{}{"example": "value"}
+	assert.Equal(t, key, getTemplateKey("sha256:abc", config))
+
+	config.Firecracker.MachineConfig.MemSizeMib = 4096
+	assert.NotEqual(t, key, getTemplateKey("sha256:abc", config))
+
+	key = getTemplateKey("sha256:abc", config)
+	config.Firecracker.RootfsRateLimiter = &RateLimiterConfig{Ops: &TokenBucketConfig{Size: 1000, RefillTime: time.Second}}
+	assert.NotEqual(t, key, getTemplateKey("sha256:abc", config))
+
+	key = getTemplateKey("sha256:abc", config)
+	config.Network = &NetworkConfig{Interfaces: []*NetworkInterfaceConfig{{NetworkName: "fireactions"}, {NetworkName: "storage"}}}
+	assert.NotEqual(t, key, getTemplateKey("sha256:abc", config))
+}
+
+func TestGetNetworkMetadata(t *testing.T) {
+	assert.Nil(t, getNetworkMetadata(firecracker.NetworkInterfaces{{AllowMMDS: true}}))
+
+	network := getNetworkMetadata(firecracker.NetworkInterfaces{{
+		StaticConfiguration: &firecracker.StaticNetworkConfiguration{
+			MacAddress:  "06:00:0a:00:00:05",
+			HostDevName: "tap0",
+			IPConfiguration: &firecracker.IPConfiguration{
+				IPAddr:  net.IPNet{IP: net.IPv4(10, 0, 0, 5), Mask: net.CIDRMask(24, 32)},
+				Gateway: net.IPv4(10, 0, 0, 1),
+			},
+		},
+	}})
+
+	assert.Equal(t, map[string]interface{}{
+		"interface":   "eth0",
+		"address":     "10.0.0.5/24",
+		"gateway":     "10.0.0.1",
+		"mac_address": "06:00:0a:00:00:05",
+	}, network)
+}
@@ -2,11 +2,20 @@ package fireactions
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 var (
 	// ErrPoolNotFound is returned when a pool is not found
 	ErrPoolNotFound = errors.New("pool not found")
+
+	// ErrRunnerNotFound is returned when a runner is not found
+	ErrRunnerNotFound = errors.New("runner not found")
+
+	// ErrInsufficientCapacity is returned when the host has not enough capacity left for a runner
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
 )
 
 // Pool represents a slice of Pool
@@ -18,6 +27,7 @@ type Pool struct {
 	MaxRunners int        `json:"max_runners"`
 	MinRunners int        `json:"min_runners"`
 	CurRunners int        `json:"cur_runners"`
+	Profile    string     `json:"profile"`
 	Status     PoolStatus `json:"status"`
 }
 
@@ -35,6 +45,9 @@ const (
 
 	// PoolStatePaused represents the paused state, meaning the pool is stopped
 	PoolStatePaused PoolState = "Paused"
+
+	// PoolStateDraining represents the draining state, meaning the pool is removing its runners
+	PoolStateDraining PoolState = "Draining"
 )
 
 // PoolStatus represents the status of a pool
@@ -44,7 +57,7 @@ type PoolStatus struct {
 }
 
 func (p *Pool) Cols() []string {
-	return []string{"Name", "Max Runners", "Min Runners", "Cur Runners", "State"}
+	return []string{"Name", "Max Runners", "Min Runners", "Cur Runners", "Profile", "State"}
 }
 
 func (p *Pool) ColsMap() map[string]string {
@@ -53,6 +66,7 @@ func (p *Pool) ColsMap() map[string]string {
 		"MaxRunners": "Max Runners",
 		"MinRunners": "Min Runners",
 		"CurRunners": "Cur Runners",
+		"Profile":    "Profile",
 		"State":      "State",
 	}
 }
@@ -64,13 +78,14 @@ func (p *Pool) KV() []map[string]interface{} {
 			"Max Runners": p.MaxRunners,
 			"Min Runners": p.MinRunners,
 			"Cur Runners": p.CurRunners,
+			"Profile":     p.Profile,
 			"State":       p.Status.State,
 		},
 	}
 }
 
 func (p Pools) Cols() []string {
-	return []string{"Name", "Max Runners", "Min Runners", "Cur Runners", "State"}
+	return []string{"Name", "Max Runners", "Min Runners", "Cur Runners", "Profile", "State"}
 }
 
 func (p Pools) ColsMap() map[string]string {
@@ -79,6 +94,7 @@ func (p Pools) ColsMap() map[string]string {
 		"MaxRunners": "Max Runners",
 		"MinRunners": "Min Runners",
 		"CurRunners": "Cur Runners",
+		"Profile":    "Profile",
 		"State":      "State",
 	}
 }
@@ -97,3 +113,325 @@ func (p Pools) KV() []map[string]interface{} {
 
 	return kv
 }
+
+// ScalePoolRequest represents a request to scale a pool. Only one of Replicas
+// or Delta can be set. If neither is set, the pool is scaled up by 1.
+type ScalePoolRequest struct {
+	// Replicas is the desired number of runners in the pool.
+	Replicas *int `json:"replicas,omitempty"`
+
+	// Delta is the number of runners to add (positive) or remove (negative).
+	Delta *int `json:"delta,omitempty"`
+}
+
+// ScalePoolResult represents the result of scaling a pool
+type ScalePoolResult struct {
+	Pool     string   `json:"pool"`
+	Replicas int      `json:"replicas"`
+	Errors   []string `json:"errors"`
+}
+
+// Runners represents a slice of Runner
+type Runners []*Runner
+
+// Runner represents a single GitHub runner virtual machine
+type Runner struct {
+	Name       string      `json:"name"`
+	Pool       string      `json:"pool"`
+	State      RunnerState `json:"state"`
+	VMID       string      `json:"vmid"`
+	SocketPath string      `json:"socket_path"`
+	LogPath    string      `json:"log_path"`
+	IPAddress  string      `json:"ip_address"`
+	StartedAt  time.Time   `json:"started_at"`
+}
+
+// RunnerState represents the state of a runner
+type RunnerState string
+
+// String returns the string representation of the runner state
+func (r RunnerState) String() string {
+	return string(r)
+}
+
+const (
+	// RunnerStateStarting represents the starting state, meaning the virtual machine is booting
+	RunnerStateStarting RunnerState = "Starting"
+
+	// RunnerStateIdle represents the idle state, meaning the runner is waiting for a job
+	RunnerStateIdle RunnerState = "Idle"
+
+	// RunnerStateBusy represents the busy state, meaning the runner is running a job
+	RunnerStateBusy RunnerState = "Busy"
+
+	// RunnerStateExiting represents the exiting state, meaning the virtual machine is shutting down
+	RunnerStateExiting RunnerState = "Exiting"
+)
+
+func (r *Runner) Cols() []string {
+	return []string{"Name", "Pool", "State", "IP Address", "Started At"}
+}
+
+func (r *Runner) ColsMap() map[string]string {
+	return map[string]string{
+		"Name":      "Name",
+		"Pool":      "Pool",
+		"State":     "State",
+		"IPAddress": "IP Address",
+		"StartedAt": "Started At",
+	}
+}
+
+func (r *Runner) KV() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"Name":       r.Name,
+			"Pool":       r.Pool,
+			"State":      r.State,
+			"IP Address": r.IPAddress,
+			"Started At": r.StartedAt.Format(time.RFC3339),
+		},
+	}
+}
+
+func (r Runners) Cols() []string {
+	return []string{"Name", "Pool", "State", "IP Address", "Started At"}
+}
+
+func (r Runners) ColsMap() map[string]string {
+	return map[string]string{
+		"Name":      "Name",
+		"Pool":      "Pool",
+		"State":     "State",
+		"IPAddress": "IP Address",
+		"StartedAt": "Started At",
+	}
+}
+
+func (r Runners) KV() []map[string]interface{} {
+	kv := make([]map[string]interface{}, 0, len(r))
+	for _, runner := range r {
+		kv = append(kv, map[string]interface{}{
+			"Name":       runner.Name,
+			"Pool":       runner.Pool,
+			"State":      runner.State,
+			"IP Address": runner.IPAddress,
+			"Started At": runner.StartedAt.Format(time.RFC3339),
+		})
+	}
+
+	return kv
+}
+
+// Capacity represents the vCPU and memory capacity of the host shared by the pools
+type Capacity struct {
+	Enabled   bool             `json:"enabled"`
+	Vcpus     CapacityResource `json:"vcpus"`
+	MemoryMib CapacityResource `json:"memory_mib"`
+	Pools     PoolCapacities   `json:"pools"`
+}
+
+// CapacityResource represents the capacity of a resource of the host. Allocatable
+// is the total minus the reserved amount, multiplied by the overcommit ratio.
+type CapacityResource struct {
+	Total           int64   `json:"total"`
+	Reserved        int64   `json:"reserved"`
+	OvercommitRatio float64 `json:"overcommit_ratio"`
+	Allocatable     int64   `json:"allocatable"`
+	Allocated       int64   `json:"allocated"`
+	Available       int64   `json:"available"`
+}
+
+func (c *Capacity) Cols() []string {
+	return []string{"Resource", "Total", "Reserved", "Overcommit", "Allocatable", "Allocated", "Available"}
+}
+
+func (c *Capacity) ColsMap() map[string]string {
+	return map[string]string{
+		"Resource":        "Resource",
+		"Total":           "Total",
+		"Reserved":        "Reserved",
+		"OvercommitRatio": "Overcommit",
+		"Allocatable":     "Allocatable",
+		"Allocated":       "Allocated",
+		"Available":       "Available",
+	}
+}
+
+func (c *Capacity) KV() []map[string]interface{} {
+	kv := make([]map[string]interface{}, 0, 2)
+	for _, resource := range []struct {
+		name string
+		*CapacityResource
+	}{{"vCPUs", &c.Vcpus}, {"Memory (MiB)", &c.MemoryMib}} {
+		kv = append(kv, map[string]interface{}{
+			"Resource":    resource.name,
+			"Total":       resource.Total,
+			"Reserved":    resource.Reserved,
+			"Overcommit":  fmt.Sprintf("%g", resource.OvercommitRatio),
+			"Allocatable": resource.Allocatable,
+			"Allocated":   resource.Allocated,
+			"Available":   resource.Available,
+		})
+	}
+
+	return kv
+}
+
+// PoolCapacities represents a slice of PoolCapacity
+type PoolCapacities []*PoolCapacity
+
+// PoolCapacity represents the capacity allocated to the runners of a pool
+type PoolCapacity struct {
+	Pool      string `json:"pool"`
+	Priority  int    `json:"priority"`
+	Runners   int    `json:"runners"`
+	Vcpus     int64  `json:"vcpus"`
+	MemoryMib int64  `json:"memory_mib"`
+}
+
+func (p PoolCapacities) Cols() []string {
+	return []string{"Pool", "Priority", "Runners", "vCPUs", "Memory (MiB)"}
+}
+
+func (p PoolCapacities) ColsMap() map[string]string {
+	return map[string]string{
+		"Pool":      "Pool",
+		"Priority":  "Priority",
+		"Runners":   "Runners",
+		"Vcpus":     "vCPUs",
+		"MemoryMib": "Memory (MiB)",
+	}
+}
+
+func (p PoolCapacities) KV() []map[string]interface{} {
+	kv := make([]map[string]interface{}, 0, len(p))
+	for _, pool := range p {
+		kv = append(kv, map[string]interface{}{
+			"Pool":         pool.Pool,
+			"Priority":     pool.Priority,
+			"Runners":      pool.Runners,
+			"vCPUs":        pool.Vcpus,
+			"Memory (MiB)": pool.MemoryMib,
+		})
+	}
+
+	return kv
+}
+
+// ReloadResult represents the changes applied to the pools by a reload of the
+// server configuration. RestartRequired lists the changed settings outside of
+// the pools, which only take effect after a restart.
+type ReloadResult struct {
+	Added           []string    `json:"added"`
+	Removed         []string    `json:"removed"`
+	Changed         PoolChanges `json:"changed"`
+	Unchanged       []string    `json:"unchanged"`
+	RestartRequired []string    `json:"restart_required"`
+}
+
+// PoolChanges represents a slice of PoolChange
+type PoolChanges []*PoolChange
+
+// PoolChange represents the settings of a pool changed by a reload. Rolled is
+// true if its runners are replaced to apply them. RestartRequired lists the
+// changed settings which only take effect after a restart.
+type PoolChange struct {
+	Pool            string   `json:"pool"`
+	Fields          []string `json:"fields"`
+	Rolled          bool     `json:"rolled"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+func (r *ReloadResult) Cols() []string {
+	return []string{"Pool", "Change", "Fields", "Rolled"}
+}
+
+func (r *ReloadResult) ColsMap() map[string]string {
+	return map[string]string{
+		"Pool":   "Pool",
+		"Change": "Change",
+		"Fields": "Fields",
+		"Rolled": "Rolled",
+	}
+}
+
+func (r *ReloadResult) KV() []map[string]interface{} {
+	kv := make([]map[string]interface{}, 0, len(r.Added)+len(r.Removed)+len(r.Changed)+len(r.Unchanged))
+	for _, pool := range r.Added {
+		kv = append(kv, map[string]interface{}{"Pool": pool, "Change": "added", "Fields": "", "Rolled": false})
+	}
+
+	for _, pool := range r.Removed {
+		kv = append(kv, map[string]interface{}{"Pool": pool, "Change": "removed", "Fields": "", "Rolled": false})
+	}
+
+	for _, change := range r.Changed {
+		kv = append(kv, map[string]interface{}{"Pool": change.Pool, "Change": "changed", "Fields": strings.Join(change.Fields, ", "), "Rolled": change.Rolled})
+	}
+
+	for _, pool := range r.Unchanged {
+		kv = append(kv, map[string]interface{}{"Pool": pool, "Change": "unchanged", "Fields": "", "Rolled": false})
+	}
+
+	return kv
+}
+
+// EventType represents the type of an event
+type EventType string
+
+// String returns the string representation of the event type
+func (e EventType) String() string {
+	return string(e)
+}
+
+const (
+	// EventTypeVMCreated is emitted when the virtual machine of a runner is created
+	EventTypeVMCreated EventType = "VMCreated"
+
+	// EventTypeVMStarted is emitted when the virtual machine of a runner is started
+	EventTypeVMStarted EventType = "VMStarted"
+
+	// EventTypeJITConfigIssued is emitted when GitHub issues the just-in-time configuration of a runner
+	EventTypeJITConfigIssued EventType = "JITConfigIssued"
+
+	// EventTypeVMExited is emitted when the virtual machine of a runner exits
+	EventTypeVMExited EventType = "VMExited"
+
+	// EventTypeScaleFailed is emitted when a pool fails to scale
+	EventTypeScaleFailed EventType = "ScaleFailed"
+
+	// EventTypePoolPaused is emitted when a pool is paused
+	EventTypePoolPaused EventType = "PoolPaused"
+
+	// EventTypeConfigReloaded is emitted when the server configuration is reloaded
+	EventTypeConfigReloaded EventType = "ConfigReloaded"
+)
+
+const (
+	// ExitReasonCompleted means the runner shut its virtual machine down, usually after running a job
+	ExitReasonCompleted = "completed"
+
+	// ExitReasonDeleted means the virtual machine was stopped by the server, e.g. when scaling down or draining
+	ExitReasonDeleted = "deleted"
+
+	// ExitReasonFailed means the virtual machine exited with an error
+	ExitReasonFailed = "failed"
+)
+
+// Event represents something that happened on the server. Events are
+// numbered in the order they're published.
+type Event struct {
+	ID      uint64    `json:"id"`
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Pool    string    `json:"pool,omitempty"`
+	Runner  string    `json:"runner,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+
+	// Reason and DurationSeconds are set on VMExited events: why and after
+	// how long the virtual machine exited.
+	Reason          string  `json:"reason,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}